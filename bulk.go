@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+)
+
+// readNameFile reads the names to query with --file, skipping malformed names with a warning
+func readNameFile(path string) ([]string, error) {
+	lines, err := readNames(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading names: %s", err)
+	}
+
+	var names []string
+	for _, line := range lines {
+		name, err := toASCII(line)
+		if err != nil {
+			log.Warnf("Skipping %s: %s", line, err)
+			continue
+		}
+		if _, ok := dns.IsDomainName(name); !ok {
+			log.Warnf("Skipping malformed name %s", line)
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no names to query in %s", path)
+	}
+	return names, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// cacheHitFactor is the fraction of the cold latency that a warm query must be under to count as a cache hit
+const cacheHitFactor = 0.5
+
+// readNames reads a list of names from a file, one per line, ignoring blank lines and # comments
+func readNames(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, dns.Fqdn(line))
+	}
+	return names, scanner.Err()
+}
+
+// timedQuery sends a query and returns its latency
+func timedQuery(ctx context.Context, txp *transport.Transport, name string, qType uint16) (time.Duration, error) {
+	start := time.Now()
+	if _, err := queryType(ctx, txp, name, qType); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// measureCacheHits queries every name in a file once to warm the cache, then again to estimate the server's cache hit ratio
+func measureCacheHits(ctx context.Context, path string, txp *transport.Transport, out io.Writer) error {
+	names, err := readNames(path)
+	if err != nil {
+		return fmt.Errorf("reading names: %s", err)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no names in %s", path)
+	}
+
+	qTypes := []uint16{dns.TypeA}
+	if len(opts.Types) > 0 {
+		rrTypes, err := cli.ParseRRTypes(opts.Types)
+		if err != nil {
+			return err
+		}
+		qTypes = qTypes[:0]
+		for rrType := range rrTypes {
+			qTypes = append(qTypes, rrType)
+		}
+	}
+
+	var cold, warm time.Duration
+	var hits, total int
+	for _, qType := range qTypes {
+		coldLatencies := make([]time.Duration, len(names))
+		for i, name := range names {
+			if coldLatencies[i], err = timedQuery(ctx, txp, name, qType); err != nil {
+				return fmt.Errorf("cold query for %s: %s", name, err)
+			}
+		}
+		for i, name := range names {
+			latency, err := timedQuery(ctx, txp, name, qType)
+			if err != nil {
+				return fmt.Errorf("warm query for %s: %s", name, err)
+			}
+			hit := float64(latency) < float64(coldLatencies[i])*cacheHitFactor
+			log.Debugf("%s %s: cold %s warm %s hit %t", name, dns.TypeToString[qType], coldLatencies[i], latency, hit)
+
+			cold += coldLatencies[i]
+			warm += latency
+			total++
+			if hit {
+				hits++
+			}
+		}
+	}
+
+	util.MustWritef(out, "Cache hit ratio: %s (%d/%d queries at least %.0f%% faster when repeated)\n",
+		util.Color(util.ColorGreen, fmt.Sprintf("%.1f%%", float64(hits)/float64(total)*100)),
+		hits, total, (1-cacheHitFactor)*100,
+	)
+	util.MustWritef(out, "Average latency: cold %s warm %s\n",
+		util.Color(util.ColorTeal, (cold/time.Duration(total)).Round(time.Microsecond)),
+		util.Color(util.ColorTeal, (warm/time.Duration(total)).Round(time.Microsecond)),
+	)
+	return nil
+}
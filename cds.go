@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// dsKey identifies a DS record by its rdata
+func dsKey(ds *dns.DS) string {
+	return fmt.Sprintf("%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType, strings.ToUpper(ds.Digest))
+}
+
+// dsLabel describes a DS record for display
+func dsLabel(ds *dns.DS) string {
+	return fmt.Sprintf("%d %s %s", ds.KeyTag, dns.AlgorithmToString[ds.Algorithm], dns.HashToString[ds.DigestType])
+}
+
+// signaledDS returns the DS records requested by a zone's CDS and CDNSKEY records, and whether they request DS removal (RFC 8078 section 4).
+// CDNSKEY records are converted to DS records using the digest types published at the parent, or SHA-256 if there are none.
+func signaledDS(cdsRRs, cdnskeyRRs []dns.RR, parent []*dns.DS) ([]*dns.DS, bool) {
+	digestTypes := map[uint8]bool{}
+	for _, ds := range parent {
+		digestTypes[ds.DigestType] = true
+	}
+	if len(digestTypes) == 0 {
+		digestTypes[dns.SHA256] = true
+	}
+
+	var signaled []*dns.DS
+	seen := map[string]bool{}
+	add := func(ds *dns.DS) {
+		if ds != nil && !seen[dsKey(ds)] {
+			seen[dsKey(ds)] = true
+			signaled = append(signaled, ds)
+		}
+	}
+
+	for _, rr := range cdsRRs {
+		if cds, ok := rr.(*dns.CDS); ok {
+			if cds.Algorithm == 0 {
+				return nil, true
+			}
+			add(&cds.DS)
+		}
+	}
+	for _, rr := range cdnskeyRRs {
+		if key, ok := rr.(*dns.CDNSKEY); ok {
+			if key.Algorithm == 0 {
+				return nil, true
+			}
+			for digestType := range digestTypes {
+				add(key.DNSKEY.ToDS(digestType))
+			}
+		}
+	}
+	return signaled, false
+}
+
+// checkCDS compares a zone's CDS and CDNSKEY records against the DS records published at the parent
+func checkCDS(ctx context.Context, zone string, txp *transport.Transport, out io.Writer) error {
+	zone = dns.Fqdn(zone)
+
+	replies := map[uint16]*dns.Msg{}
+	for _, qType := range []uint16{dns.TypeDS, dns.TypeCDS, dns.TypeCDNSKEY} {
+		reply, err := queryType(ctx, txp, zone, qType)
+		if err != nil {
+			return fmt.Errorf("%s query for %s: %s", dns.TypeToString[qType], zone, err)
+		}
+		replies[qType] = reply
+	}
+
+	var parent []*dns.DS
+	for _, rr := range replies[dns.TypeDS].Answer {
+		if ds, ok := rr.(*dns.DS); ok {
+			parent = append(parent, ds)
+		}
+	}
+
+	signaled, remove := signaledDS(replies[dns.TypeCDS].Answer, replies[dns.TypeCDNSKEY].Answer, parent)
+	switch {
+	case remove:
+		util.MustWritef(out, "%s: %s\n", zone, util.Color(util.ColorYellow, "CDS/CDNSKEY request removal of all DS records (RFC 8078 section 4)"))
+		for _, ds := range parent {
+			util.MustWritef(out, "  DS %s: %s\n", dsLabel(ds), "published at parent")
+		}
+		return nil
+	case len(signaled) == 0:
+		util.MustWritef(out, "%s: %s\n", zone, util.Color(util.ColorYellow, "no CDS or CDNSKEY records published"))
+		return nil
+	}
+
+	atParent := map[string]bool{}
+	for _, ds := range parent {
+		atParent[dsKey(ds)] = true
+	}
+	atChild := map[string]bool{}
+	for _, ds := range signaled {
+		atChild[dsKey(ds)] = true
+	}
+
+	inSync := len(atParent) == len(atChild)
+	var lines []string
+	for _, ds := range signaled {
+		if atParent[dsKey(ds)] {
+			lines = append(lines, fmt.Sprintf("  CDS %s: %s", dsLabel(ds), util.Color(util.ColorGreen, "published at parent")))
+		} else {
+			inSync = false
+			lines = append(lines, fmt.Sprintf("  CDS %s: %s", dsLabel(ds), util.Color(util.ColorRed, "not at parent")))
+		}
+	}
+	for _, ds := range parent {
+		if !atChild[dsKey(ds)] {
+			inSync = false
+			lines = append(lines, fmt.Sprintf("  DS %s: %s", dsLabel(ds), util.Color(util.ColorRed, "not signaled by CDS/CDNSKEY")))
+		}
+	}
+
+	if inSync {
+		util.MustWritef(out, "%s: %s\n", zone, util.Color(util.ColorGreen, "parent DS matches CDS/CDNSKEY"))
+	} else {
+		util.MustWritef(out, "%s: %s\n", zone, util.Color(util.ColorRed, "parent DS update pending"))
+	}
+	for _, line := range lines {
+		util.MustWriteln(out, line)
+	}
+	return nil
+}
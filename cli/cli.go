@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,50 +17,152 @@ import (
 type Flags struct {
 	Name             string        `short:"q" long:"qname" description:"Query name"`
 	Server           []string      `short:"s" long:"server" description:"DNS server(s)"`
-	Types            []string      `short:"t" long:"type" description:"RR type (e.g. A, AAAA, MX, etc.) or type integer"`
+	Transport        string        `long:"transport" env:"Q_TRANSPORT" description:"Transport for servers given without a scheme, e.g. tcp, tls, https, or quic (default: plain)"`
+	Types            []string      `short:"t" long:"type" env:"Q_TYPE" env-delim:"," description:"RR type (e.g. A, AAAA, MX, etc.) or type integer"`
 	Reverse          bool          `short:"x" long:"reverse" description:"Reverse lookup"`
+	NoReverse        bool          `long:"no-reverse" description:"Don't reverse lookup a name that is an IP address when no type is given"`
 	DNSSEC           bool          `short:"d" long:"dnssec" description:"Set the DO (DNSSEC OK) bit in the OPT record"`
+	CompactOK        bool          `long:"compact-ok" description:"Set the CO (Compact answers OK) bit in the OPT record to signal support for compact denial of existence (RFC 9824)"`
 	NSID             bool          `short:"n" long:"nsid" description:"Set EDNS0 NSID opt"`
 	NSIDOnly         bool          `short:"N" long:"nsid-only" description:"Set EDNS0 NSID opt and query only for the NSID"`
+	Expire           bool          `long:"expire" description:"Set EDNS0 expire opt and show the zone expire timer returned by the server (RFC 7314)"`
+	KeyTags          string        `long:"key-tag" description:"Set EDNS0 key tag opt with comma-separated trust anchor key tags (RFC 8145)"`
 	ClientSubnet     string        `long:"subnet" description:"Set EDNS0 client subnet"`
-	Chaos            bool          `short:"c" long:"chaos" description:"Use CHAOS query class"`
-	Class            uint16        `short:"C" description:"Set query class (default: IN 0x01)" default:"1"`
+	Chaos            bool          `short:"c" long:"chaos" description:"Use CHAOS query class, querying the TXT record of version.bind if no name or type is given"`
+	Class            Class         `short:"C" long:"class" description:"Set query class by name (IN, CH, HS, NONE, ANY) or number" default:"IN"`
 	ODoHProxy        string        `short:"p" long:"odoh-proxy" description:"ODoH proxy"`
-	Timeout          time.Duration `long:"timeout" description:"Query timeout" default:"10s"`
+	Timeout          time.Duration `long:"timeout" env:"Q_TIMEOUT" description:"Query timeout, applied to each attempt" default:"10s"`
+	OverallTimeout   time.Duration `long:"overall-timeout" description:"Maximum total runtime including retries and PTR lookups (default: no limit)"`
+	Retry            int           `long:"retry" env:"Q_RETRY" description:"Number of times to retry a failed query" default:"0"`
+	RetryOn          []string      `long:"retry-on" description:"Failure categories to retry (timeout, network, servfail, refused, formerr, nxdomain)" default:"timeout" default:"network"` //nolint:golint,staticcheck
+	RetryRandomID    bool          `long:"randomize-id-on-retry" description:"Use a new random query ID for each retry"`
+	RetryBackoff     time.Duration `long:"retry-backoff" description:"Wait before each retry, doubling after every attempt (0 to retry immediately)" default:"0s"`
+	RetryServfail    bool          `long:"retry-servfail" description:"Also retry SERVFAIL responses (same as adding servfail to --retry-on)"`
 	Pad              bool          `long:"pad" description:"Set EDNS0 padding"`
+	NoCompression    bool          `long:"no-compression" description:"Don't compress names in the query"`
 	HTTP2            bool          `long:"http2" description:"Use HTTP/2 for DoH"`
 	HTTP3            bool          `long:"http3" description:"Use HTTP/3 for DoH"`
 	IDCheck          bool          `long:"id-check" description:"Check DNS response ID (default: true)"`
 	ReuseConn        bool          `long:"reuse-conn" description:"Reuse connections across queries to the same server (default: true)"`
+	SourcePort       uint16        `long:"fixed-srcport" description:"Bind UDP and TCP queries to a fixed source port"`
+	SourceIP         string        `long:"source-ip" description:"Send queries from this local address, which must be of the same family as the server"`
+	Interface        string        `long:"interface" description:"Send queries through this network interface (Linux only)"`
+	TFO              bool          `long:"tfo" description:"Enable TCP Fast Open for TCP and TLS transports where supported"`
+	Proxy            string        `long:"proxy" description:"SOCKS5 proxy to connect to servers through (socks5://[user:pass@]host:port), sending plain DNS over TCP"`
 	TXTConcat        bool          `long:"txtconcat" description:"Concatenate TXT responses"`
 	ID               int           `long:"qid" description:"Set query ID (-1 for random)" default:"-1"`
+	IDSequential     bool          `long:"id-sequential" description:"Number query IDs sequentially from --id-base, wrapping after 65535, to correlate queries with a packet capture"`
+	IDBase           int           `long:"id-base" description:"First query ID with --id-sequential" default:"0"`
 	BootstrapServer  string        `short:"b" long:"bootstrap-server" description:"DNS server to use for bootstrapping"`
 	BootstrapTimeout time.Duration `long:"bootstrap-timeout" description:"Bootstrapping timeout" default:"5s"`
-	Cookie           string        `long:"cookie" description:"EDNS0 cookie"`
+	TraceTimeout     time.Duration `long:"trace-timeout" description:"Per-hop timeout for --trace-graph, after which the next nameserver of the zone is tried" default:"2s"`
+	Cookie           string        `long:"cookie" optional:"yes" optional-value:"auto" description:"EDNS0 cookie as hex, or generate a random client cookie and validate the server cookie if no value is given"`
+	MaxCNAMEDepth    int           `long:"max-cname-depth" description:"Follow CNAME chains up to this many hops, failing on loops (0 to disable)" default:"0"`
+	Verify           bool          `long:"verify" description:"Send each query twice and report if the answers differ"`
+	Match            string        `long:"match" description:"Exit with an error unless an answer's rdata matches this regular expression"`
+	MatchType        string        `long:"match-type" description:"Only match answers of this record type"`
+	NoMatch          bool          `long:"no-match" description:"Exit with an error if an answer matches --match instead"`
+	IXFRSerial       uint32        `long:"ixfr-serial" description:"SOA serial to request changes since with an IXFR query (also set with IXFR=serial)"`
+	ExpectCount      int           `long:"expect-count" description:"Exit with an error unless each reply has exactly this many answers of the queried type" default:"-1"`
+	ExpectMinCount   int           `long:"expect-min-count" description:"Exit with an error if a reply has fewer answers of the queried type"`
+	ExpectMaxCount   int           `long:"expect-max-count" description:"Exit with an error if a reply has more answers of the queried type" default:"-1"`
+	RequireECSScope  bool          `long:"require-ecs-scope" description:"Exit with an error if a reply doesn't return an EDNS0 client subnet scope, and show the scope of those that do (requires --subnet)"`
+	Zone             bool          `long:"zone" description:"Find the zone the name belongs to by walking up its labels with SOA queries and show how far below the zone apex it is"`
+	TSIG             string        `long:"tsig" description:"Sign queries with a TSIG key (keyname:[algorithm:]secret, algorithm defaults to hmac-sha256)"`
+	NamesFile        string        `long:"file" description:"Query each name in a file, one per line, ignoring blank lines and # comments"`
+	Profile          string        `long:"profile" description:"Load flags from a named profile in the config file"`
+	ConfigFile       string        `long:"config" description:"Config file path (default: $XDG_CONFIG_HOME/q/config.yaml)"`
+
+	// Multiple servers
+	ServerConcurrency int  `long:"server-concurrency" description:"Maximum number of servers to query concurrently" default:"20"`
+	ContinueOnError   bool `long:"continue-on-error" description:"Record servers and zones that can't be queried as failures in the output instead of aborting"`
+	FailFast          bool `long:"fail-fast" description:"Stop querying the remaining servers as soon as one fails or its replies fail an --expect-count or --require-ecs-scope assertion"`
+
+	// Latency sampling
+	SampleDuration time.Duration `long:"roundtrip-over-time" description:"Query the server at a fixed interval for this long and write a timestamped latency series"`
+	SampleInterval time.Duration `long:"sample-interval" description:"Interval between latency samples with --roundtrip-over-time" default:"10s"`
+	SampleFormat   string        `long:"sample-format" description:"Latency series format (csv, ndjson)" default:"csv"`
+
+	// Tailing
+	Tail      time.Duration `long:"tail" description:"Query the server at this interval and append each result under a timestamp, noting when the answers change"`
+	TailCount int           `long:"tail-count" description:"Stop after this many polls with --tail (0 to poll until interrupted)"`
 
 	// Special query modes
-	RecAXFR bool `long:"recaxfr" description:"Perform recursive AXFR"`
+	RecAXFR           bool   `long:"recaxfr" description:"Perform recursive AXFR"`
+	Sweep             string `long:"sweep" description:"Query PTR records for every address in a CIDR range"`
+	SweepConcurrency  int    `long:"sweep-concurrency" description:"Number of concurrent PTR queries in sweep mode" default:"16"`
+	LimitAnswer       bool   `long:"limit-answer-section" description:"Query with a minimal UDP buffer and classify how the server truncates its response"`
+	CheckSecondaries  string `long:"check-secondaries" description:"Report the SOA serial and EDNS0 expire timer of each authoritative server for a zone"`
+	SecondaryPort     uint16 `long:"secondary-port" description:"Port to query authoritative servers on with --check-secondaries" default:"53"`
+	HeaderOnly        bool   `long:"header-only" description:"Send a query without a question and report whether the server responds and how fast"`
+	CheckCDS          string `long:"check-cds" description:"Compare a zone's CDS and CDNSKEY records against the DS records at the parent"`
+	CacheHitRatio     string `long:"measure-cache-hit-ratio" description:"Query each name in a file twice and estimate the server's cache hit ratio from the latency difference"`
+	NegativeCacheTest string `long:"negative-caching-test" description:"Query a random nonexistent name in a zone twice and report how the server caches the NXDOMAIN"`
+	TraceGraph        string `long:"trace-graph" description:"Iteratively resolve the query from the server (e.g. a root server) and write a Graphviz DOT graph of the delegation chain to a file (- for stdout)"`
+	CheckRecursion    bool   `long:"check-recursion" description:"Send a recursive query and report whether the server is open to recursion"`
+	CompareFamily     bool   `long:"compare-family" description:"Send each query to the server over both IPv4 and IPv6 and report differences in answers and latency"`
+	CookieRateLimit   int    `long:"cookie-rate-limit-test" description:"Send a burst of this many queries without and then with a server cookie and report whether the cookie bypasses rate limiting"`
+	Service           string `long:"service" description:"Discover the instances of a DNS-SD service type (e.g. _http._tcp) in the query domain with their endpoints and metadata"`
+	ReplayPcap        string `long:"replay-pcap" description:"Replay the DNS queries in a pcap file against the server and report whether each response matches the captured one"`
+	QNAMEMinimization string `long:"probe-qname-minimization" optional:"yes" optional-value:"qnamemintest.internet.nl" description:"Query a QNAME minimization test name through the resolver and report whether it minimizes the names it sends to authoritative servers"`
+	CheckPoisoning    bool   `long:"compare-cache-poisoning-resistance" description:"Report a resolver's observable cache poisoning defenses (DNS cookies, 0x20 case preservation, DNSSEC validation, duplicate query handling)"`
+	DNSSECOverhead    bool   `long:"dnssec-overhead" description:"Send each query without and then with the DO bit set and report how much DNSSEC adds to the response size"`
+	ValidateTargets   bool   `long:"validate-targets" description:"Resolve the target of each NS and MX record in the answers and report any that don't resolve, are CNAMEs, or point to private addresses"`
 
 	// Output
-	Format         string `short:"f" long:"format" description:"Output format (pretty, column, json, yaml, raw)" default:"pretty"`
+	Format         string `short:"f" long:"format" env:"Q_FORMAT" description:"Output format (pretty, column, json, yaml, raw, compare, influx, short, html)" default:"pretty"`
+	JSONFlatten    bool   `long:"json-flatten" description:"Output one flat JSON object per answer record"`
+	JSONCompact    bool   `long:"json-compact" description:"Print JSON output on a single line"`
+	DedupServers   bool   `long:"dedup-servers" description:"Group servers by identical answer sets"`
+	RTTTable       bool   `long:"show-rtt-per-server" description:"Show a table of each server's rcode, answer count, and RTT"`
 	PrettyTTLs     bool   `long:"pretty-ttls" description:"Format TTLs in human readable format (default: true)"`
 	ShortTTLs      bool   `long:"short-ttls" description:"Remove zero components of pretty TTLs. (24h0m0s->24h) (default: true)"`
-	Color          bool   `long:"color" description:"Enable color output"`
+	TTLHuman       bool   `long:"ttl-human" description:"Always show TTLs as short durations, including in flattened JSON output"`
+	Color          string `long:"color" description:"Color output (never, auto, always), auto only coloring terminals without NO_COLOR set" default:"auto"`
+	Theme          string `long:"theme" description:"Color theme (default, colorblind)" default:"default"`
+	ShowHeader     bool   `long:"header" description:"Show a dig-style line of header flags and section counts, and the EDNS version and flags"`
 	ShowQuestion   bool   `long:"question" description:"Show question section"`
 	ShowOpt        bool   `long:"opt" description:"Show OPT records"`
 	ShowAnswer     bool   `long:"answer" description:"Show answer section (default: true)"`
 	ShowAuthority  bool   `long:"authority" description:"Show authority section"`
 	ShowAdditional bool   `long:"additional" description:"Show additional section"`
 	ShowStats      bool   `short:"S" long:"stats" description:"Show time statistics"`
+	ShowMeta       bool   `long:"meta" description:"Show connection metadata"`
+	ShowTLS        bool   `long:"tls-info" description:"Show the negotiated TLS version, cipher suite, ALPN protocol, and session resumption of secure transports (implies --meta)"`
+	ShowTimings    bool   `long:"timings" description:"Show transport timing breakdown"`
+	VerboseTiming  bool   `long:"verbose-timing" description:"Show connection setup and TLS handshake times in the transport timing breakdown"`
 	ShowAll        bool   `long:"all" description:"Show all sections and statistics"`
 	Whois          bool   `short:"w" description:"Resolve ASN/ASName for A and AAAA records"`
-	ValueOnly      bool   `short:"r" long:"short" description:"Show record values only"`
+	ValueOnly      bool   `short:"r" long:"short" description:"Show record values only (same as --format short)"`
 	ResolveIPs     bool   `short:"R" long:"resolve-ips" description:"Resolve PTR records for IP addresses in A and AAAA records"`
 	RoundTTLs      bool   `long:"round-ttls" description:"Round TTLs to the nearest minute"`
+	SortTTL        bool   `long:"sort-ttl" description:"Sort records by ascending TTL instead of by type"`
+	Sort           bool   `long:"sort" description:"Sort records in every section by name, type, and value so that output is stable across runs"`
+	TTLAbove       uint32 `long:"highlight-ttl-above" description:"Highlight records with a TTL above this many seconds"`
+	TTLBelow       uint32 `long:"highlight-ttl-below" description:"Highlight records with a TTL below this many seconds"`
+	LOCMapLink     bool   `long:"loc-map-link" description:"Show a map link for LOC records"`
+	Save           string `long:"save" description:"Save every query and response with its server and timing to a JSON file to print again with --replay"`
+	Replay         string `long:"replay" description:"Print the responses saved to a file with --save instead of querying"`
+	WireOut        string `long:"wire-out" description:"Write each response in DNS wire format to a file, numbered if there are multiple responses"`
+	OutputOrder    string `long:"output-order" description:"Print entries from multiple servers in request or completion order" default:"request"`
+	SSHFPVerify    string `long:"sshfp-verify" description:"Verify SSHFP records against the host keys in an OpenSSH public key or known_hosts file"`
+	Annotate       bool   `long:"annotate" description:"Add comments explaining header flags, codes, and sections to YAML output"`
+	GeoDB          string `long:"geo-db" description:"Annotate A and AAAA records with their ASN and country from a local iptoasn.com TSV database"`
+
+	// Syslog
+	Syslog         bool   `long:"syslog" description:"Send query results to the local syslog daemon"`
+	SyslogServer   string `long:"syslog-server" description:"Send query results to a remote syslog server (udp://host:port or tcp://host:port)"`
+	SyslogFacility string `long:"syslog-facility" description:"Syslog facility" default:"user"`
+	SyslogSeverity string `long:"syslog-severity" description:"Syslog severity" default:"info"`
+	SyslogJSON     bool   `long:"syslog-json" description:"Format syslog messages as JSON"`
+
+	// Latency histogram
+	Histogram        bool            `long:"resolve-timeout-histogram" description:"Show a histogram of query latencies across all servers and types"`
+	HistogramBuckets []time.Duration `long:"histogram-buckets" description:"Upper bounds of latency histogram buckets" default:"10ms" default:"50ms" default:"100ms" default:"250ms" default:"500ms" default:"1s"` //nolint:golint,staticcheck
 
 	// Header flags
 	AuthoritativeAnswer bool `long:"aa" description:"Set AA (Authoritative Answer) flag in query"`
-	AuthenticData       bool `long:"ad" description:"Set AD (Authentic Data) flag in query"`
+	AuthenticData       bool `long:"ad" description:"Set AD (Authentic Data) flag in query to request validation, and report whether the response has AD set"`
 	CheckingDisabled    bool `long:"cd" description:"Set CD (Checking Disabled) flag in query"`
 	RecursionDesired    bool `long:"rd" description:"Set RD (Recursion Desired) flag in query (default: true)"`
 	RecursionAvailable  bool `long:"ra" description:"Set RA (Recursion Available) flag in query"`
@@ -67,6 +172,8 @@ type Flags struct {
 	// TLS parameters
 	TLSInsecureSkipVerify bool     `short:"i" long:"tls-insecure-skip-verify" description:"Disable TLS certificate verification"`
 	TLSServerName         string   `long:"tls-server-name" description:"TLS server name for host verification"`
+	TLSSNI                string   `long:"tls-sni" description:"TLS server name indication to send and verify the certificate against, independent of the server address (overrides --tls-server-name)"`
+	TLSPinSHA256          []string `long:"pin-sha256" description:"Base64 SHA-256 hash of the server certificate's SubjectPublicKeyInfo to require (can be repeated)"`
 	TLSMinVersion         string   `long:"tls-min-version" description:"Minimum TLS version to use" default:"1.0"`
 	TLSMaxVersion         string   `long:"tls-max-version" description:"Maximum TLS version to use" default:"1.3"`
 	TLSNextProtos         []string `long:"tls-next-protos" description:"TLS next protocols for ALPN"`
@@ -74,6 +181,7 @@ type Flags struct {
 	TLSCurvePreferences   []string `long:"tls-curve-preferences" description:"TLS curve preferences"`
 	TLSClientCertificate  string   `long:"tls-client-cert" description:"TLS client certificate file"`
 	TLSClientKey          string   `long:"tls-client-key" description:"TLS client key file"`
+	TLSClientCertFor      []string `long:"tls-client-cert-for" description:"TLS client certificate and key files for a server name as name=certfile,keyfile, overriding --tls-client-cert (*.example.com matches subdomains)"`
 	TLSKeyLogFile         string   `long:"tls-key-log-file" env:"SSLKEYLOGFILE" description:"TLS key log file"`
 
 	// HTTP
@@ -94,13 +202,43 @@ type Flags struct {
 	DNSCryptProvider  string `long:"dnscrypt-provider" description:"DNSCrypt provider name"`
 
 	DefaultRRTypes []string `long:"default-rr-types" description:"Default record types" default:"A" default:"AAAA" default:"NS" default:"MX" default:"TXT" default:"CNAME"` //nolint:golint,staticcheck
+	DNS64Prefixes  []string `long:"dns64-prefix" description:"DNS64 prefixes to detect synthesized AAAA records" default:"64:ff9b::/96"`
 
 	UDPBuffer   uint16 `long:"udp-buffer" description:"Set EDNS0 UDP size in query" default:"1232"`
+	EDNSVersion uint8  `long:"edns-version" description:"Set EDNS version in query, downgrading if the server responds with BADVERS" default:"0"`
 	Verbose     bool   `short:"v" long:"verbose" description:"Show verbose log messages"`
 	Trace       bool   `long:"trace" description:"Show trace log messages"`
 	ShowVersion bool   `short:"V" long:"version" description:"Show version and exit"`
 }
 
+// Class is a DNS class that can be set by name or number
+type Class uint16
+
+// classAliases are the long names of classes that also identify them, in addition to dns.StringToClass
+var classAliases = map[string]uint16{
+	"INTERNET": dns.ClassINET,
+	"CHAOS":    dns.ClassCHAOS,
+	"HESIOD":   dns.ClassHESIOD,
+}
+
+// UnmarshalFlag parses a DNS class case-insensitively from its name (e.g. IN, CH, CHAOS, ANY) or integer value
+func (c *Class) UnmarshalFlag(value string) error {
+	if class, ok := classAliases[strings.ToUpper(value)]; ok {
+		*c = Class(class)
+		return nil
+	}
+	if class, ok := dns.StringToClass[strings.ToUpper(value)]; ok {
+		*c = Class(class)
+		return nil
+	}
+	class, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid class", value)
+	}
+	*c = Class(class)
+	return nil
+}
+
 // ParsePlusFlags parses a list of flags notated by +[no]flag and sets the corresponding opts fields
 func ParsePlusFlags(opts *Flags, args []string) {
 	for _, arg := range args {
@@ -113,6 +251,15 @@ func ParsePlusFlags(opts *Flags, args []string) {
 				flag = strings.ToLower(arg[1:])
 			}
 
+			// --color takes a mode, so map +color and +nocolor to its modes
+			if flag == "color" {
+				opts.Color = "never"
+				if state {
+					opts.Color = "always"
+				}
+				continue
+			}
+
 			v := reflect.Indirect(reflect.ValueOf(opts))
 			vT := v.Type()
 			for i := 0; i < v.NumField(); i++ {
@@ -162,14 +309,20 @@ func SetFalseBooleans(opts *Flags, args []string) []string {
 
 			v := reflect.Indirect(reflect.ValueOf(opts))
 			vT := v.Type()
+			found := false
 			for i := 0; i < v.NumField(); i++ {
 				if vT.Field(i).Type == reflect.TypeOf(true) && (vT.Field(i).Tag.Get("long") == flag || vT.Field(i).Tag.Get("short") == flag) {
 					boolState := strings.HasSuffix(arg, "=true")
 					log.Tracef("Setting %s to %t", arg, boolState)
 					reflect.ValueOf(opts).Elem().Field(i).SetBool(boolState)
+					found = true
 					break
 				}
 			}
+			// Leave true and false values of other flags (e.g. --color=false) for the parser
+			if !found {
+				remainingArgs = append(remainingArgs, arg)
+			}
 		} else {
 			remainingArgs = append(remainingArgs, arg)
 		}
@@ -210,6 +363,82 @@ func ParseRRTypes(t []string) (map[uint16]bool, error) {
 	return rrTypes, nil
 }
 
+// ArgType returns the RR type of a positional argument in mnemonic ("MX") or RFC 3597 ("TYPE65") notation
+func ArgType(arg string) (uint16, bool) {
+	upper := strings.ToUpper(arg)
+	if rrType, ok := dns.StringToType[upper]; ok {
+		return rrType, true
+	}
+	if code, ok := strings.CutPrefix(upper, "TYPE"); ok {
+		if rrType, err := strconv.ParseUint(code, 10, 16); err == nil {
+			return uint16(rrType), true
+		}
+	}
+	return 0, false
+}
+
+// ArgIXFR returns the serial of an IXFR query argument in IXFR=serial notation
+func ArgIXFR(arg string) (uint32, bool) {
+	prefix, serial, found := strings.Cut(arg, "=")
+	if !found || !strings.EqualFold(prefix, "IXFR") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(serial, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// tsigAlgorithms maps TSIG algorithm names to their dns package identifiers
+var tsigAlgorithms = map[string]string{
+	"hmac-sha1":   dns.HmacSHA1,
+	"hmac-sha224": dns.HmacSHA224,
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha384": dns.HmacSHA384,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+// ParseTSIG parses a TSIG key in keyname:[algorithm:]secret format, defaulting to hmac-sha256, and returns the
+// fully qualified key name, algorithm, and base64 secret
+func ParseTSIG(s string) (string, string, string, error) {
+	parts := strings.Split(s, ":")
+	var name, algorithm, secret string
+	switch len(parts) {
+	case 2:
+		name, algorithm, secret = parts[0], "hmac-sha256", parts[1]
+	case 3:
+		name, algorithm, secret = parts[0], strings.ToLower(strings.TrimSuffix(parts[1], ".")), parts[2]
+	default:
+		return "", "", "", fmt.Errorf("invalid TSIG key %s, expected keyname:[algorithm:]secret", s)
+	}
+
+	alg, ok := tsigAlgorithms[algorithm]
+	if !ok {
+		return "", "", "", fmt.Errorf("unsupported TSIG algorithm %s", algorithm)
+	}
+	if name == "" {
+		return "", "", "", fmt.Errorf("TSIG key name is empty")
+	}
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil || secret == "" {
+		return "", "", "", fmt.Errorf("TSIG secret must be base64 encoded")
+	}
+	return dns.Fqdn(name), alg, secret, nil
+}
+
+// ParseKeyTags parses a comma-separated list of DNSKEY key tags
+func ParseKeyTags(s string) ([]uint16, error) {
+	var tags []uint16
+	for _, tag := range strings.Split(s, ",") {
+		t, err := strconv.ParseUint(strings.TrimSpace(tag), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key tag %s, expected a number from 0 to 65535", tag)
+		}
+		tags = append(tags, uint16(t))
+	}
+	return tags, nil
+}
+
 // isBool checks if a flag by a given name is a boolean flag of Flags
 func isBool(name string) bool {
 	v := reflect.ValueOf(Flags{})
@@ -222,7 +451,32 @@ func isBool(name string) bool {
 	return false
 }
 
-// AddEqualSigns adds equal signs between flags and their values, ignoring boolean flags
+// hasOptionalValue returns true if a flag's value is optional, so it can only be set with an equal sign
+func hasOptionalValue(name string) bool {
+	v := reflect.ValueOf(Flags{})
+	vT := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		if vT.Field(i).Tag.Get("short") == name || vT.Field(i).Tag.Get("long") == name {
+			return vT.Field(i).Tag.Get("optional") != ""
+		}
+	}
+	return false
+}
+
+// colorModes are the values of --color, including true and false for compatibility with it being a boolean flag
+var colorModes = []string{"never", "auto", "always", "true", "false"}
+
+// isCookie checks if an argument is an EDNS0 cookie in hex, either an 8 byte client cookie or a 16 to 40 byte client and
+// server cookie (RFC 7873 section 4)
+func isCookie(arg string) bool {
+	if n := len(arg); n != 16 && (n < 32 || n > 80 || n%2 != 0) {
+		return false
+	}
+	_, err := hex.DecodeString(arg)
+	return err == nil
+}
+
+// AddEqualSigns adds equal signs between flags and their values, ignoring boolean flags and flags with optional values
 func AddEqualSigns(args []string) []string {
 	var newArgs []string
 	skip := false
@@ -232,12 +486,19 @@ func AddEqualSigns(args []string) []string {
 			continue
 		}
 
-		isFlag := arg[0] == '-'
+		isFlag := arg[0] == '-' && !strings.Contains(arg, "=") // Flags with an equal sign are already joined
 		flagName := strings.TrimLeft(arg, "-")
 
-		if isFlag && isBool(flagName) { // Standalone boolean flag
+		if isFlag && flagName == "color" && (i+1 == len(args) || !slices.Contains(colorModes, args[i+1])) {
+			// A bare --color enables color like +color
+			newArgs = append(newArgs, arg+"=always")
+		} else if isFlag && flagName == "cookie" && i+1 < len(args) && isCookie(args[i+1]) {
+			// --cookie HEX sets the cookie like --cookie=HEX instead of generating one
+			newArgs = append(newArgs, arg+"="+args[i+1])
+			skip = true
+		} else if isFlag && (isBool(flagName) || hasOptionalValue(flagName)) { // Standalone boolean flag or flag without a value
 			newArgs = append(newArgs, arg)
-		} else if isFlag && !isBool(flagName) { // Flag with mapping
+		} else if isFlag { // Flag with mapping
 			if i+1 < len(args) {
 				nextArg := args[i+1]
 				if nextArg[0] == '@' { // Skip joining if the next argument starts with @
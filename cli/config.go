@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServerVar is the environment variable that sets the server if none is given on the command line
+const ServerVar = "Q_SERVER"
+
+// DefaultServerVar is an alias of ServerVar, which takes precedence if both are set
+const DefaultServerVar = "Q_DEFAULT_SERVER"
+
+// EnvServer returns the server set by ServerVar or DefaultServerVar and the name of the variable it's set by, or empty
+// strings if neither is set
+func EnvServer() (string, string) {
+	for _, env := range []string{ServerVar, DefaultServerVar} {
+		if server := os.Getenv(env); server != "" {
+			return server, env
+		}
+	}
+	return "", ""
+}
+
+// Config is the q configuration file
+type Config struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile is a named set of flags keyed by their long name (e.g. server, tls-insecure-skip-verify, nsid)
+type Profile map[string]any
+
+// DefaultConfigPath returns the default location of the configuration file
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "q", "config.yaml")
+}
+
+// LoadConfig reads a configuration file
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// Profile looks up a profile by name
+func (c *Config) Profile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		var names []string
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %s not found. available profiles: %+v", name, names)
+	}
+	return profile, nil
+}
+
+// Args converts a profile to command line arguments, skipping any flag that is also set in args or by its environment variable
+// so that the precedence is command line, environment, profile, then defaults
+func (p Profile) Args(args []string) ([]string, error) {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var profileArgs []string
+	for _, key := range keys {
+		field, ok := flagField(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown flag %s in profile", key)
+		}
+		if flagSet(field, args) {
+			continue
+		}
+
+		values, ok := p[key].([]any)
+		if !ok {
+			values = []any{p[key]}
+		}
+		for _, value := range values {
+			profileArgs = append(profileArgs, fmt.Sprintf("--%s=%v", key, value))
+		}
+	}
+	return profileArgs, nil
+}
+
+// FlagValue returns the value of a flag from an argument list, or an empty string if it isn't set
+func FlagValue(args []string, name string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			return value
+		}
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// flagField finds the Flags field for a long flag name
+func flagField(name string) (reflect.StructField, bool) {
+	vT := reflect.TypeOf(Flags{})
+	for i := 0; i < vT.NumField(); i++ {
+		if vT.Field(i).Tag.Get("long") == name {
+			return vT.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// flagSet checks if a flag is set in an argument list by its short, long, or +[no]flag name, or by its environment variable
+func flagSet(field reflect.StructField, args []string) bool {
+	long := field.Tag.Get("long")
+	short := field.Tag.Get("short")
+
+	if env := field.Tag.Get("env"); env != "" && os.Getenv(env) != "" {
+		return true
+	}
+	if server, _ := EnvServer(); long == "server" && server != "" {
+		return true
+	}
+
+	for _, arg := range args {
+		name, _, _ := strings.Cut(arg, "=")
+		switch {
+		case name == "--"+long, name == "+"+long, name == "+no"+long:
+			return true
+		case short != "" && name == "-"+short:
+			return true
+		case long == "server" && strings.HasPrefix(arg, "@"):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	log "github.com/sirupsen/logrus"
+)
+
+// serverParams maps the query parameters a server URL can carry to the long names of the flags they set. Only query
+// and transport options are allowed, since other flags don't describe how to talk to a server.
+var serverParams = map[string]string{
+	"dnssec":     "dnssec",
+	"do":         "dnssec",
+	"pad":        "pad",
+	"padding":    "pad",
+	"nsid":       "nsid",
+	"subnet":     "subnet",
+	"ecs":        "subnet",
+	"cookie":     "cookie",
+	"expire":     "expire",
+	"key-tag":    "key-tag",
+	"udp-buffer": "udp-buffer",
+	"ad":         "ad",
+	"cd":         "cd",
+	"rd":         "rd",
+	"timeout":    "timeout",
+	"http2":      "http2",
+	"http3":      "http3",
+	"tfo":        "tfo",
+	"sni":        "tls-server-name",
+	"insecure":   "tls-insecure-skip-verify",
+	"pin-sha256": "pin-sha256",
+}
+
+// ServerParams sets the flags given as query parameters of a server URL (e.g. https://dns.example/dns-query?dnssec=1)
+// and returns the server without them. Unknown parameters are kept in DoH URLs, since the endpoint may use its own,
+// and dropped from other servers, with a warning either way. Flags apply to every server, so known parameters are
+// rejected when there are multiple servers.
+func ServerParams(parser *flags.Parser, server string, multiple bool) (string, error) {
+	base, rawQuery, ok := strings.Cut(server, "?")
+	if !ok {
+		return server, nil
+	}
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("parsing options of server %s: %s", server, err)
+	}
+	isHTTP := strings.HasPrefix(base, "https://") || strings.HasPrefix(base, "http://")
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	unknown := url.Values{}
+	for _, key := range keys {
+		name, ok := serverParams[strings.ToLower(key)]
+		if !ok {
+			if isHTTP {
+				log.Warnf("Unknown option %s in server %s, passing it to the DoH server", key, base)
+				unknown[key] = params[key]
+			} else {
+				log.Warnf("Ignoring unknown option %s in server %s", key, base)
+			}
+			continue
+		}
+		if multiple {
+			return "", fmt.Errorf("option %s in server %s would apply to every server, set --%s instead or query one server at a time", key, base, name)
+		}
+
+		option := parser.FindOptionByLongName(name)
+		for _, value := range params[key] {
+			// Set a bare parameter like the flag without a value
+			if value == "" && option.Field().Type.Kind() == reflect.Bool {
+				value = "true"
+			} else if value == "" && len(option.OptionalValue) > 0 {
+				value = option.OptionalValue[0]
+			}
+			log.Debugf("Setting --%s=%s from server %s", name, value, base)
+			if err := option.Set(&value); err != nil {
+				return "", fmt.Errorf("invalid option %s=%s in server %s: %s", key, value, base, err)
+			}
+		}
+	}
+
+	if len(unknown) > 0 {
+		return base + "?" + unknown.Encode(), nil
+	}
+	return base, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/transport"
+)
+
+// followCNAMEs extends a chain of names by following CNAME records in answers, returning the extended chain and whether it loops
+func followCNAMEs(chain []string, answers []dns.RR) ([]string, bool) {
+	for {
+		current := chain[len(chain)-1]
+		var target string
+		for _, rr := range answers {
+			if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, current) {
+				target = cname.Target
+				break
+			}
+		}
+		if target == "" {
+			return chain, false
+		}
+
+		for _, name := range chain {
+			if strings.EqualFold(name, target) {
+				return append(chain, target), true
+			}
+		}
+		chain = append(chain, target)
+	}
+}
+
+// hasRRType checks if answers contain a record of a given type for a name
+func hasRRType(answers []dns.RR, name string, rrType uint16) bool {
+	for _, rr := range answers {
+		if rr.Header().Rrtype == rrType && strings.EqualFold(rr.Header().Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// chaseCNAMEs follows the CNAME chain in a reply up to maxDepth hops, querying for targets that the reply doesn't answer
+// and appending their answers to the reply. It returns an error with the full cycle if the chain loops.
+func chaseCNAMEs(ctx context.Context, txp *transport.Transport, msg, reply *dns.Msg, maxDepth int) error {
+	q := msg.Question[0]
+	if q.Qtype == dns.TypeCNAME || q.Qtype == dns.TypeANY {
+		return nil
+	}
+
+	chain := []string{q.Name}
+	for {
+		var loop bool
+		chain, loop = followCNAMEs(chain, reply.Answer)
+		if loop {
+			return fmt.Errorf("CNAME loop: %s", strings.Join(chain, " -> "))
+		}
+		if len(chain)-1 > maxDepth {
+			return fmt.Errorf("CNAME chain exceeds max depth %d: %s", maxDepth, strings.Join(chain, " -> "))
+		}
+
+		target := chain[len(chain)-1]
+		if len(chain) == 1 || hasRRType(reply.Answer, target, q.Qtype) {
+			return nil
+		}
+
+		log.Debugf("Chasing CNAME target %s (depth %d)", target, len(chain)-1)
+		next, err := queryType(ctx, txp, target, q.Qtype)
+		if err != nil {
+			return fmt.Errorf("chasing CNAME target %s: %s", target, err)
+		}
+		if len(next.Answer) == 0 {
+			return nil
+		}
+		reply.Answer = append(reply.Answer, next.Answer...)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// cookieAuto is the --cookie value set when no cookie is given, which generates a random client cookie and
+// validates the server cookie in the reply
+const cookieAuto = "auto"
+
+// checkCookie validates that a reply echoes the client cookie of a query with an 8 to 32 byte server cookie
+// (RFC 7873 section 4), returning a description and the full cookie if the server sent a valid one
+func checkCookie(msg, reply *dns.Msg) (string, string) {
+	sent, ok := util.EDNSOption[*dns.EDNS0_COOKIE](msg)
+	if !ok || len(sent.Cookie) < 16 {
+		return "no client cookie sent", ""
+	}
+	client := sent.Cookie[:16]
+
+	received, ok := util.EDNSOption[*dns.EDNS0_COOKIE](reply)
+	switch {
+	case !ok:
+		return "no cookie in reply", ""
+	case len(received.Cookie) < 16 || received.Cookie[:16] != client:
+		return fmt.Sprintf("client cookie mismatch (sent %s, received %s)", client, received.Cookie), ""
+	case len(received.Cookie) == 16:
+		return "client cookie echoed without a server cookie", ""
+	}
+
+	server := received.Cookie[16:]
+	if n := len(server) / 2; n < 8 || n > 32 {
+		return fmt.Sprintf("client cookie echoed with an invalid %d byte server cookie", n), ""
+	}
+	return fmt.Sprintf("full cookie, client %s server %s (%d bytes)", client, server, len(server)/2), received.Cookie
+}
+
+// checkFollowUpCookie describes whether a reply to a query sent with a full cookie returned the same server cookie
+func checkFollowUpCookie(cookie string, reply *dns.Msg) string {
+	if reply.Rcode == dns.RcodeBadCookie {
+		return "BADCOOKIE, server rejected its own cookie"
+	}
+	received, ok := util.EDNSOption[*dns.EDNS0_COOKIE](reply)
+	switch {
+	case !ok:
+		return "no cookie in reply"
+	case received.Cookie == cookie:
+		return "server cookie matched"
+	case len(received.Cookie) > 16 && received.Cookie[:16] == cookie[:16]:
+		return fmt.Sprintf("server cookie changed to %s", received.Cookie[16:])
+	default:
+		return fmt.Sprintf("client cookie mismatch (sent %s, received %s)", cookie[:16], received.Cookie)
+	}
+}
+
+// queryCookie returns the hex cookie of a query, or an empty string if it doesn't have one
+func queryCookie(msg *dns.Msg) string {
+	if cookie, ok := util.EDNSOption[*dns.EDNS0_COOKIE](msg); ok {
+		return cookie.Cookie
+	}
+	return ""
+}
+
+// cookieHandshake returns the handshake of a query whose cookie changed from the one it was sent with, since it was
+// retried with the server cookie from a BADCOOKIE reply
+func cookieHandshake(sent string, msg *dns.Msg) (output.CookieHandshake, bool) {
+	cookie := queryCookie(msg)
+	if cookie == sent || len(cookie) <= 16 || len(msg.Question) == 0 {
+		return output.CookieHandshake{}, false
+	}
+	q := msg.Question[0]
+	return output.CookieHandshake{
+		Question:     fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype]),
+		ServerCookie: cookie[16:],
+	}, true
+}
+
+// cookieRoundTrip validates the cookie of the first reply from a server and, if it's a full cookie, sends a follow-up
+// query with it. It returns the full cookie to send with later queries, or an empty string if there isn't one.
+func cookieRoundTrip(ctx context.Context, txp *transport.Transport, msg, reply *dns.Msg) string {
+	status, cookie := checkCookie(msg, reply)
+	log.Debugf("Cookie: %s", status)
+	if cookie == "" {
+		return ""
+	}
+
+	// Exchange directly so that a BADCOOKIE reply isn't retried
+	followUp, err := (*txp).Exchange(ctx, cookieQuery(*msg, cookie))
+	if err != nil {
+		log.Debugf("Cookie follow-up: %s", err)
+		return cookie
+	}
+	log.Debugf("Cookie follow-up: %s", checkFollowUpCookie(cookie, followUp))
+	return cookie
+}
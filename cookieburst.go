@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/util"
+)
+
+// cookieBurstTimeout is the longest to wait for each reply in a burst, since rate limited queries are usually dropped
+const cookieBurstTimeout = time.Second
+
+// burstResult counts how a server handled a burst of UDP queries
+type burstResult struct {
+	answered  int // Full responses
+	truncated int // TC set, commonly sent by response rate limiting to make the client retry over TCP
+	refused   int
+	dropped   int // No response before the timeout
+}
+
+// cookieQuery copies a query with its EDNS0 cookie option replaced by cookie, or removed if cookie is empty
+func cookieQuery(msg dns.Msg, cookie string) *dns.Msg {
+	query := msg.Copy()
+	opt := query.IsEdns0()
+	if opt == nil {
+		query.SetEdns0(opts.UDPBuffer, false)
+		opt = query.IsEdns0()
+	}
+	var options []dns.EDNS0
+	for _, o := range opt.Option {
+		if o.Option() != dns.EDNS0COOKIE {
+			options = append(options, o)
+		}
+	}
+	if cookie != "" {
+		options = append(options, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie})
+	}
+	opt.Option = options
+	return query
+}
+
+// serverCookie sends a query with a client cookie and returns the full client and server cookie from the reply
+func serverCookie(ctx context.Context, msg dns.Msg, server string) (string, error) {
+	clientCookie := fmt.Sprintf("%016x", rand.Uint64())
+	client := dns.Client{Net: "udp", UDPSize: opts.UDPBuffer, Timeout: opts.Timeout}
+	reply, _, err := client.ExchangeContext(ctx, cookieQuery(msg, clientCookie), server)
+	if err != nil {
+		return "", err
+	}
+
+	cookie, ok := util.EDNSOption[*dns.EDNS0_COOKIE](reply)
+	if !ok || len(cookie.Cookie) <= 16 {
+		return "", fmt.Errorf("server didn't return a server cookie")
+	}
+	if cookie.Cookie[:16] != clientCookie {
+		return "", fmt.Errorf("server returned a cookie for a different client cookie")
+	}
+	return cookie.Cookie, nil
+}
+
+// burst sends n copies of a query at once over UDP without TCP fallback and counts how they were handled
+func burst(ctx context.Context, query *dns.Msg, server string, n int) burstResult {
+	var result burstResult
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q := query.Copy()
+			q.Id = dns.Id()
+			client := dns.Client{Net: "udp", UDPSize: opts.UDPBuffer, Timeout: min(opts.Timeout, cookieBurstTimeout)}
+			reply, _, err := client.ExchangeContext(ctx, q, server)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				result.dropped++
+			case reply.Truncated:
+				result.truncated++
+			case reply.Rcode == dns.RcodeRefused:
+				result.refused++
+			default:
+				result.answered++
+			}
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+// classifyCookieBursts describes whether a valid server cookie exempted queries from rate limiting
+func classifyCookieBursts(without, with burstResult, n int) (string, bool) {
+	switch {
+	case without.answered == n && with.answered == n:
+		return "no rate limiting observed, try a larger burst", false
+	case with.answered > without.answered:
+		return fmt.Sprintf("cookie bypasses rate limit (%d more answered with a cookie)", with.answered-without.answered), true
+	case with.answered == without.answered:
+		return "cookie doesn't bypass rate limit (same number answered)", false
+	default:
+		return fmt.Sprintf("cookie doesn't bypass rate limit (%d fewer answered with a cookie)", without.answered-with.answered), false
+	}
+}
+
+// cookieRateLimitTest sends a burst of queries without a cookie and then with a valid server cookie and reports
+// whether the cookie lets queries bypass response rate limiting (RFC 7873 section 5.2.3)
+func cookieRateLimitTest(ctx context.Context, msgs []dns.Msg, server string, n int, out io.Writer) error {
+	if len(msgs) == 0 {
+		return fmt.Errorf("no query to send")
+	}
+
+	cookie, err := serverCookie(ctx, msgs[0], server)
+	if err != nil {
+		return fmt.Errorf("getting server cookie: %s", err)
+	}
+	log.Debugf("Got server cookie %s", cookie[16:])
+
+	without := burst(ctx, cookieQuery(msgs[0], ""), server, n)
+	// Let the rate limit window pass between bursts
+	if err := sleep(ctx, cookieBurstTimeout); err != nil {
+		return err
+	}
+	with := burst(ctx, cookieQuery(msgs[0], cookie), server, n)
+
+	for _, b := range []struct {
+		label  string
+		result burstResult
+	}{
+		{"without cookie", without},
+		{"with cookie", with},
+	} {
+		util.MustWritef(out, "%-14s %d/%d answered, %d truncated, %d refused, %d dropped\n",
+			b.label, b.result.answered, n, b.result.truncated, b.result.refused, b.result.dropped)
+	}
+
+	description, ok := classifyCookieBursts(without, with, n)
+	color := util.ColorGreen
+	if !ok {
+		color = util.ColorYellow
+	}
+	util.MustWriteln(out, util.Color(color, description))
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// withDO returns a copy of a query with the DO bit set or cleared, adding an OPT record if it doesn't have one
+func withDO(msg *dns.Msg, do bool) *dns.Msg {
+	m := msg.Copy()
+	if opt := m.IsEdns0(); opt != nil {
+		opt.SetDo(do)
+	} else {
+		m.SetEdns0(opts.UDPBuffer, do)
+	}
+	return m
+}
+
+// responseSize returns the size of a reply on the wire, assuming the server compressed it
+func responseSize(reply *dns.Msg) int {
+	r := reply.Copy()
+	r.Compress = true
+	return r.Len()
+}
+
+// dnssecOverhead sends each query without and then with the DO bit set and reports how much larger DNSSEC records make
+// the response
+func dnssecOverhead(ctx context.Context, msgs []dns.Msg, txp *transport.Transport, out io.Writer) error {
+	if len(msgs) == 0 {
+		return fmt.Errorf("no query to send")
+	}
+	for i := range msgs {
+		without, err := exchange(ctx, txp, withDO(&msgs[i], false))
+		if err != nil {
+			return fmt.Errorf("query without DO: %s", err)
+		}
+		with, err := exchange(ctx, txp, withDO(&msgs[i], true))
+		if err != nil {
+			return fmt.Errorf("query with DO: %s", err)
+		}
+
+		var signatures int
+		for _, section := range [][]dns.RR{with.Answer, with.Ns, with.Extra} {
+			for _, rr := range section {
+				if rr.Header().Rrtype == dns.TypeRRSIG {
+					signatures++
+				}
+			}
+		}
+
+		before, after := responseSize(without), responseSize(with)
+		delta := fmt.Sprintf("%+d B", after-before)
+		if before > 0 {
+			delta += fmt.Sprintf(", %+.1f%%", float64(after-before)/float64(before)*100)
+		}
+		util.MustWritef(out, "%s %s: %s without DO, %s with DO (%s), %d RRSIGs\n",
+			questionName(&msgs[i]),
+			dns.TypeToString[msgs[i].Question[0].Qtype],
+			util.Color(util.ColorPurple, fmt.Sprintf("%d B", before)),
+			util.Color(util.ColorPurple, fmt.Sprintf("%d B", after)),
+			util.Color(util.ColorYellow, delta),
+			signatures,
+		)
+		if after > int(opts.UDPBuffer) && before <= int(opts.UDPBuffer) {
+			util.MustWritef(out, "  DNSSEC response exceeds the %d byte UDP payload size and needs TCP\n", opts.UDPBuffer)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+// addressFamilies maps plain transport address families to their display names
+var addressFamilies = []struct {
+	family string
+	name   string
+}{
+	{"4", "IPv4"},
+	{"6", "IPv6"},
+}
+
+// queryFamily sends a query to a server over a single address family
+func queryFamily(ctx context.Context, r *resolver, msg dns.Msg, server, family, name string) output.FamilyResult {
+	result := output.FamilyResult{Family: name}
+
+	txp, err := r.newTransport(server, transport.TypePlain, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer (*txp).Close()
+	(*txp).(*transport.Plain).Family = family
+
+	start := time.Now()
+	reply, err := exchange(ctx, txp, &msg)
+	result.RTT = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Rcode = dns.RcodeToString[reply.Rcode]
+	if len(reply.Answer) > 0 {
+		result.Answers = output.AnswerSet([]*dns.Msg{reply})
+	}
+	return result
+}
+
+// compareFamilies sends each query to a server over both IPv4 and IPv6 and reports differences in answers and latency
+func compareFamilies(ctx context.Context, r *resolver, msgs []dns.Msg, server string, out io.Writer) error {
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		return fmt.Errorf("parsing server %s: %s", server, err)
+	}
+	if net.ParseIP(host) != nil {
+		return fmt.Errorf("comparing address families requires a server hostname, got address %s", host)
+	}
+
+	var comparisons []output.FamilyComparison
+	for _, msg := range msgs {
+		var results []output.FamilyResult
+		for _, f := range addressFamilies {
+			log.Debugf("Querying %s over %s for %s", server, f.name, questionName(&msg))
+			results = append(results, queryFamily(ctx, r, *msg.Copy(), server, f.family, f.name))
+		}
+		q := msg.Question[0]
+		comparisons = append(comparisons, output.CompareFamilies(fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype]), results))
+	}
+
+	printer := output.Printer{
+		Out:  out,
+		Opts: &opts,
+	}
+	printer.PrintFamilyComparisons(comparisons)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// headerOnlyQuery sends a query with a header and no question to check that a server responds, regardless of its zone data.
+// Any reply counts as reachable, since servers commonly answer an empty question with FORMERR.
+func headerOnlyQuery(ctx context.Context, server string, txp *transport.Transport, out io.Writer) error {
+	msg := &dns.Msg{MsgHdr: dns.MsgHdr{Id: dns.Id(), Opcode: dns.OpcodeQuery}}
+	if opts.ID != -1 {
+		msg.Id = uint16(opts.ID)
+	}
+
+	start := time.Now()
+	reply, err := exchange(ctx, txp, msg)
+	latency := time.Since(start)
+	if err != nil {
+		util.MustWritef(out, "%s %s (%s)\n", server, util.Color(util.ColorRed, "unreachable"), err)
+		return fmt.Errorf("%s is unreachable", server)
+	}
+
+	util.MustWritef(out, "%s %s in %s (%s)\n",
+		server,
+		util.Color(util.ColorGreen, "reachable"),
+		util.Color(util.ColorTeal, latency.Round(time.Microsecond)),
+		dns.RcodeToString[reply.Rcode],
+	)
+	return nil
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/url"
 	"os"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jedisct1/go-dnsstamps"
@@ -26,8 +30,6 @@ import (
 	tlsutil "github.com/natesales/q/util/tls"
 )
 
-const defaultServerVar = "Q_DEFAULT_SERVER"
-
 var opts = cli.Flags{}
 
 // Build process flags
@@ -41,18 +43,29 @@ var (
 func clearOpts() {
 	opts = cli.Flags{}
 	cli.SetDefaultTrueBools(&opts)
+}
 
-	// Enable color output if stdout is a terminal
-	if fileInfo, _ := os.Stdout.Stat(); (fileInfo.Mode() & os.ModeCharDevice) != 0 {
-		opts.Color = true
-	}
-
-	// Disable color output if NO_COLOR env var is set
-	if os.Getenv("NO_COLOR") != "" {
-		log.Debug("NO_COLOR set")
-		opts.Color = false
+// useColor returns whether to color output written to out in a --color mode, where auto colors terminals unless the
+// NO_COLOR environment variable is set (https://no-color.org)
+func useColor(mode string, out io.Writer) (bool, error) {
+	switch mode {
+	case "always", "true":
+		return true, nil
+	case "never", "false":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			log.Debug("NO_COLOR set")
+			return false, nil
+		}
+		f, ok := out.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		fileInfo, err := f.Stat()
+		return err == nil && fileInfo.Mode()&os.ModeCharDevice != 0, nil
 	}
-	util.UseColor = opts.Color
+	return false, fmt.Errorf("invalid color mode %s. expected: never, auto, or always", mode)
 }
 
 func txtConcat(m *dns.Msg) {
@@ -145,7 +158,7 @@ func parseServer(s string) (string, transport.Type, error) {
 		}
 	}
 
-	// Check if server starts with a scheme, if not, default to plain
+	// Check if server starts with a scheme, if not, default to --transport or plain
 	schemeRe := regexp.MustCompile(`^[a-zA-Z0-9]+://`)
 	if !schemeRe.MatchString(s) {
 		// Enclose in brackets if IPv6
@@ -153,7 +166,11 @@ func parseServer(s string) (string, transport.Type, error) {
 		if v6re.MatchString(s) {
 			s = "[" + s + "]"
 		}
-		s = "plain://" + s
+		scheme := string(transport.TypePlain)
+		if opts.Transport != "" {
+			scheme = opts.Transport
+		}
+		s = scheme + "://" + s
 	}
 
 	// Parse server as URL
@@ -167,6 +184,9 @@ func parseServer(s string) (string, transport.Type, error) {
 	if tu.Scheme == "https" { // Override HTTPS to HTTP, preserving tu.Scheme as HTTPS
 		ts = transport.TypeHTTP
 	}
+	if tu.Scheme == "wss" { // Override WSS to WS, preserving tu.Scheme as WSS
+		ts = transport.TypeWS
+	}
 	if !slices.Contains(transport.Types, ts) {
 		return "", "", fmt.Errorf("unsupported transport %s. expected: %+v", ts, transport.Types)
 	}
@@ -176,8 +196,8 @@ func parseServer(s string) (string, transport.Type, error) {
 		switch ts {
 		case transport.TypeQUIC, transport.TypeTLS:
 			setPort(tu, 853)
-		case transport.TypeHTTP:
-			if tu.Scheme == "https" {
+		case transport.TypeHTTP, transport.TypeWS:
+			if tu.Scheme == "https" || tu.Scheme == "wss" {
 				setPort(tu, 443)
 			} else {
 				setPort(tu, 80)
@@ -194,7 +214,7 @@ func parseServer(s string) (string, transport.Type, error) {
 
 	server := tu.String()
 	// Remove scheme from server if irrelevant to protocol
-	if ts != transport.TypeHTTP {
+	if ts != transport.TypeHTTP && ts != transport.TypeWS {
 		server = strings.Split(server, "://")[1]
 	}
 
@@ -206,13 +226,397 @@ func parseServer(s string) (string, transport.Type, error) {
 	return server, ts, nil
 }
 
+// runMode runs a special query mode against a server, returning false if no mode is enabled
+func runMode(ctx context.Context, r *resolver, serverStr string, msgs []dns.Msg, tlsConfig *tls.Config, out io.Writer) (bool, error) {
+	if !opts.RecAXFR && opts.Sweep == "" && !opts.LimitAnswer && opts.CheckSecondaries == "" && !opts.HeaderOnly &&
+		opts.CheckCDS == "" && opts.CacheHitRatio == "" && opts.NegativeCacheTest == "" && opts.TraceGraph == "" &&
+		!opts.CheckRecursion && !opts.CompareFamily && opts.CookieRateLimit == 0 &&
+		opts.Service == "" && !opts.CheckPoisoning && opts.SampleDuration == 0 && opts.Tail == 0 && opts.ReplayPcap == "" &&
+		opts.QNAMEMinimization == "" && !opts.DNSSECOverhead && !opts.ValidateTargets && transferQuery(msgs) == nil {
+		return false, nil
+	}
+
+	// Parse server address and transport type
+	server, transportType, err := parseServer(serverStr)
+	if err != nil {
+		return true, fmt.Errorf("parsing server %s: %s", serverStr, err)
+	}
+	log.Debugf("Using server %s with transport %s", server, transportType)
+
+	// Recursive zone transfer
+	if opts.RecAXFR {
+		if opts.Name == "" {
+			return true, fmt.Errorf("no name specified for AXFR")
+		}
+		_, err := RecAXFR(ctx, opts.Name, server, out)
+		return true, err
+	}
+
+	// Zone transfer
+	if msg := transferQuery(msgs); msg != nil {
+		return true, streamTransfer(ctx, msg, server, transportType, out)
+	}
+
+	// Reverse sweep of a CIDR range
+	if opts.Sweep != "" {
+		return true, sweep(ctx, r, opts.Sweep, server, transportType, tlsConfig, out)
+	}
+
+	// Delegation chain graph
+	if opts.TraceGraph != "" {
+		if transportType != transport.TypePlain {
+			return true, fmt.Errorf("trace graph requires a plain DNS server")
+		}
+		return true, traceGraph(ctx, r, opts.TraceGraph, msgs, server, out)
+	}
+
+	// IPv4 and IPv6 comparison
+	if opts.CompareFamily {
+		if transportType != transport.TypePlain {
+			return true, fmt.Errorf("address family comparison requires a plain DNS server")
+		}
+		return true, compareFamilies(ctx, r, msgs, server, out)
+	}
+
+	// Cookie rate limit bypass test
+	if opts.CookieRateLimit > 0 {
+		if transportType != transport.TypePlain {
+			return true, fmt.Errorf("cookie rate limit test requires a plain DNS server")
+		}
+		return true, cookieRateLimitTest(ctx, msgs, server, opts.CookieRateLimit, out)
+	}
+
+	// Truncation behavior test
+	if opts.LimitAnswer {
+		if transportType != transport.TypePlain {
+			return true, fmt.Errorf("truncation test requires a plain DNS server")
+		}
+		return true, truncationTest(ctx, msgs, server, out)
+	}
+
+	// Create transport
+	txp, err := r.newTransport(server, transportType, tlsConfig)
+	if err != nil {
+		return true, fmt.Errorf("creating transport: %s", err)
+	}
+	defer (*txp).Close()
+
+	switch {
+	case opts.CheckSecondaries != "": // Secondary SOA/expire check
+		return true, checkSecondaries(ctx, r, opts.CheckSecondaries, strconv.Itoa(int(opts.SecondaryPort)), txp, out)
+	case opts.HeaderOnly: // Liveness check without a question
+		return true, headerOnlyQuery(ctx, server, txp, out)
+	case opts.CheckCDS != "": // CDS/CDNSKEY comparison with the parent DS
+		return true, checkCDS(ctx, opts.CheckCDS, txp, out)
+	case opts.Service != "": // DNS-SD service discovery
+		return true, discoverService(ctx, opts.Service, opts.Name, txp, out)
+	case opts.CheckPoisoning: // Anti-poisoning indicators
+		return true, poisoningResistance(ctx, msgs, txp, out)
+	case opts.CheckRecursion: // Open recursion check
+		return true, checkRecursion(ctx, msgs, server, txp, out)
+	case opts.QNAMEMinimization != "": // QNAME minimization test
+		return true, probeQNAMEMinimization(ctx, opts.QNAMEMinimization, server, txp, out)
+	case opts.DNSSECOverhead: // DO bit response size difference
+		return true, dnssecOverhead(ctx, msgs, txp, out)
+	case opts.ValidateTargets: // NS and MX target resolution
+		return true, validateTargets(ctx, msgs, txp, out)
+	case opts.Tail != 0: // Timestamped answer log
+		return true, tailAnswers(ctx, msgs, server, txp, out)
+	case opts.SampleDuration > 0: // Latency series over time
+		return true, sampleLatency(ctx, msgs, server, txp, out)
+	case opts.ReplayPcap != "": // Captured query replay
+		return true, replayPcap(ctx, opts.ReplayPcap, txp, out)
+	case opts.CacheHitRatio != "": // Cache hit ratio over a list of names
+		return true, measureCacheHits(ctx, opts.CacheHitRatio, txp, out)
+	default: // Negative caching test
+		return true, negativeCacheTest(ctx, opts.NegativeCacheTest, txp, out)
+	}
+}
+
+// queryServer sends every query to a single server and collects the replies into an entry
+func queryServer(ctx context.Context, r *resolver, serverStr string, msgs []dns.Msg, tlsConfig *tls.Config) (*output.Entry, error) {
+	// Parse server address and transport type
+	server, transportType, err := parseServer(serverStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing server %s: %s", serverStr, err)
+	}
+	log.Debugf("Using server %s with transport %s", server, transportType)
+	if opts.TSIG != "" && !tsigTransport(transportType) {
+		return nil, fmt.Errorf("TSIG is only supported over plain DNS and TCP, not %s", transportType)
+	}
+
+	// Create transport
+	txp, err := r.newTransport(server, transportType, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating transport: %s", err)
+	}
+	defer (*txp).Close()
+
+	// Copy the queries since retries may change their ID, cookie, and EDNS version
+	queries := make([]dns.Msg, len(msgs))
+	for i := range msgs {
+		queries[i] = *msgs[i].Copy()
+	}
+
+	startTime := time.Now()
+	var replies []*dns.Msg
+	var durations []time.Duration
+	var timings []transport.Timings
+	var inconsistencies []output.Inconsistency
+	var handshakes []output.CookieHandshake
+	for i := range queries {
+		msg := &queries[i]
+		cookie := queryCookie(msg)
+		exchangeStart := time.Now()
+		reply, err := exchange(ctx, txp, msg)
+		durations = append(durations, time.Since(exchangeStart))
+		if opts.TSIG != "" {
+			err = tsigError(reply, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("exchange: %s", err)
+		}
+
+		if reply == nil {
+			return nil, fmt.Errorf("no reply from server")
+		}
+		if h, ok := cookieHandshake(cookie, msg); ok {
+			handshakes = append(handshakes, h)
+		}
+
+		if opts.ShowOpt {
+			for _, o := range reply.Extra {
+				if o.Header().Rrtype == dns.TypeOPT {
+					fmt.Printf("OPT: %v\n", o)
+				}
+			}
+		}
+
+		if transportType != transport.TypeQUIC && opts.IDCheck && reply.Id != msg.Id {
+			return nil, fmt.Errorf("ID mismatch: expected %d, got %d", msg.Id, reply.Id)
+		}
+		if opts.Cookie == cookieAuto && i == 0 {
+			if cookie := cookieRoundTrip(ctx, txp, msg, reply); cookie != "" {
+				for j := i + 1; j < len(queries); j++ {
+					queries[j] = *cookieQuery(queries[j], cookie)
+				}
+			}
+		}
+		if opts.Verify {
+			inconsistency, err := verifyReply(ctx, txp, msg, reply)
+			if err != nil {
+				return nil, fmt.Errorf("verify: %s", err)
+			}
+			if inconsistency != nil {
+				inconsistencies = append(inconsistencies, *inconsistency)
+			}
+		}
+		if opts.MaxCNAMEDepth > 0 && reply.Rcode == dns.RcodeSuccess {
+			if err := chaseCNAMEs(ctx, txp, msg, reply, opts.MaxCNAMEDepth); err != nil {
+				return nil, err
+			}
+		}
+		replies = append(replies, reply)
+		if timer, ok := (*txp).(transport.Timer); ok {
+			timings = append(timings, timer.Timings())
+		}
+	}
+
+	// Process TXT parsing
+	if opts.TXTConcat {
+		for _, reply := range replies {
+			txtConcat(reply)
+		}
+	}
+
+	// Round TTL
+	if opts.RoundTTLs {
+		for _, reply := range replies {
+			for _, rr := range reply.Answer {
+				rr.Header().Ttl = rr.Header().Ttl - (rr.Header().Ttl % 60)
+			}
+		}
+	}
+
+	e := &output.Entry{
+		Queries:   queries,
+		Replies:   replies,
+		Server:    server,
+		Transport: string(transportType),
+		Time:      time.Since(startTime),
+		Durations: durations,
+		Timings:   timings,
+
+		CookieHandshakes: handshakes,
+		Inconsistencies:  inconsistencies,
+	}
+
+	e.LoadTLS(txp)
+
+	if opts.Zone && len(queries) > 0 && len(queries[0].Question) > 0 {
+		if e.Zone, err = findZone(ctx, txp, queries[0].Question[0].Name); err != nil {
+			return nil, fmt.Errorf("finding zone: %s", err)
+		}
+	}
+
+	if opts.ResolveIPs {
+		e.LoadPTRs(ctx, txp)
+	}
+	return e, nil
+}
+
+// queryServers queries every server with at most opts.ServerConcurrency in flight, returning entries in server order.
+// If done is not nil, it is called with each entry as soon as its server has been queried.
+func queryServers(ctx context.Context, r *resolver, servers []string, msgs []dns.Msg, tlsConfig *tls.Config, done func(*output.Entry)) ([]*output.Entry, error) {
+	entries := make([]*output.Entry, len(servers))
+	errs := make([]error, len(servers))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	// With --fail-fast, the first failure stops the remaining servers from being queried
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var failure error
+	var failOnce sync.Once
+	for w := 0; w < min(max(opts.ServerConcurrency, 1), len(servers)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				entries[i], errs[i] = queryServer(ctx, r, servers[i], msgs, tlsConfig)
+
+				// Comparisons report each server's error alongside the answers of the others
+				if errs[i] != nil && (opts.ContinueOnError || opts.Format == output.FormatCompare) {
+					log.Warnf("Querying %s: %s", servers[i], errs[i])
+					entries[i], errs[i] = &output.Entry{Queries: msgs, Server: servers[i], Error: errs[i].Error()}, nil
+				}
+				if opts.FailFast {
+					if errs[i] == nil {
+						errs[i] = checkEntry(entries[i])
+					}
+					if errs[i] != nil {
+						failOnce.Do(func() {
+							failure = fmt.Errorf("stopping at first failure from %s: %s", servers[i], errs[i])
+							cancel()
+						})
+					}
+				}
+				if done != nil && errs[i] == nil {
+					done(entries[i])
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range servers {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failure != nil {
+		return nil, failure
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// printEntries prints entries in the selected output format
+func printEntries(printer output.Printer, entries []*output.Entry) error {
+	if opts.NSIDOnly {
+		printer.PrettyPrintNSID(entries, false)
+		return nil
+	}
+	if opts.NSID && (opts.Format == output.FormatPretty || opts.Format == output.FormatColumn) {
+		printer.PrettyPrintNSID(entries, true)
+	}
+
+	switch opts.Format {
+	case output.FormatPretty:
+		printer.PrintPretty(entries)
+	case output.FormatColumn:
+		printer.PrintColumn(entries)
+	case output.FormatRAW:
+		printer.PrintRaw(entries)
+	case output.FormatCompare:
+		printer.PrintCompare(entries)
+	case output.FormatInflux:
+		printer.PrintInflux(entries)
+	case output.FormatShort:
+		printer.PrintShort(entries)
+	case output.FormatHTML:
+		printer.PrintHTML(entries)
+	case output.FormatJSON, output.FormatYAML, "yml":
+		printer.PrintStructured(entries)
+	default:
+		return fmt.Errorf("invalid output format %s", opts.Format)
+	}
+	return nil
+}
+
+// toASCII IDNA (punycode) normalizes a non-ASCII domain name, leaving reverse lookup names as they are
+func toASCII(name string) (string, error) {
+	// Skip if already an in-addr.arpa or ip6.arpa name
+	lowerName := strings.TrimSuffix(strings.ToLower(name), ".")
+	if strings.HasSuffix(lowerName, ".in-addr.arpa") || strings.HasSuffix(lowerName, ".ip6.arpa") {
+		return name, nil
+	}
+
+	// Allow underscores during IDNA conversion
+	_asciiName := strings.ReplaceAll(name, "_", "..")
+	asciiName, err := idna.Lookup.ToASCII(_asciiName)
+	if err != nil {
+		return "", fmt.Errorf("idna toascii: %s", err)
+	}
+	return strings.ReplaceAll(asciiName, "..", "_"), nil
+}
+
+// loadProfile reads a named profile from the config file and returns its flags that aren't already set in args
+func loadProfile(configFile, name string, args []string) ([]string, error) {
+	if configFile == "" {
+		configFile = cli.DefaultConfigPath()
+	}
+	config, err := cli.LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := config.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Args(args)
+}
+
 // driver is the "main" function for this program that accepts a flag slice for testing
 func driver(args []string, out io.Writer) error {
+	// Prepend flags from a profile so they can be overridden on the command line
+	if profileName := cli.FlagValue(args, "profile"); profileName != "" {
+		profileArgs, err := loadProfile(cli.FlagValue(args, "config"), profileName, args)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Using profile %s: %v", profileName, profileArgs)
+		args = append(profileArgs, args...)
+	}
+
 	args = cli.SetFalseBooleans(&opts, args)
 	args = cli.AddEqualSigns(args)
 	parser := flags.NewParser(&opts, flags.Default)
-	parser.Usage = `[OPTIONS] [@server] [type...] [name]
+	parser.Usage = `[OPTIONS] [@server] [type...] [class] [name]
 
+Positional arguments can be given in any order.
 All long form (--) flags can be toggled with the dig-standard +[no]flag notation.`
 	_, err := parser.ParseArgs(args)
 	if err != nil {
@@ -222,7 +626,12 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 		os.Exit(1)
 	}
 	cli.ParsePlusFlags(&opts, args)
-	util.UseColor = opts.Color
+	if util.UseColor, err = useColor(opts.Color, out); err != nil {
+		return err
+	}
+	if err := util.SetTheme(opts.Theme); err != nil {
+		return err
+	}
 
 	if opts.Verbose {
 		log.SetLevel(log.DebugLevel)
@@ -237,11 +646,27 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 	}
 
 	if opts.ShowAll {
+		opts.ShowHeader = true
 		opts.ShowQuestion = true
 		opts.ShowAnswer = true
 		opts.ShowAuthority = true
 		opts.ShowAdditional = true
 		opts.ShowStats = true
+		opts.ShowMeta = true
+		opts.ShowTimings = true
+	}
+	if opts.VerboseTiming {
+		opts.ShowTimings = true
+	}
+	if opts.ShowTLS {
+		opts.ShowMeta = true
+	}
+
+	if opts.JSONFlatten {
+		opts.Format = output.FormatJSON
+	}
+	if opts.ValueOnly && opts.Format == output.FormatPretty {
+		opts.Format = output.FormatShort
 	}
 
 	// Set bootstrap resolver
@@ -263,47 +688,185 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 		log.Debugf("Using bootstrap resolver %s", opts.BootstrapServer)
 	}
 
+	// Validate retry categories
+	if opts.RetryServfail && !slices.Contains(opts.RetryOn, retryServfail) {
+		opts.RetryOn = append(opts.RetryOn, retryServfail)
+	}
+	for _, category := range opts.RetryOn {
+		if !slices.Contains(retryCategories, category) {
+			return fmt.Errorf("invalid retry category %s. expected: %+v", category, retryCategories)
+		}
+	}
+
+	// Validate output order
+	if opts.OutputOrder != "request" && opts.OutputOrder != "completion" {
+		return fmt.Errorf("invalid output order %s. expected: request or completion", opts.OutputOrder)
+	}
+
+	// Validate the TSIG key
+	if opts.TSIG != "" {
+		if _, _, _, err := cli.ParseTSIG(opts.TSIG); err != nil {
+			return err
+		}
+	}
+
+	// Validate the trust anchor key tags
+	if opts.KeyTags != "" {
+		if _, err := cli.ParseKeyTags(opts.KeyTags); err != nil {
+			return err
+		}
+	}
+
+	// Compile the answer assertion
+	var matchPattern *regexp.Regexp
+	var matchType uint16
+	if opts.Match != "" {
+		matchPattern, err = regexp.Compile(opts.Match)
+		if err != nil {
+			return fmt.Errorf("invalid --match pattern: %s", err)
+		}
+		if opts.MatchType != "" {
+			t, ok := cli.ArgType(opts.MatchType)
+			if !ok {
+				return fmt.Errorf("invalid --match-type %s", opts.MatchType)
+			}
+			matchType = t
+		}
+	} else if opts.MatchType != "" || opts.NoMatch {
+		return fmt.Errorf("--match-type and --no-match require --match")
+	}
+
+	if opts.ExpectMaxCount >= 0 && opts.ExpectMinCount > opts.ExpectMaxCount {
+		return fmt.Errorf("--expect-min-count %d is greater than --expect-max-count %d", opts.ExpectMinCount, opts.ExpectMaxCount)
+	}
+	if opts.FailFast && opts.ContinueOnError {
+		return fmt.Errorf("--fail-fast and --continue-on-error can't be used together")
+	}
+	if opts.IDSequential && opts.ID != -1 {
+		return fmt.Errorf("--qid and --id-sequential can't be used together")
+	}
+	if opts.IDBase < 0 || opts.IDBase > math.MaxUint16 {
+		return fmt.Errorf("--id-base %d is out of range, expected 0 to %d", opts.IDBase, math.MaxUint16)
+	}
+	queryID.Store(uint32(opts.IDBase))
+	if opts.RequireECSScope && opts.ClientSubnet == "" {
+		return fmt.Errorf("--require-ecs-scope requires --subnet")
+	}
+
+	// Validate DNS64 prefixes
+	if _, err := output.ParseDNS64Prefixes(opts.DNS64Prefixes); err != nil {
+		return err
+	}
+
+	// Load SSH host keys to verify SSHFP records against
+	var sshKeys []output.SSHHostKey
+	if opts.SSHFPVerify != "" {
+		sshKeys, err = output.ReadSSHKeys(opts.SSHFPVerify)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Load the geo database, continuing without annotations if it can't be read
+	var geoDB *output.GeoDB
+	if opts.GeoDB != "" {
+		geoDB, err = output.LoadGeoDB(opts.GeoDB)
+		if err != nil {
+			log.Warnf("Not annotating addresses: %s", err)
+		}
+	}
+
 	// Parse requested RR types
 	rrTypes, err := cli.ParseRRTypes(opts.Types)
 	if err != nil {
 		return err
 	}
 
-	// Add non-flag RR types
+	// Classify positional arguments as servers, classes, RR types, and names regardless of their order
+	var names []string
 	for _, arg := range args {
 		// Find a server by @ symbol if it isn't set by flag
 		if strings.HasPrefix(arg, "@") {
 			opts.Server = append(opts.Server, strings.TrimPrefix(arg, "@"))
+			continue
 		}
 
-		// Parse chaos class
-		if strings.ToLower(arg) == "ch" {
+		// Parse query class
+		switch strings.ToLower(arg) {
+		case "ch", "chaos":
 			opts.Chaos = true
+			continue
+		case "in": // Default class
+			continue
+		}
+
+		// Add IXFR with the serial to request changes since
+		if serial, ok := cli.ArgIXFR(arg); ok {
+			rrTypes[dns.TypeIXFR] = true
+			opts.IXFRSerial = serial
+			continue
 		}
 
 		// Add non-flag RR types
-		rrType, typeFound := dns.StringToType[strings.ToUpper(arg)]
-		if typeFound {
+		if rrType, ok := cli.ArgType(arg); ok {
 			rrTypes[rrType] = true
 			if rrType == dns.TypeNS {
 				opts.ShowAuthority = true
 				opts.ShowAdditional = true
 			}
+			continue
 		}
 
-		// Set qname if not set by flag
-		if opts.Name == "" &&
-			!util.ContainsAny(arg, []string{"@", "/", "\\", "+"}) && // Not a server, path, or flag
-			!typeFound && // Not a RR type
+		if !util.ContainsAny(arg, []string{"@", "/", "\\", "+"}) && // Not a server, path, or flag
 			!strings.HasSuffix(arg, ".exe") && // Not an executable
 			!strings.HasPrefix(arg, "-") { // Not a flag
-			opts.Name = arg
+			names = append(names, arg)
+		}
+	}
+
+	// Set qname if not set by flag
+	if opts.Name == "" {
+		switch len(names) {
+		case 0:
+		case 1:
+			opts.Name = names[0]
+		default:
+			return fmt.Errorf("multiple names given (%s), set the name with -q", strings.Join(names, ", "))
+		}
+	}
+
+	// Read names to query in bulk
+	var fileNames []string
+	if opts.NamesFile != "" {
+		if opts.Name != "" {
+			return fmt.Errorf("can't query %s and names from a file at the same time", opts.Name)
+		}
+		fileNames, err = readNameFile(opts.NamesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Look up the PTR record of an IP address given as the name without a type, like dig -x
+	if len(rrTypes) < 1 && !opts.Reverse && !opts.NoReverse && net.ParseIP(opts.Name) != nil {
+		log.Debugf("Name %s is an IP address, querying its PTR record", opts.Name)
+		opts.Reverse = true
+		rrTypes[dns.TypePTR] = true
+	}
+
+	// CHAOS class queries are for server identification, so default to the TXT record of version.bind
+	if opts.Chaos || opts.Class == dns.ClassCHAOS {
+		if opts.Name == "" && len(fileNames) == 0 {
+			opts.Name = "version.bind"
+		}
+		if len(rrTypes) < 1 {
+			rrTypes[dns.TypeTXT] = true
 		}
 	}
 
 	// If no RR types are defined, set a list of default ones
 	if len(rrTypes) < 1 {
-		if opts.Name == "" {
+		if opts.Name == "" && len(fileNames) == 0 {
 			rrTypes[dns.StringToType["NS"]] = true
 		} else {
 			for _, defaultRRType := range opts.DefaultRRTypes {
@@ -323,16 +886,9 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 
 	// IDNA (punycode) normalize non-ASCII domain names unless reverse lookup
 	if opts.Name != "" && !opts.Reverse {
-		// Skip if already an in-addr.arpa or ip6.arpa name
-		lowerName := strings.ToLower(opts.Name)
-		if !strings.HasSuffix(lowerName, ".in-addr.arpa") && !strings.HasSuffix(lowerName, ".ip6.arpa") {
-			// Allow underscores during IDNA conversion
-			_asciiName := strings.ReplaceAll(opts.Name, "_", "..")
-			asciiName, err := idna.Lookup.ToASCII(_asciiName)
-			if err != nil {
-				return fmt.Errorf("idna toascii: %s", err)
-			}
-			opts.Name = strings.ReplaceAll(asciiName, "..", "_")
+		opts.Name, err = toASCII(opts.Name)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -354,11 +910,11 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 	if len(opts.Server) == 0 {
 		opts.Server = make([]string, 1)
 
-		if os.Getenv(defaultServerVar) != "" {
-			opts.Server[0] = os.Getenv(defaultServerVar)
-			log.Debugf("Using %s from %s environment variable", opts.Server, defaultServerVar)
+		if server, env := cli.EnvServer(); server != "" {
+			opts.Server[0] = server
+			log.Debugf("Using %s from %s environment variable", opts.Server, env)
 		} else {
-			log.Debugf("No server specified or %s set, using /etc/resolv.conf", defaultServerVar)
+			log.Debugf("No server specified or %s set, using /etc/resolv.conf", cli.ServerVar)
 			conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
 			if err != nil {
 				opts.Server[0] = "https://cloudflare-dns.com/dns-query"
@@ -375,6 +931,14 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 		}
 	}
 
+	// Set options given as query parameters of server URLs
+	for i, server := range opts.Server {
+		opts.Server[i], err = cli.ServerParams(parser, server, len(opts.Server) > 1)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Validate ODoH
 	if opts.ODoHProxy != "" {
 		if !strings.HasPrefix(opts.ODoHProxy, "https://") {
@@ -409,6 +973,18 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 		CurvePreferences:   tlsutil.ParseCurves(opts.TLSCurvePreferences),
 	}
 
+	if opts.TLSSNI != "" {
+		tlsConfig.ServerName = opts.TLSSNI
+	}
+
+	// TLS certificate public key pinning
+	if len(opts.TLSPinSHA256) > 0 {
+		tlsConfig.VerifyConnection, err = tlsutil.VerifyPins(opts.TLSPinSHA256)
+		if err != nil {
+			return err
+		}
+	}
+
 	// TLS client certificate authentication
 	if opts.TLSClientCertificate != "" {
 		cert, err := tls.LoadX509KeyPair(opts.TLSClientCertificate, opts.TLSClientKey)
@@ -417,6 +993,18 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 		}
 		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
+	// Client certificates, proxy, and source address
+	r, err := newResolver(opts)
+	if err != nil {
+		return err
+	}
+
+	// Interface to send queries through
+	if opts.Interface != "" {
+		if _, err := net.InterfaceByName(opts.Interface); err != nil {
+			return fmt.Errorf("invalid interface %s: %s", opts.Interface, err)
+		}
+	}
 
 	// TLS secret logging
 	if opts.TLSKeyLogFile != "" {
@@ -432,134 +1020,177 @@ All long form (--) flags can be toggled with the dig-standard +[no]flag notation
 	for rrType := range rrTypes {
 		rrTypesSlice = append(rrTypesSlice, rrType)
 	}
-	msgs := createQuery(opts, rrTypesSlice)
+	// Build one set of queries per name, so that results are grouped by name when querying in bulk
+	queries := [][]dns.Msg{createQuery(opts, rrTypesSlice)}
+	if len(fileNames) > 0 {
+		queries = nil
+		for _, name := range fileNames {
+			o := opts
+			o.Name = name
+			queries = append(queries, createQuery(o, rrTypesSlice))
+		}
+	}
+	msgs := queries[0]
+
+	// Everything the run starts stops when the context is done, so no queries outlive the driver
+	ctx, cancel := context.WithCancel(context.Background())
+	if opts.OverallTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), opts.OverallTimeout)
+	}
+	defer cancel()
 
 	errChan := make(chan error)
 
 	go func() {
-		var entries []*output.Entry
-		for _, serverStr := range opts.Server {
-			// Parse server address and transport type
-			server, transportType, err := parseServer(serverStr)
-			if err != nil {
-				errChan <- fmt.Errorf("parsing server %s: %s", serverStr, err)
-			}
-			log.Debugf("Using server %s with transport %s", server, transportType)
+		// Special query modes run against the first server only
+		if handled, err := runMode(ctx, r, opts.Server[0], msgs, tlsConfig, out); handled {
+			errChan <- err
+			return
+		}
 
-			// Recursive zone transfer
-			if opts.RecAXFR {
-				if opts.Name == "" {
-					errChan <- fmt.Errorf("no name specified for AXFR")
+		printer := output.Printer{
+			Out:  out,
+			Opts: &opts,
+		}
+
+		// Summaries replace the entries in structured output so that it stays a single document
+		structured := opts.Format == output.FormatJSON || opts.Format == output.FormatYAML || opts.Format == "yml"
+
+		// Print entries in completion order as each server finishes, buffering each one so it's written in one piece
+		var done func(*output.Entry)
+		streamed := opts.OutputOrder == "completion" && !structured && !opts.DedupServers &&
+			opts.Format != output.FormatCompare && opts.Format != output.FormatHTML
+		if streamed {
+			var mu sync.Mutex
+			done = func(e *output.Entry) {
+				e.SSHKeys = sshKeys
+				e.GeoDB = geoDB
+				var buf bytes.Buffer
+				if err := printEntries(output.Printer{Out: &buf, Opts: &opts}, []*output.Entry{e}); err != nil {
+					log.Warn(err)
+					return
 				}
-				_ = RecAXFR(opts.Name, server, out)
-				errChan <- nil // exit immediately
+				mu.Lock()
+				defer mu.Unlock()
+				util.MustWritef(out, "%s", buf.String())
 			}
+		}
 
-			// Create transport
-			txp, err := newTransport(server, transportType, tlsConfig)
+		var entries []*output.Entry
+		if opts.Replay != "" {
+			saved, err := output.LoadEntries(opts.Replay)
 			if err != nil {
-				errChan <- fmt.Errorf("creating transport: %s", err)
+				errChan <- err
+				return
 			}
-
-			startTime := time.Now()
-			var replies []*dns.Msg
-			for _, msg := range msgs {
-				if txp == nil {
-					errChan <- fmt.Errorf("transport is nil")
+			for _, e := range saved {
+				if done != nil {
+					done(e)
 				}
-				reply, err := (*txp).Exchange(&msg)
+			}
+			entries = saved
+		} else {
+			for _, msgs := range queries {
+				nameEntries, err := queryServers(ctx, r, opts.Server, msgs, tlsConfig, done)
 				if err != nil {
-					errChan <- fmt.Errorf("exchange: %s", err)
-				}
-
-				if reply == nil {
-					errChan <- fmt.Errorf("no reply from server")
-				}
-
-				if opts.ShowOpt {
-					for _, o := range reply.Extra {
-						if o.Header().Rrtype == dns.TypeOPT {
-							fmt.Printf("OPT: %v\n", o)
-						}
-					}
-				}
-
-				if transportType != transport.TypeQUIC && opts.IDCheck && reply.Id != msg.Id {
-					errChan <- fmt.Errorf("ID mismatch: expected %d, got %d", msg.Id, reply.Id)
+					errChan <- err
+					return
 				}
-				replies = append(replies, reply)
+				entries = append(entries, nameEntries...)
 			}
+		}
+		for _, e := range entries {
+			e.SSHKeys = sshKeys
+			e.GeoDB = geoDB
+		}
 
-			// Process TXT parsing
-			if opts.TXTConcat {
-				for _, reply := range replies {
-					txtConcat(reply)
-				}
-			}
+		// Check the answer assertions now, but only fail once the answers have been printed
+		var matchErr error
+		if matchPattern != nil {
+			matchErr = checkMatch(entries, matchPattern, matchType)
+		}
+		if matchErr == nil {
+			matchErr = checkCount(entries)
+		}
+		if matchErr == nil && opts.RequireECSScope {
+			matchErr = checkECSScope(entries)
+		}
+		// Short output is empty without answers, so fail for scripts to tell
+		if matchErr == nil && opts.Format == output.FormatShort && !output.HasAnswers(entries) {
+			matchErr = fmt.Errorf("no answers")
+		}
 
-			// Round TTL
-			if opts.RoundTTLs {
-				for _, reply := range replies {
-					for _, rr := range reply.Answer {
-						rr.Header().Ttl = rr.Header().Ttl - (rr.Header().Ttl % 60)
-					}
-				}
+		if opts.Save != "" {
+			if err := output.SaveEntries(opts.Save, entries); err != nil {
+				errChan <- err
+				return
 			}
+		}
 
-			e := &output.Entry{
-				Queries: msgs,
-				Replies: replies,
-				Server:  server,
-				Time:    time.Since(startTime),
+		if opts.WireOut != "" {
+			if err := writeWire(opts.WireOut, entries); err != nil {
+				errChan <- err
+				return
 			}
+		}
 
-			if opts.ResolveIPs {
-				e.LoadPTRs(txp)
+		// Skip printing if NSIDOnly is set
+		if opts.NSIDOnly {
+			if !streamed {
+				printer.PrettyPrintNSID(entries, false)
 			}
+			errChan <- matchErr
+			return
+		}
 
-			entries = append(entries, e)
-
-			if err := (*txp).Close(); err != nil {
-				errChan <- fmt.Errorf("closing transport: %s", err)
+		// Group servers by identical answers instead of printing each entry
+		if opts.DedupServers {
+			if opts.NSID && (opts.Format == output.FormatPretty || opts.Format == output.FormatColumn) {
+				printer.PrettyPrintNSID(entries, true)
 			}
+			printer.PrintDedup(entries)
+			errChan <- matchErr
+			return
 		}
 
-		printer := output.Printer{
-			Out:  out,
-			Opts: &opts,
-		}
+		switch {
+		case structured && opts.RTTTable:
+			printer.PrintRTTTable(entries)
+		case structured && opts.Histogram:
+			printer.PrintHistogram(entries)
+		default:
+			if !streamed {
+				if err := printEntries(printer, entries); err != nil {
+					errChan <- err
+					return
+				}
+			}
 
-		if (opts.NSID && (opts.Format == output.FormatPretty || opts.Format == output.FormatColumn)) || opts.NSIDOnly {
-			printer.PrettyPrintNSID(entries, !opts.NSIDOnly)
+			if opts.RTTTable {
+				printer.PrintRTTTable(entries)
+			}
+			if opts.Histogram {
+				printer.PrintHistogram(entries)
+			}
 		}
 
-		// Skip printing if NSIDOnly is set
-		if opts.NSIDOnly {
-			errChan <- nil
-		}
-
-		switch opts.Format {
-		case output.FormatPretty:
-			printer.PrintPretty(entries)
-		case output.FormatColumn:
-			printer.PrintColumn(entries)
-		case output.FormatRAW:
-			printer.PrintRaw(entries)
-		case output.FormatJSON, output.FormatYAML, "yml":
-			printer.PrintStructured(entries)
-		default:
-			errChan <- fmt.Errorf("invalid output format %s", opts.Format)
+		if opts.Syslog || opts.SyslogServer != "" {
+			if err := sendSyslog(entries); err != nil {
+				errChan <- err
+				return
+			}
 		}
 
-		errChan <- nil
+		errChan <- matchErr
 	}()
 
-	select {
-	case <-time.After(opts.Timeout):
-		return fmt.Errorf("timeout after %s", opts.Timeout)
-	case err := <-errChan:
-		return err
+	// Each exchange times out on its own, so only --overall-timeout limits the whole run. The queries return as soon as
+	// it's exceeded, so wait for them instead of leaving them running.
+	err = <-errChan
+	if deadline, ok := ctx.Deadline(); ok && err != nil && !time.Now().Before(deadline) {
+		return fmt.Errorf("overall timeout of %s exceeded", opts.OverallTimeout)
 	}
+	return err
 }
 
 func main() {
@@ -2,17 +2,40 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/idna"
 
 	"github.com/natesales/q/cli"
 	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+	tlsutil "github.com/natesales/q/util/tls"
 )
 
 func run(args ...string) (*bytes.Buffer, error) {
@@ -220,10 +243,11 @@ func TestMainPad(t *testing.T) {
 		"-q", "example.com",
 		"--pad",
 		"--format=json",
+		"--json-compact",
 	)
 	assert.Nil(t, err)
 	o := strings.ReplaceAll(out.String(), `\\"`, `"`)
-	assert.Contains(t, o, `"truncated":false`)
+	assert.Contains(t, o, `"tc":false`)
 }
 
 func TestMainChaosClass(t *testing.T) {
@@ -491,6 +515,16 @@ func TestMainParseServer(t *testing.T) {
 			Type:         transport.TypePlain,
 			ExpectedHost: "[2001:db8:11:8340:dea6:32ff:fe5b:a19e]:53",
 		},
+		{ // WebSocket with no port
+			Server:       "ws://localhost/dns",
+			Type:         transport.TypeWS,
+			ExpectedHost: "ws://localhost:80/dns",
+		},
+		{ // Secure WebSocket with no port
+			Server:       "wss://localhost/dns",
+			Type:         transport.TypeWS,
+			ExpectedHost: "wss://localhost:443/dns",
+		},
 	} {
 		t.Run(tc.Server, func(t *testing.T) {
 			server, transportType, err := parseServer(tc.Server)
@@ -625,3 +659,2315 @@ func TestIDNAUnderscoreASCII(t *testing.T) {
 	re := regexp.MustCompile(regexp.QuoteMeta("_acme-challenge.example.com."))
 	assert.Regexp(t, re, out.String())
 }
+
+func TestMainFailureCategory(t *testing.T) {
+	timeoutErr := &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}
+	assert.Equal(t, "timeout", failureCategory(nil, timeoutErr))
+	assert.Equal(t, "network", failureCategory(nil, fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	assert.Equal(t, "network", failureCategory(nil, nil))
+	assert.Equal(t, "error", failureCategory(nil, fmt.Errorf("packing message")))
+	assert.Equal(t, "", failureCategory(&dns.Msg{}, nil))
+	assert.Equal(t, "servfail", failureCategory(&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeServerFailure}}, nil))
+	assert.Equal(t, "refused", failureCategory(&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeRefused}}, nil))
+	assert.Equal(t, "nxdomain", failureCategory(&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError}}, nil))
+}
+
+func TestMainInvalidRetryCategory(t *testing.T) {
+	_, err := run(
+		"-q", "example.com",
+		"--retry", "2",
+		"--retry-on", "sometimes",
+	)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid retry category sometimes")
+}
+
+func TestMainSweepAddrs(t *testing.T) {
+	addrs, err := sweepAddrs("192.0.2.17/30")
+	assert.Nil(t, err)
+	assert.Len(t, addrs, 4)
+	assert.Equal(t, "192.0.2.16", addrs[0].String())
+	assert.Equal(t, "192.0.2.19", addrs[3].String())
+
+	addrs, err = sweepAddrs("2001:db8::/120")
+	assert.Nil(t, err)
+	assert.Len(t, addrs, 256)
+	assert.Equal(t, "2001:db8::ff", addrs[255].String())
+
+	_, err = sweepAddrs("2001:db8::/64")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "too large")
+
+	_, err = sweepAddrs("192.0.2.0")
+	assert.NotNil(t, err)
+}
+
+// localServer starts a DNS server on a random local port over both UDP and TCP and returns its address
+func localServer(t *testing.T, handler dns.HandlerFunc) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	assert.Nil(t, err)
+
+	for _, server := range []*dns.Server{
+		{PacketConn: pc, Handler: handler},
+		{Listener: l, Handler: handler},
+	} {
+		go func() {
+			_ = server.ActivateAndServe()
+		}()
+		t.Cleanup(func() {
+			_ = server.Shutdown()
+		})
+	}
+	return pc.LocalAddr().String()
+}
+
+func TestMainSweep(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Name == "2.2.0.192.in-addr.arpa." {
+			m.Answer = append(m.Answer, &dns.PTR{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 60},
+				Ptr: "host2.example.",
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run(
+		"@"+server,
+		"--sweep", "192.0.2.0/29",
+	)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "192.0.2.2 host2.example.")
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+}
+
+func TestMainSweepLongerThanTimeout(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(50 * time.Millisecond)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.PTR{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 60},
+			Ptr: "host.example.",
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	// The sweep takes longer than --timeout, but each query is within it
+	out, err := run("@"+server, "--timeout", "200ms", "--sweep-concurrency", "1", "--sweep", "192.0.2.0/29")
+	assert.Nil(t, err)
+	assert.Equal(t, 8, strings.Count(out.String(), "host.example."))
+}
+
+func TestMainClassifyTruncation(t *testing.T) {
+	a := &dns.A{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET}}
+	full := &dns.Msg{Answer: []dns.RR{a, a}}
+
+	description, compliant := classifyTruncation(&dns.Msg{MsgHdr: dns.MsgHdr{Truncated: true}}, full, 40)
+	assert.True(t, compliant)
+	assert.Contains(t, description, "minimal response")
+
+	description, compliant = classifyTruncation(&dns.Msg{Answer: []dns.RR{a}}, full, 60)
+	assert.False(t, compliant)
+	assert.Contains(t, description, "partial answer section without TC")
+
+	description, compliant = classifyTruncation(full, full, 1400)
+	assert.False(t, compliant)
+	assert.Contains(t, description, "exceeded the advertised buffer")
+}
+
+func TestMainLimitAnswerSection(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if w.RemoteAddr().Network() == "udp" {
+			m.Truncated = true
+		} else {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{strings.Repeat("a", 255), strings.Repeat("b", 255), strings.Repeat("c", 255)},
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run(
+		"@"+server,
+		"--limit-answer-section",
+		"example.com", "TXT",
+	)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "example.com. TXT: TC set with empty sections (minimal response)")
+	assert.Contains(t, out.String(), "1/0/0 records")
+}
+
+func TestMainDNSSerialNewer(t *testing.T) {
+	assert.True(t, dnsSerialNewer(2024010102, 2024010101))
+	assert.False(t, dnsSerialNewer(2024010101, 2024010101))
+	assert.False(t, dnsSerialNewer(2024010101, 2024010102))
+	assert.True(t, dnsSerialNewer(1, 4294967295)) // Wraparound
+}
+
+func TestMainClassFlag(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassCHAOS, Ttl: 0},
+			Txt: []string{fmt.Sprintf("class %d", r.Question[0].Qclass)},
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "version.bind", "TXT", "--class", "ANY")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), `version.bind. 0s CH TXT "class 255"`)
+
+	out, err = run("@"+server, "version.bind", "TXT", "-C", "3")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), `"class 3"`)
+}
+
+func TestMainEDNSVersionDowngrade(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.SetEdns0(1232, false)
+		if r.IsEdns0().Version() > 0 {
+			m.Rcode = dns.RcodeBadVers
+		} else {
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run(
+		"@"+server,
+		"--edns-version=1",
+		"--stats",
+		"example.com", "A",
+	)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "192.0.2.1")
+	assert.Contains(t, out.String(), "EDNS version: 0")
+}
+
+func TestMainProfile(t *testing.T) {
+	answer := func(ip string) dns.HandlerFunc {
+		return func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(ip),
+			})
+			_ = w.WriteMsg(m)
+		}
+	}
+	profileServer := localServer(t, answer("192.0.2.1"))
+	overrideServer := localServer(t, answer("192.0.2.2"))
+
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(config, []byte(fmt.Sprintf(`profiles:
+  local:
+    server: %s
+    timeout: 2s
+    stats: true
+`, profileServer)), 0o644))
+
+	out, err := run("--config", config, "--profile", "local", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "192.0.2.1")
+	assert.Contains(t, out.String(), "Stats:")
+
+	// Command line flags take precedence over the profile
+	out, err = run("--config", config, "--profile", "local", "@"+overrideServer, "--stats=false", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "192.0.2.2")
+	assert.NotContains(t, out.String(), "192.0.2.1")
+	assert.NotContains(t, out.String(), "Stats:")
+
+	_, err = run("--config", config, "--profile", "missing", "example.com")
+	assert.ErrorContains(t, err, "profile missing not found")
+}
+
+func TestMainVerify(t *testing.T) {
+	var queries atomic.Int32
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		n := queries.Add(1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP(fmt.Sprintf("192.0.2.%d", n)),
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--verify", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), queries.Load())
+	assert.Contains(t, out.String(), "inconsistent answers for example.com. A")
+	assert.Contains(t, out.String(), "  - example.com. A 192.0.2.1")
+	assert.Contains(t, out.String(), "  + example.com. A 192.0.2.2")
+}
+
+func TestMainClassifyNegativeCache(t *testing.T) {
+	first := negativeProbe{rcode: dns.RcodeNameError, rtt: 20 * time.Millisecond, ttl: 300, minimum: 300, hasSOA: true}
+	cached := negativeProbe{rcode: dns.RcodeNameError, rtt: time.Millisecond, ttl: 299, minimum: 300, hasSOA: true}
+
+	description, ok := classifyNegativeCache(first, cached)
+	assert.True(t, ok)
+	assert.Equal(t, "cached, negative TTL decremented from 300 to 299", description)
+
+	_, ok = classifyNegativeCache(first, first)
+	assert.False(t, ok)
+
+	_, ok = classifyNegativeCache(first, negativeProbe{rcode: dns.RcodeNameError})
+	assert.False(t, ok)
+}
+
+func TestMainNegativeCacheTest(t *testing.T) {
+	ttl := uint32(300)
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		m.Ns = append(m.Ns, &dns.SOA{
+			Hdr:    dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+			Ns:     "ns.example.com.",
+			Mbox:   "hostmaster.example.com.",
+			Minttl: 300,
+		})
+		ttl--
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--negative-caching-test", "example.com")
+	assert.Nil(t, err)
+	assert.Regexp(t, `q-nxdomain-[0-9a-f]{8}\.example\.com\.: cached, negative TTL decremented from 300 to 299`, out.String())
+	assert.Contains(t, out.String(), "Query 2: NXDOMAIN")
+}
+
+func TestMainCheckCDS(t *testing.T) {
+	generate := func() *dns.DNSKEY {
+		key := &dns.DNSKEY{
+			Hdr:       dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+			Flags:     257,
+			Protocol:  3,
+			Algorithm: dns.ECDSAP256SHA256,
+		}
+		_, err := key.Generate(256)
+		assert.Nil(t, err)
+		return key
+	}
+	oldKey, newKey := generate(), generate()
+
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Qtype {
+		case dns.TypeDS:
+			m.Answer = append(m.Answer, oldKey.ToDS(dns.SHA256))
+		case dns.TypeCDNSKEY:
+			cdnskey := &dns.CDNSKEY{DNSKEY: *newKey}
+			cdnskey.Hdr.Rrtype = dns.TypeCDNSKEY
+			m.Answer = append(m.Answer, cdnskey)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--check-cds", "example.com")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "example.com.: parent DS update pending")
+	assert.Contains(t, out.String(), fmt.Sprintf("CDS %d ECDSAP256SHA256 SHA256: not at parent", newKey.KeyTag()))
+	assert.Contains(t, out.String(), fmt.Sprintf("DS %d ECDSAP256SHA256 SHA256: not signaled by CDS/CDNSKEY", oldKey.KeyTag()))
+
+	signaled, remove := signaledDS(nil, []dns.RR{&dns.CDNSKEY{DNSKEY: dns.DNSKEY{Algorithm: 0}}}, nil)
+	assert.True(t, remove)
+	assert.Empty(t, signaled)
+}
+
+func TestMainCNAMELoop(t *testing.T) {
+	cname := func(name, target string) *dns.CNAME {
+		return &dns.CNAME{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60}, Target: target}
+	}
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Name {
+		case "a.example.com.":
+			m.Answer = append(m.Answer, cname("a.example.com.", "b.example.com."))
+		case "b.example.com.":
+			m.Answer = append(m.Answer, cname("b.example.com.", "c.example.com."))
+		case "c.example.com.":
+			m.Answer = append(m.Answer, cname("c.example.com.", "a.example.com."))
+		case "d.example.com.":
+			m.Answer = append(m.Answer, cname("d.example.com.", "e.example.com."))
+		case "e.example.com.":
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: "e.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	_, err := run("@"+server, "--max-cname-depth=8", "a.example.com", "A")
+	assert.EqualError(t, err, "CNAME loop: a.example.com. -> b.example.com. -> c.example.com. -> a.example.com.")
+
+	_, err = run("@"+server, "--max-cname-depth=1", "a.example.com", "A")
+	assert.EqualError(t, err, "CNAME chain exceeds max depth 1: a.example.com. -> b.example.com. -> c.example.com.")
+
+	out, err := run("@"+server, "--max-cname-depth=8", "d.example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "e.example.com. 1m A 192.0.2.1")
+}
+
+func TestMainCompression(t *testing.T) {
+	var compressed []bool
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	for _, args := range [][]string{{}, {"--no-compression"}} {
+		_, err := run(append([]string{"@" + server, "example.com", "A"}, args...)...)
+		assert.Nil(t, err)
+		compressed = append(compressed, createQuery(opts, []uint16{dns.TypeA})[0].Compress)
+	}
+	assert.Equal(t, []bool{true, false}, compressed)
+}
+
+func TestMainHistogram(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--resolve-timeout-histogram", "--format=json", "--json-compact", "example.com", "A", "AAAA")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"label":"0-10ms","count":2},{"label":"10ms-50ms","count":0},{"label":"50ms-100ms","count":0},{"label":"100ms-250ms","count":0},{"label":"250ms-500ms","count":0},{"label":"500ms-1s","count":0},{"label":"1s+","count":0}]`+"\n", out.String())
+}
+
+func TestMainMeasureCacheHits(t *testing.T) {
+	seen := map[string]bool{}
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		name := r.Question[0].Name
+		if name == "uncached.example.com." {
+			time.Sleep(10 * time.Millisecond)
+		} else if !seen[name] {
+			time.Sleep(20 * time.Millisecond)
+		}
+		seen[name] = true
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	names := filepath.Join(t.TempDir(), "names.txt")
+	assert.Nil(t, os.WriteFile(names, []byte("# test names\na.example.com\nb.example.com\n\nc.example.com\nuncached.example.com\n"), 0o644))
+
+	out, err := run("@"+server, "--measure-cache-hit-ratio", names)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "Cache hit ratio: 75.0% (3/4 queries at least 50% faster when repeated)")
+	assert.Contains(t, out.String(), "Average latency: cold ")
+}
+
+func TestMainSyslogServer(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+	collector, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer collector.Close()
+
+	_, err = run("@"+server, "--syslog-server", "udp://"+collector.LocalAddr().String(), "--syslog-facility", "local3", "example.com", "A")
+	assert.Nil(t, err)
+
+	buf := make([]byte, 1024)
+	assert.Nil(t, collector.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := collector.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^<158>.* q\[\d+\]: server=127\.0\.0\.1:\d+ name=example\.com\. type=A rcode=NOERROR latency_ms=[\d.]+ answers=""`, string(buf[:n]))
+
+	_, err = run("@"+server, "--syslog", "--syslog-severity", "bogus", "example.com", "A")
+	assert.EqualError(t, err, "invalid syslog severity bogus")
+}
+
+func TestMainHeaderOnly(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		t.Error("handler called for a query without a question")
+	})
+
+	out, err := run("@"+server, "--header-only")
+	assert.Nil(t, err)
+	assert.Regexp(t, `^127\.0\.0\.1:\d+ reachable in .+ \(FORMERR\)\n$`, out.String())
+
+	out, err = run("@tcp://127.0.0.1:1", "--header-only")
+	assert.NotNil(t, err)
+	assert.Contains(t, out.String(), "unreachable")
+}
+
+func TestMainRandomizeIDOnRetry(t *testing.T) {
+	var ids []uint16
+	var mu sync.Mutex
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		ids = append(ids, r.Id)
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		_ = w.WriteMsg(m)
+	})
+
+	_, err := run("@"+server, "--qid=1234", "--retry=3", "--retry-on=servfail", "example.com", "A")
+	assert.Nil(t, err)
+	mu.Lock()
+	assert.Equal(t, []uint16{1234, 1234, 1234, 1234}, ids)
+	ids = nil
+	mu.Unlock()
+
+	_, err = run("@"+server, "--qid=1234", "--retry=3", "--retry-on=servfail", "--randomize-id-on-retry", "example.com", "A")
+	assert.Nil(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, ids, 4)
+	assert.Equal(t, uint16(1234), ids[0])
+	assert.NotEqual(t, []uint16{1234, 1234, 1234, 1234}, ids)
+}
+
+func TestMainServerConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		inFlight.Add(-1)
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		_ = w.WriteMsg(m)
+	}
+
+	args := []string{"--server-concurrency=2", "example.com", "A"}
+	var servers []string
+	for i := 0; i < 5; i++ {
+		server := localServer(t, handler)
+		servers = append(servers, server)
+		args = append(args, "@"+server)
+	}
+
+	out, err := run(args...)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), peak.Load())
+
+	// Entries are printed in server order regardless of completion order
+	last := -1
+	for _, server := range servers {
+		i := strings.Index(out.String(), server)
+		assert.Greater(t, i, last)
+		last = i
+	}
+}
+
+func TestMainTraceGraph(t *testing.T) {
+	var queries atomic.Int32
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		assert.False(t, r.RecursionDesired)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch queries.Add(1) {
+		case 1:
+			m.Ns = append(m.Ns, &dns.NS{
+				Hdr: dns.RR_Header{Name: "com.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
+				Ns:  "a.gtld-servers.net.",
+			})
+			m.Extra = append(m.Extra, &dns.A{
+				Hdr: dns.RR_Header{Name: "a.gtld-servers.net.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("127.0.0.1"),
+			})
+		default:
+			m.Authoritative = true
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--trace-graph=-", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), queries.Load())
+	assert.Contains(t, out.String(), "digraph trace {")
+	assert.Contains(t, out.String(), `hop0 -> hop1 [label="referral to com.\na.gtld-servers.net."];`)
+	assert.Contains(t, out.String(), "192.0.2.1")
+}
+
+func TestMainTraceTimeoutFailover(t *testing.T) {
+	var queries atomic.Int32
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch queries.Add(1) {
+		case 1:
+			for i, ns := range []string{"a.gtld-servers.net.", "b.gtld-servers.net."} {
+				m.Ns = append(m.Ns, &dns.NS{
+					Hdr: dns.RR_Header{Name: "com.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
+					Ns:  ns,
+				})
+				m.Extra = append(m.Extra, &dns.A{
+					Hdr: dns.RR_Header{Name: ns, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+					A:   net.ParseIP([]string{"127.0.0.2", "127.0.0.1"}[i]),
+				})
+			}
+		default:
+			m.Authoritative = true
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	// The first nameserver accepts queries on the same port but never answers
+	_, port, _ := net.SplitHostPort(server)
+	silent, err := net.ListenPacket("udp", net.JoinHostPort("127.0.0.2", port))
+	if err != nil {
+		t.Skipf("binding 127.0.0.2: %s", err)
+	}
+	defer silent.Close()
+
+	out, err := run("@"+server, "--trace-graph=-", "--trace-timeout=100ms", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), queries.Load())
+	assert.Contains(t, out.String(), `hop1 [label="com.\nb.gtld-servers.net. (127.0.0.1:`+port+`)\ntimed out: a.gtld-servers.net. (127.0.0.2:`+port+`)"];`)
+	assert.Contains(t, out.String(), "192.0.2.1")
+}
+
+func TestMainCompactOK(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		opt := r.IsEdns0()
+		assert.NotNil(t, opt)
+		assert.True(t, opt.Co())
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Ns = append(m.Ns, &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: "\\000." + r.Question[0].Name,
+			TypeBitMap: []uint16{dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNXNAME},
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--compact-ok", "missing.example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "Denial: NXDOMAIN (compact denial, NSEC with NXNAME)")
+}
+
+func TestMainFormatCompare(t *testing.T) {
+	answer := func(addrs ...string) dns.HandlerFunc {
+		return func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			for _, addr := range addrs {
+				m.Answer = append(m.Answer, &dns.A{
+					Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+					A:   net.ParseIP(addr),
+				})
+			}
+			_ = w.WriteMsg(m)
+		}
+	}
+	primary := localServer(t, answer("192.0.2.1", "192.0.2.2"))
+	secondary := localServer(t, answer("192.0.2.1"))
+
+	// Nothing listens on a closed socket's port, so queries to it fail
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	dead := pc.LocalAddr().String()
+	_ = pc.Close()
+
+	out, err := run("@"+primary, "@"+secondary, "@"+dead, "--format=compare", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `  A 192\.0\.2\.1 +✓ +✓ +\?\n`, out.String())
+	assert.Regexp(t, `≠ A 192\.0\.2\.2 +✓ +✗ +\?\n`, out.String())
+	assert.Contains(t, out.String(), "Error from "+dead)
+}
+
+func TestMainContinueOnError(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	_, err := run("@"+server, "@bogus://192.0.2.53", "example.com", "A")
+	assert.ErrorContains(t, err, "unsupported transport bogus")
+
+	out, err := run("@"+server, "@bogus://192.0.2.53", "--continue-on-error", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "192.0.2.1")
+	assert.Contains(t, out.String(), "Error from bogus://192.0.2.53: parsing server bogus://192.0.2.53: unsupported transport bogus")
+
+	out, err = run("@"+server, "@bogus://192.0.2.53", "--continue-on-error", "--format=json", "--json-compact", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), `"error":"parsing server bogus://192.0.2.53: unsupported transport bogus`)
+}
+
+func TestMainMatch(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+			Txt: []string{"v=spf1 include:_spf.example.com", " -all"},
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	// TXT strings are concatenated before matching
+	out, err := run("@"+server, "--match=^v=spf1 .* -all$", "example.com", "TXT")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "v=spf1")
+
+	_, err = run("@"+server, "--match=~all", "example.com", "TXT")
+	assert.EqualError(t, err, "no answer matched /~all/")
+
+	_, err = run("@"+server, "--match=spf1", "--match-type=MX", "example.com", "TXT")
+	assert.EqualError(t, err, "no MX answer matched /spf1/")
+
+	_, err = run("@"+server, "--match=spf1", "--no-match", "example.com", "TXT")
+	assert.ErrorContains(t, err, "answer matched /spf1/: example.com.")
+
+	_, err = run("@"+server, "--match=[", "example.com", "TXT")
+	assert.ErrorContains(t, err, "invalid --match pattern")
+
+	_, err = run("@"+server, "--no-match", "example.com", "TXT")
+	assert.EqualError(t, err, "--match-type and --no-match require --match")
+}
+
+func TestMainExpectCount(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.CNAME{
+			Hdr:    dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60},
+			Target: "ns.example.com.",
+		})
+		for _, ns := range []string{"ns1.example.com.", "ns2.example.com."} {
+			m.Answer = append(m.Answer, &dns.NS{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
+				Ns:  ns,
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	// Only answers of the queried type count
+	_, err := run("@"+server, "--expect-count=2", "example.com", "NS")
+	assert.Nil(t, err)
+	_, err = run("@"+server, "--expect-min-count=1", "--expect-max-count=2", "example.com", "NS")
+	assert.Nil(t, err)
+
+	_, err = run("@"+server, "--expect-count=3", "example.com", "NS")
+	assert.EqualError(t, err, "expected exactly 3 NS answers for example.com. from "+server+", got 2")
+	_, err = run("@"+server, "--expect-min-count=3", "example.com", "NS")
+	assert.EqualError(t, err, "expected at least 3 NS answers for example.com. from "+server+", got 2")
+	_, err = run("@"+server, "--expect-max-count=1", "example.com", "NS")
+	assert.EqualError(t, err, "expected at most 1 NS answers for example.com. from "+server+", got 2")
+
+	_, err = run("@"+server, "--expect-min-count=3", "--expect-max-count=1", "example.com", "NS")
+	assert.EqualError(t, err, "--expect-min-count 3 is greater than --expect-max-count 1")
+}
+
+func TestMainTSIG(t *testing.T) {
+	const secret = "c2VjcmV0LXNlY3JldC1zZWNyZXQ="
+	var sign, reject atomic.Bool
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := &dns.Server{PacketConn: pc, TsigSecret: map[string]string{"key.example.": secret}, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if tsig := r.IsTsig(); tsig != nil && sign.Load() {
+			assert.Equal(t, dns.HmacSHA256, tsig.Algorithm)
+			m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+			if reject.Load() {
+				m.Rcode = dns.RcodeNotAuth
+				m.Extra[len(m.Extra)-1].(*dns.TSIG).Error = dns.RcodeBadSig
+			} else {
+				assert.Nil(t, w.TsigStatus())
+			}
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+	addr := pc.LocalAddr().String()
+
+	sign.Store(true)
+	out, err := run("@"+addr, "--tsig=key.example:"+secret, "example.com", "SOA")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "TSIG: verified (key.example. hmac-sha256)")
+
+	reject.Store(true)
+	_, err = run("@"+addr, "--tsig=key.example:hmac-sha256:"+secret, "example.com", "SOA")
+	assert.ErrorContains(t, err, "TSIG rejected by server: BADSIG")
+
+	sign.Store(false)
+	out, err = run("@"+addr, "--tsig=key.example:"+secret, "example.com", "SOA")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "TSIG: reply not signed")
+
+	_, err = run("@"+addr, "--tsig=key.example:hmac-md4:"+secret, "example.com", "SOA")
+	assert.EqualError(t, err, "unsupported TSIG algorithm hmac-md4")
+
+	_, err = run("@"+addr, "--tsig=key.example", "example.com", "SOA")
+	assert.EqualError(t, err, "invalid TSIG key key.example, expected keyname:[algorithm:]secret")
+
+	_, err = run("@https://"+addr, "--tsig=key.example:"+secret, "example.com", "SOA")
+	assert.ErrorContains(t, err, "TSIG is only supported over plain DNS and TCP, not http")
+}
+
+func TestMainFormatInflux(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Rcode = dns.RcodeNameError
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--format=influx", "example.com", "A", "AAAA")
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	// Types are queried in no particular order
+	sort.Strings(lines)
+	assert.Regexp(t, `^dns,server=`+regexp.QuoteMeta(server)+`,transport=plain,name=example.com.,type=A,rcode=NXDOMAIN latency=[\d.e-]+,answers=0i,size=\d+i \d+$`, lines[0])
+	assert.Contains(t, lines[1], ",type=AAAA,")
+}
+
+func TestMainClassifyRecursion(t *testing.T) {
+	answer := []dns.RR{&dns.A{
+		Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   net.ParseIP("192.0.2.1"),
+	}}
+	for _, tc := range []struct {
+		reply  *dns.Msg
+		result string
+		open   bool
+	}{
+		{&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeRefused}}, "recursion refused (REFUSED)", false},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{Authoritative: true}, Answer: answer}, "authoritative only, RA not set (NOERROR)", false},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{}}, "recursion not available, RA not set (NOERROR)", false},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{RecursionAvailable: true, Authoritative: true}, Answer: answer}, "RA set, answered authoritatively (NOERROR)", false},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{RecursionAvailable: true}, Answer: answer}, "open to recursion, RA set and recursed (NOERROR)", true},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{RecursionAvailable: true, Rcode: dns.RcodeNameError}}, "open to recursion, RA set and recursed (NXDOMAIN)", true},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{RecursionAvailable: true, Rcode: dns.RcodeServerFailure}}, "RA set but did not recurse (SERVFAIL, 0 answers)", false},
+	} {
+		result, open := classifyRecursion(tc.reply)
+		assert.Equal(t, tc.result, result)
+		assert.Equal(t, tc.open, open)
+	}
+}
+
+func TestMainCheckRecursion(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		assert.True(t, r.RecursionDesired)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.RecursionAvailable = true
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--check-recursion", "+nord", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, server+" example.com. open to recursion, RA set and recursed (NOERROR)\n", out.String())
+}
+
+func TestMainCompareFamily(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+	_, port, err := net.SplitHostPort(server)
+	assert.Nil(t, err)
+
+	out, err := run("@localhost:"+port, "--compare-family", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "IPv4 NOERROR 0 answers")
+	assert.Contains(t, out.String(), "IPv6")
+
+	_, err = run("@"+server, "--compare-family", "example.com", "A")
+	assert.ErrorContains(t, err, "requires a server hostname")
+}
+
+func TestMainOutputOrder(t *testing.T) {
+	answer := func(delay time.Duration) dns.HandlerFunc {
+		return func(w dns.ResponseWriter, r *dns.Msg) {
+			time.Sleep(delay)
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+			_ = w.WriteMsg(m)
+		}
+	}
+	slow := localServer(t, answer(100*time.Millisecond))
+	fast := localServer(t, answer(0))
+
+	out, err := run("@"+slow, "@"+fast, "example.com", "A")
+	assert.Nil(t, err)
+	assert.Less(t, strings.Index(out.String(), slow), strings.Index(out.String(), fast))
+
+	out, err = run("@"+slow, "@"+fast, "--output-order=completion", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Less(t, strings.Index(out.String(), fast), strings.Index(out.String(), slow))
+	assert.Equal(t, 2, strings.Count(out.String(), "example.com. 1m A 192.0.2.1"))
+
+	_, err = run("@"+fast, "--output-order=random", "example.com", "A")
+	assert.ErrorContains(t, err, "invalid output order")
+}
+
+func TestMainCookieRateLimitTest(t *testing.T) {
+	const serverCookie = "0102030405060708"
+	var cookieless atomic.Int32
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		cookie, ok := util.EDNSOption[*dns.EDNS0_COOKIE](r)
+		switch {
+		case !ok:
+			// Rate limit queries without a cookie after the first two by slipping a truncated response
+			if cookieless.Add(1) > 2 {
+				m.Truncated = true
+			}
+		case len(cookie.Cookie) == 16:
+			m.SetEdns0(1232, false)
+			m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie.Cookie + serverCookie})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--cookie-rate-limit-test=5", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, `without cookie 2/5 answered, 3 truncated, 0 refused, 0 dropped
+with cookie    5/5 answered, 0 truncated, 0 refused, 0 dropped
+cookie bypasses rate limit (3 more answered with a cookie)
+`, out.String())
+
+	_, err = run("@"+localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	}), "--cookie-rate-limit-test=5", "example.com", "A")
+	assert.ErrorContains(t, err, "server didn't return a server cookie")
+}
+
+func TestMainCheckCookie(t *testing.T) {
+	withCookie := func(cookie string) *dns.Msg {
+		m := new(dns.Msg)
+		if cookie != "" {
+			m.SetEdns0(1232, false)
+			m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie})
+		}
+		return m
+	}
+	const client = "0011223344556677"
+	for _, tc := range []struct {
+		reply  string
+		result string
+		full   string
+	}{
+		{"", "no cookie in reply", ""},
+		{"7766554433221100", "client cookie mismatch (sent 0011223344556677, received 7766554433221100)", ""},
+		{client, "client cookie echoed without a server cookie", ""},
+		{client + "0102", "client cookie echoed with an invalid 2 byte server cookie", ""},
+		{client + "0102030405060708", "full cookie, client 0011223344556677 server 0102030405060708 (8 bytes)", client + "0102030405060708"},
+	} {
+		result, full := checkCookie(withCookie(client), withCookie(tc.reply))
+		assert.Equal(t, tc.result, result)
+		assert.Equal(t, tc.full, full)
+	}
+
+	full := client + "0102030405060708"
+	assert.Equal(t, "server cookie matched", checkFollowUpCookie(full, withCookie(full)))
+	assert.Equal(t, "server cookie changed to 0807060504030201", checkFollowUpCookie(full, withCookie(client+"0807060504030201")))
+	assert.Equal(t, "no cookie in reply", checkFollowUpCookie(full, withCookie("")))
+	badCookie := withCookie(full)
+	badCookie.Rcode = dns.RcodeBadCookie
+	assert.Equal(t, "BADCOOKIE, server rejected its own cookie", checkFollowUpCookie(full, badCookie))
+}
+
+func TestMainCookieAuto(t *testing.T) {
+	const serverCookie = "0102030405060708"
+	var mu sync.Mutex
+	var cookies []string
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		cookie, ok := util.EDNSOption[*dns.EDNS0_COOKIE](r)
+		assert.True(t, ok)
+		mu.Lock()
+		cookies = append(cookies, cookie.Cookie)
+		mu.Unlock()
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.SetEdns0(1232, false)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie.Cookie[:16] + serverCookie})
+		_ = w.WriteMsg(m)
+	})
+
+	_, err := run("@"+server, "--cookie", "example.com", "A", "AAAA")
+	assert.Nil(t, err)
+
+	// The first query sends a random client cookie, then the follow-up and the next query send the full cookie
+	mu.Lock()
+	assert.Len(t, cookies, 3)
+	assert.Regexp(t, `^[0-9a-f]{16}$`, cookies[0])
+	assert.Equal(t, cookies[0]+serverCookie, cookies[1])
+	assert.Equal(t, cookies[0]+serverCookie, cookies[2])
+	mu.Unlock()
+
+	// -v shows the cookie validation and whether the follow-up matched
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetLevel(log.InfoLevel)
+	})
+	_, err = run("@"+server, "-v", "--cookie", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `Cookie: full cookie, client [0-9a-f]{16} server 0102030405060708 \(8 bytes\)`, logs.String())
+	assert.Contains(t, logs.String(), "Cookie follow-up: server cookie matched")
+
+	// A hex value after --cookie sets the cookie instead of being taken as the name
+	mu.Lock()
+	cookies = nil
+	mu.Unlock()
+	_, err = run("@"+server, "--cookie", "0011223344556677", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, "0011223344556677", opts.Cookie)
+	assert.Equal(t, "example.com", opts.Name)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"0011223344556677"}, cookies)
+}
+
+func TestMainBadCookieRetry(t *testing.T) {
+	const clientCookie, serverCookie = "0011223344556677", "0102030405060708"
+	var mu sync.Mutex
+	var cookies []string
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		cookie, ok := util.EDNSOption[*dns.EDNS0_COOKIE](r)
+		assert.True(t, ok)
+		mu.Lock()
+		cookies = append(cookies, cookie.Cookie)
+		mu.Unlock()
+
+		// Reject queries without a server cookie, returning a fresh one
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.SetEdns0(1232, false)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie.Cookie[:16] + serverCookie})
+		if len(cookie.Cookie) == 16 {
+			m.Rcode = dns.RcodeBadCookie
+		} else {
+			rr, _ := dns.NewRR("example.com. 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--cookie="+clientCookie, "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "example.com. 1m A 192.0.2.1")
+	assert.Contains(t, out.String(), "Cookie: BADCOOKIE for example.com. A, retried with server cookie "+serverCookie+"\n")
+
+	// Exactly one retry, with the server cookie after the unchanged client cookie
+	mu.Lock()
+	assert.Equal(t, []string{clientCookie, clientCookie + serverCookie}, cookies)
+	cookies = nil
+	mu.Unlock()
+
+	out, err = run("@"+server, "--cookie="+clientCookie, "--format=json", "--json-compact", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), `"cookie_handshakes":[{"question":"example.com. A","server_cookie":"`+serverCookie+`"}]`)
+}
+
+func TestMainService(t *testing.T) {
+	zone := map[string][]string{
+		"_ipp._tcp.example.com. PTR":         {"_ipp._tcp.example.com. 60 IN PTR Printer._ipp._tcp.example.com."},
+		"Printer._ipp._tcp.example.com. SRV": {"Printer._ipp._tcp.example.com. 60 IN SRV 0 0 631 printer.example.com."},
+		"Printer._ipp._tcp.example.com. TXT": {`Printer._ipp._tcp.example.com. 60 IN TXT "txtvers=1" "rp=printer"`},
+		"printer.example.com. A":             {"printer.example.com. 60 IN A 192.0.2.1"},
+		"printer.example.com. AAAA":          {"printer.example.com. 60 IN AAAA 2001:db8::1"},
+	}
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		for _, s := range zone[q.Name+" "+dns.TypeToString[q.Qtype]] {
+			rr, err := dns.NewRR(s)
+			assert.Nil(t, err)
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--service", "_ipp._tcp", "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, `_ipp._tcp.example.com.
+  Printer._ipp._tcp.example.com.
+    printer.example.com.:631 priority 0 weight 0 (192.0.2.1, 2001:db8::1)
+    txtvers=1 rp=printer
+`, out.String())
+
+	out, err = run("@"+server, "--service", "_http._tcp", "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "_http._tcp.example.com.\n  no instances\n", out.String())
+}
+
+func TestMainWireOut(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+	dir := t.TempDir()
+
+	_, err := run("@"+server, "--wire-out", filepath.Join(dir, "response.bin"), "example.com", "A")
+	assert.Nil(t, err)
+	b, err := os.ReadFile(filepath.Join(dir, "response.bin"))
+	assert.Nil(t, err)
+	var msg dns.Msg
+	assert.Nil(t, msg.Unpack(b))
+	assert.Equal(t, "example.com.", msg.Question[0].Name)
+
+	_, err = run("@"+server, "--wire-out", filepath.Join(dir, "multi.bin"), "example.com", "A", "AAAA")
+	assert.Nil(t, err)
+	for _, file := range []string{"multi-1.bin", "multi-2.bin"} {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		assert.Nil(t, err)
+		assert.Nil(t, msg.Unpack(b))
+	}
+}
+
+func TestMainCheckPoisoning(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if strings.EqualFold(r.Question[0].Name, "dnssec-failed.org.") {
+			m.Rcode = dns.RcodeServerFailure
+		}
+		if cookie, ok := util.EDNSOption[*dns.EDNS0_COOKIE](r); ok {
+			m.SetEdns0(1232, false)
+			m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie.Cookie[:16] + "0102030405060708"})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--compare-cache-poisoning-resistance", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `^DNS cookies +server cookie returned
+0x20 case preservation preserved (?i:example\.com\.)
+DNSSEC validation +SERVFAIL for bogus dnssec-failed\.org\.
+Duplicate queries +10 replies with matching IDs and answers
+$`, out.String())
+
+	// A resolver that lowercases the question and doesn't validate
+	server = localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Question[0].Name = strings.ToLower(m.Question[0].Name)
+		_ = w.WriteMsg(m)
+	})
+	out, err = run("@"+server, "--compare-cache-poisoning-resistance", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "no server cookie")
+	assert.Contains(t, out.String(), "NOERROR for bogus dnssec-failed.org.")
+}
+
+func TestMainEnvironment(t *testing.T) {
+	answer := func(ip string) dns.HandlerFunc {
+		return func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(ip),
+			})
+			_ = w.WriteMsg(m)
+		}
+	}
+	profileServer := localServer(t, answer("192.0.2.1"))
+	envServer := localServer(t, answer("192.0.2.2"))
+
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(config, []byte(fmt.Sprintf(`profiles:
+  local:
+    server: %s
+    format: json
+    transport: bogus
+`, profileServer)), 0o644))
+
+	t.Setenv("Q_FORMAT", "raw")
+	t.Setenv("Q_TYPE", "A")
+	t.Setenv("Q_TRANSPORT", "plain")
+	t.Setenv(cli.DefaultServerVar, envServer)
+
+	// Environment variables take precedence over the profile
+	out, err := run("--config", config, "--profile", "local", "example.com")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), ";; opcode: QUERY")
+	assert.Contains(t, out.String(), "192.0.2.2")
+
+	// Command line flags take precedence over environment variables
+	out, err = run("--format=column", "@"+profileServer, "example.com")
+	assert.Nil(t, err)
+	assert.NotContains(t, out.String(), ";; opcode: QUERY")
+	assert.Contains(t, out.String(), "192.0.2.1")
+
+	// Q_SERVER takes precedence over Q_DEFAULT_SERVER
+	t.Setenv(cli.ServerVar, profileServer)
+	out, err = run("example.com")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "192.0.2.1")
+
+	// Q_TRANSPORT sets the transport of servers without a scheme, unless --transport is given
+	t.Setenv("Q_TRANSPORT", "bogus")
+	_, err = run("example.com")
+	assert.ErrorContains(t, err, "unsupported transport bogus")
+	_, err = run("--transport=plain", "example.com")
+	assert.Nil(t, err)
+}
+
+func TestMainRoundtripOverTime(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--roundtrip-over-time", "50ms", "--sample-interval", "20ms", "example.com", "A")
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, "timestamp,server,name,type,rcode,latency_ms,error", lines[0])
+	assert.Len(t, lines, 4) // Samples at 0, 20, and 40ms
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT[^,]+,`+regexp.QuoteMeta(server)+`,example\.com\.,A,NOERROR,\d+\.\d{3},$`, lines[1])
+
+	out, err = run("@"+server, "--roundtrip-over-time", "10ms", "--sample-interval", "20ms", "--sample-format", "ndjson", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `^\{"timestamp":"[^"]+","server":"`+regexp.QuoteMeta(server)+`","name":"example.com.","type":"A","rcode":"NOERROR","latency_ms":[\d.]+\}\n$`, out.String())
+
+	_, err = run("@"+server, "--roundtrip-over-time", "10ms", "--sample-format", "xml", "example.com", "A")
+	assert.NotNil(t, err)
+
+	// Sampling continues for longer than --timeout, which only applies to each query
+	out, err = run("@"+server, "--roundtrip-over-time", "400ms", "--sample-interval", "100ms", "--timeout", "150ms", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 5)
+}
+
+func TestMainPositionalArgs(t *testing.T) {
+	var questions []dns.Question
+	var mu sync.Mutex
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		questions = append(questions, r.Question[0])
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	// asked returns the questions received since it was last called
+	asked := func() []dns.Question {
+		mu.Lock()
+		defer mu.Unlock()
+		q := questions
+		questions = nil
+		return q
+	}
+
+	for _, args := range [][]string{
+		{"example.com", "MX", "@" + server},
+		{"MX", "example.com", "@" + server},
+		{"@" + server, "IN", "mx", "example.com"},
+	} {
+		_, err := run(args...)
+		assert.Nil(t, err)
+		assert.Equal(t, []dns.Question{{Name: "example.com.", Qtype: dns.TypeMX, Qclass: dns.ClassINET}}, asked())
+	}
+
+	// RFC 3597 type notation
+	_, err := run("@"+server, "TYPE65", "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, dns.TypeHTTPS, asked()[0].Qtype)
+
+	// Class isn't mistaken for the name
+	_, err = run("@"+server, "CH", "TXT", "version.bind")
+	assert.Nil(t, err)
+	assert.Equal(t, []dns.Question{{Name: "version.bind.", Qtype: dns.TypeTXT, Qclass: dns.ClassCHAOS}}, asked())
+
+	_, err = run("@"+server, "example.com", "example.org", "A")
+	assert.ErrorContains(t, err, "multiple names given (example.com, example.org)")
+}
+
+func TestMainNamesFile(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	path := filepath.Join(t.TempDir(), "names.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("# Names to check\nexample.com\n\nbad name\nexample.org.\n"), 0644))
+
+	out, err := run("@"+server, "--file", path, "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `^example\.com\. .* A 192\.0\.2\.1\nexample\.org\. .* A 192\.0\.2\.1\n$`, out.String())
+
+	out, err = run("@"+server, "--file", path, "A", "--format", "json", "--json-compact")
+	assert.Nil(t, err)
+	assert.Regexp(t, `^\[\{"schema_version":1,.*"example\.com\.".*\},\{"schema_version":1,.*"example\.org\.".*\}\]`, out.String())
+
+	_, err = run("@"+server, "--file", path, "example.net")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, os.WriteFile(path, []byte("# No names\n"), 0644))
+	_, err = run("@"+server, "--file", path)
+	assert.ErrorContains(t, err, "no names to query")
+}
+
+func TestMainRetryBackoff(t *testing.T) {
+	var times []time.Time
+	var mu sync.Mutex
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		_ = w.WriteMsg(m)
+	})
+
+	// SERVFAIL isn't retried by default
+	_, err := run("@"+server, "--retry=2", "example.com", "A")
+	assert.Nil(t, err)
+	mu.Lock()
+	assert.Len(t, times, 1)
+	times = nil
+	mu.Unlock()
+
+	_, err = run("@"+server, "--retry=2", "--retry-servfail", "--retry-backoff=20ms", "example.com", "A")
+	assert.Nil(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, times, 3)
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, times[2].Sub(times[1]), 40*time.Millisecond)
+}
+
+func TestMainZoneTransfer(t *testing.T) {
+	soa := func(serial uint32) dns.RR {
+		return &dns.SOA{
+			Hdr:    dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+			Ns:     "ns1.example.com.",
+			Mbox:   "hostmaster.example.com.",
+			Serial: serial,
+		}
+	}
+	a := func(name, addr string) dns.RR {
+		return &dns.A{
+			Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP(addr),
+		}
+	}
+
+	var ixfrSerial uint32
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		envelopes := []*dns.Envelope{
+			{RR: []dns.RR{soa(2024010102), a("www.example.com.", "192.0.2.1")}},
+			{RR: []dns.RR{a("mail.example.com.", "192.0.2.2"), soa(2024010102)}},
+		}
+		if r.Question[0].Qtype == dns.TypeIXFR {
+			ixfrSerial = r.Ns[0].(*dns.SOA).Serial
+			envelopes = []*dns.Envelope{{RR: []dns.RR{soa(2024010101)}}}
+		}
+
+		ch := make(chan *dns.Envelope)
+		done := make(chan struct{})
+		go func() {
+			_ = new(dns.Transfer).Out(w, r, ch)
+			close(done)
+		}()
+		for _, env := range envelopes {
+			ch <- env
+		}
+		close(ch)
+		<-done
+		w.Hijack()
+	})
+
+	out, err := run("@tcp://"+server, "example.com", "AXFR")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "www.example.com.\t60\tIN\tA\t192.0.2.1\nmail.example.com.\t60\tIN\tA\t192.0.2.2\n")
+	assert.Contains(t, out.String(), ";; AXFR of example.com. from "+server+": 4 records in 2 messages")
+
+	out, err = run("@"+server, "example.com", "IXFR=2024010101")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2024010101), ixfrSerial)
+	assert.Contains(t, out.String(), ";; IXFR of example.com. from "+server+": 1 records in 1 messages")
+
+	_, err = run("@tls://"+server, "example.com", "AXFR")
+	assert.ErrorContains(t, err, "zone transfers are only supported over plain DNS and TCP")
+}
+
+func TestMainFormatShort(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Qtype == dns.TypeMX {
+			m.Answer = append(m.Answer, &dns.MX{
+				Hdr:        dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeMX, Class: dns.ClassINET, Ttl: 60},
+				Preference: 10,
+				Mx:         "mail.example.com.",
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--short", "example.com", "MX")
+	assert.Nil(t, err)
+	assert.Equal(t, "10 mail.example.com.\n", out.String())
+
+	out, err = run("@"+server, "--format=short", "example.com", "A")
+	assert.ErrorContains(t, err, "no answers")
+	assert.Empty(t, out.String())
+}
+
+// writePcap writes DNS messages as IPv4 UDP packets between a client and server to a raw IP pcap file
+func writePcap(t *testing.T, msgs ...*dns.Msg) string {
+	var buf bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeRaw)
+	buf.Write(header)
+
+	client, server := []byte{192, 0, 2, 10}, []byte{192, 0, 2, 53}
+	for _, msg := range msgs {
+		data, err := msg.Pack()
+		assert.Nil(t, err)
+
+		src, dst, srcPort, dstPort := client, server, uint16(40000), uint16(53)
+		if msg.Response {
+			src, dst, srcPort, dstPort = server, client, 53, 40000
+		}
+		packet := make([]byte, 28, 28+len(data))
+		packet[0] = 0x45
+		binary.BigEndian.PutUint16(packet[2:], uint16(28+len(data)))
+		packet[8], packet[9] = 64, 17
+		copy(packet[12:], src)
+		copy(packet[16:], dst)
+		binary.BigEndian.PutUint16(packet[20:], srcPort)
+		binary.BigEndian.PutUint16(packet[22:], dstPort)
+		binary.BigEndian.PutUint16(packet[24:], uint16(8+len(data)))
+		packet = append(packet, data...)
+
+		record := make([]byte, 16)
+		binary.LittleEndian.PutUint32(record[8:], uint32(len(packet)))
+		binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+		buf.Write(record)
+		buf.Write(packet)
+	}
+
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+	return path
+}
+
+func TestMainReplayPcap(t *testing.T) {
+	reply := func(r *dns.Msg, addr string) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if addr != "" {
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(addr),
+			})
+		}
+		return m
+	}
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := reply(r, "192.0.2.1")
+		if r.Question[0].Name == "gone.example.com." {
+			m = reply(r, "")
+			m.Rcode = dns.RcodeNameError
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	same, changed, gone, unanswered := new(dns.Msg), new(dns.Msg), new(dns.Msg), new(dns.Msg)
+	same.SetQuestion("same.example.com.", dns.TypeA)
+	changed.SetQuestion("changed.example.com.", dns.TypeA)
+	gone.SetQuestion("gone.example.com.", dns.TypeA)
+	unanswered.SetQuestion("unanswered.example.com.", dns.TypeA)
+	capture := writePcap(t,
+		same, changed, gone, unanswered,
+		reply(same, "192.0.2.1"), reply(changed, "192.0.2.2"), reply(gone, "192.0.2.3"),
+	)
+
+	out, err := run("@"+server, "--replay-pcap", capture)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "✓ same.example.com. A NOERROR\n")
+	assert.Contains(t, out.String(), "✗ changed.example.com. A -changed.example.com. A 192.0.2.2, +changed.example.com. A 192.0.2.1\n")
+	assert.Contains(t, out.String(), "✗ gone.example.com. A rcode NOERROR → NXDOMAIN, -gone.example.com. A 192.0.2.3, +A NXDOMAIN (no answers)\n")
+	assert.Contains(t, out.String(), "? unanswered.example.com. A no response in capture\n")
+	assert.Contains(t, out.String(), "1/4 replayed responses matched the capture")
+}
+
+func TestMainReverseInferred(t *testing.T) {
+	var mu sync.Mutex
+	var questions []string
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		questions = append(questions, fmt.Sprintf("%s %s", r.Question[0].Name, dns.TypeToString[r.Question[0].Qtype]))
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Qtype == dns.TypePTR {
+			m.Answer = append(m.Answer, &dns.PTR{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 60},
+				Ptr: "host.example.",
+			})
+		}
+		_ = w.WriteMsg(m)
+	})
+	asked := func(args ...string) []string {
+		mu.Lock()
+		questions = nil
+		mu.Unlock()
+		_, err := run(append([]string{"@" + server}, args...)...)
+		assert.Nil(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		return questions
+	}
+
+	assert.Equal(t, []string{"1.2.0.192.in-addr.arpa. PTR"}, asked("192.0.2.1"))
+	assert.Equal(t, []string{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa. PTR"}, asked("2001:db8::1"))
+	assert.Equal(t, []string{"192.0.2.1. A"}, asked("192.0.2.1", "A"))
+	assert.NotContains(t, asked("192.0.2.1", "--no-reverse"), "1.2.0.192.in-addr.arpa. PTR")
+}
+
+// writeCert writes a self-signed certificate and its key for a common name to PEM files, returning their paths
+func writeCert(t *testing.T, cn string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: cn}}, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestMainTLSClientCertFor(t *testing.T) {
+	serverCert, serverKey := writeCert(t, "server")
+	cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	assert.Nil(t, err)
+
+	// Answer with the common name of the client certificate
+	var mu sync.Mutex
+	var clientCN string
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			c, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			clientCN = c.Subject.CommonName
+			mu.Unlock()
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	server := &dns.Server{Listener: l, Net: "tcp-tls", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+	presented := func(args ...string) string {
+		mu.Lock()
+		clientCN = ""
+		mu.Unlock()
+		_, err := run(append([]string{"@tls://" + l.Addr().String(), "-i", "example.com", "A"}, args...)...)
+		assert.Nil(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		return clientCN
+	}
+
+	localCert, localKey := writeCert(t, "local")
+	otherCert, otherKey := writeCert(t, "other")
+	defaultCert, defaultKey := writeCert(t, "default")
+	assert.Equal(t, "local", presented(
+		"--tls-client-cert-for=127.0.0.1="+localCert+","+localKey,
+		"--tls-client-cert-for=other.example.com="+otherCert+","+otherKey,
+	))
+
+	// The TLS server name selects the certificate if it's set
+	assert.Equal(t, "other", presented(
+		"--tls-server-name=ns.other.example.com",
+		"--tls-client-cert-for=127.0.0.1="+localCert+","+localKey,
+		"--tls-client-cert-for=*.other.example.com="+otherCert+","+otherKey,
+	))
+
+	// Servers without a mapping get the default certificate
+	assert.Equal(t, "default", presented(
+		"--tls-client-cert="+defaultCert, "--tls-client-key="+defaultKey,
+		"--tls-client-cert-for=other.example.com="+otherCert+","+otherKey,
+	))
+
+	_, err = run("@tls://"+l.Addr().String(), "--tls-client-cert-for=127.0.0.1", "example.com", "A")
+	assert.ErrorContains(t, err, "invalid client certificate mapping 127.0.0.1")
+}
+
+func TestMainTLSSNIPinAndInfo(t *testing.T) {
+	serverCert, serverKey := writeCert(t, "server")
+	cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	assert.Nil(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.Nil(t, err)
+
+	// Record the SNI sent by the client
+	var mu sync.Mutex
+	var sni string
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			mu.Lock()
+			sni = hello.ServerName
+			mu.Unlock()
+			return &cert, nil
+		},
+	})
+	assert.Nil(t, err)
+	server := &dns.Server{Listener: l, Net: "tcp-tls", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+
+	_, err = run("@tls://"+l.Addr().String(), "-i", "--tls-sni=dns.example", "--pin-sha256="+tlsutil.SPKIHash(leaf), "example.com", "A")
+	assert.Nil(t, err)
+	mu.Lock()
+	assert.Equal(t, "dns.example", sni)
+	mu.Unlock()
+
+	_, err = run("@tls://"+l.Addr().String(), "-i", "--pin-sha256=47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", "example.com", "A")
+	assert.ErrorContains(t, err, "got sha256 "+tlsutil.SPKIHash(leaf))
+
+	_, err = run("@tls://"+l.Addr().String(), "--pin-sha256=invalid", "example.com", "A")
+	assert.ErrorContains(t, err, "invalid SPKI pin invalid")
+
+	out, err := run("@tls://"+l.Addr().String(), "-i", "--tls-info", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `Meta:\nTLS SNI: none ALPN: none\nTLS version: TLS 1\.3 Cipher: TLS_\w+ Resumed: false\n`, out.String())
+
+	out, err = run("@tls://"+l.Addr().String(), "-i", "--format=json", "--json-compact", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `"tls":\{"server_name":"","alpn":"","version":"TLS 1\.3","cipher_suite":"TLS_\w+","resumed":false\}`, out.String())
+}
+
+func TestMainRequireECSScope(t *testing.T) {
+	honors := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		subnet, _ := util.EDNSOption[*dns.EDNS0_SUBNET](r)
+		subnet.SourceScope = 16
+		m.SetEdns0(1232, false)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, subnet)
+		_ = w.WriteMsg(m)
+	})
+	ignores := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.SetEdns0(1232, false)
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+honors, "--subnet=192.0.2.0/24", "--require-ecs-scope", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, "ECS scope: /16 for 192.0.2.0/24\n", out.String())
+
+	_, err = run("@"+honors, "@"+ignores, "--subnet=192.0.2.0/24", "--require-ecs-scope", "example.com", "A")
+	assert.EqualError(t, err, "no ECS scope returned by "+ignores+" (example.com.)")
+
+	_, err = run("@"+honors, "--require-ecs-scope", "example.com", "A")
+	assert.EqualError(t, err, "--require-ecs-scope requires --subnet")
+}
+
+func TestMainTail(t *testing.T) {
+	// Change the answer after the second poll
+	var mu sync.Mutex
+	var polls int
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		polls++
+		address := net.IPv4(192, 0, 2, 1)
+		if polls > 2 {
+			address = net.IPv4(192, 0, 2, 2)
+		}
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   address,
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--tail=10ms", "--tail-count=3", "example.com", "A")
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 6) {
+		assert.Regexp(t, `^=== \d{4}-\d\d-\d\dT\S+$`, lines[0])
+		assert.Equal(t, "example.com. 1m A 192.0.2.1", lines[1])
+		assert.Regexp(t, `^=== \S+ unchanged$`, lines[2])
+		assert.Regexp(t, `^=== \S+ changed$`, lines[4])
+		assert.Equal(t, "example.com. 1m A 192.0.2.2", lines[5])
+	}
+
+	// Polling continues for longer than --timeout, which only applies to each query
+	out, err = run("@"+server, "--tail=100ms", "--tail-count=4", "--timeout=150ms", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, strings.Count(out.String(), "=== "))
+}
+
+// socks5Server runs a minimal SOCKS5 proxy (RFC 1928) requiring a username and password (RFC 1929), returning its
+// address and a counter of the connections it relayed
+func socks5Server(t *testing.T, user, pass string) (string, *atomic.Int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	var relayed atomic.Int32
+	handle := func(conn net.Conn) {
+		defer conn.Close()
+		buf := make([]byte, 262)
+
+		// Method negotiation, offering only username/password authentication
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{5, 2})
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		username := make([]byte, buf[1])
+		_, _ = io.ReadFull(conn, username)
+		_, _ = io.ReadFull(conn, buf[:1])
+		password := make([]byte, buf[0])
+		_, _ = io.ReadFull(conn, password)
+		if string(username) != user || string(password) != pass {
+			_, _ = conn.Write([]byte{1, 1})
+			return
+		}
+		_, _ = conn.Write([]byte{1, 0})
+
+		// CONNECT request to an IPv4 address
+		if _, err := io.ReadFull(conn, buf[:10]); err != nil || buf[1] != 1 || buf[3] != 1 {
+			return
+		}
+		target, err := net.Dial("tcp", net.JoinHostPort(net.IP(buf[4:8]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(buf[8:10])))))
+		if err != nil {
+			_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer target.Close()
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		relayed.Add(1)
+		go func() {
+			_, _ = io.Copy(target, conn)
+		}()
+		_, _ = io.Copy(conn, target)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return l.Addr().String(), &relayed
+}
+
+func TestMainProxy(t *testing.T) {
+	var network atomic.Value
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		network.Store(w.RemoteAddr().Network())
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.IPv4(192, 0, 2, 1),
+		})
+		_ = w.WriteMsg(m)
+	})
+	proxy, relayed := socks5Server(t, "user", "pass")
+
+	// Plain DNS is sent over TCP through the proxy
+	out, err := run("@"+server, "--proxy=socks5://user:pass@"+proxy, "example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "192.0.2.1")
+	assert.Equal(t, int32(1), relayed.Load())
+	assert.Equal(t, "tcp", network.Load())
+
+	_, err = run("@"+server, "--proxy=socks5://user:wrong@"+proxy, "example.com", "A")
+	assert.ErrorContains(t, err, "through proxy")
+	assert.Equal(t, int32(1), relayed.Load())
+
+	_, err = run("@quic://"+server, "--proxy=socks5://user:pass@"+proxy, "example.com", "A")
+	assert.ErrorContains(t, err, "quic transport can't be used through a SOCKS5 proxy")
+
+	_, err = run("@"+server, "--proxy=http://"+proxy, "example.com", "A")
+	assert.ErrorContains(t, err, "expected socks5://[user:pass@]host:port")
+}
+
+func TestMainFailFast(t *testing.T) {
+	var queried atomic.Int32
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		queried.Add(1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	_ = closed.Close()
+
+	// An unreachable server stops the run before the next one is queried
+	_, err = run("@tcp://"+closed.Addr().String(), "@"+server, "--fail-fast", "--server-concurrency=1", "example.com", "A")
+	assert.ErrorContains(t, err, "stopping at first failure from tcp://"+closed.Addr().String())
+	assert.Equal(t, int32(0), queried.Load())
+
+	// So does a failed assertion
+	_, err = run("@"+server, "@tcp://"+closed.Addr().String(), "--fail-fast", "--server-concurrency=1", "--expect-count=1", "example.com", "A")
+	assert.ErrorContains(t, err, "stopping at first failure from "+server+": expected exactly 1 A answers for example.com.")
+	assert.Equal(t, int32(1), queried.Load())
+
+	_, err = run("@"+server, "--fail-fast", "--continue-on-error", "example.com", "A")
+	assert.EqualError(t, err, "--fail-fast and --continue-on-error can't be used together")
+}
+
+func TestMainZone(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		soa := &dns.SOA{
+			Hdr:    dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
+			Ns:     "ns.example.com.",
+			Mbox:   "hostmaster.example.com.",
+			Serial: 1,
+		}
+		if r.Question[0].Name == "example.com." && r.Question[0].Qtype == dns.TypeSOA {
+			m.Answer = append(m.Answer, soa)
+		} else {
+			m.Ns = append(m.Ns, soa)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--zone", "www.a.example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, "Zone: www.a.example.com. is 2 labels under zone example.com.\n", out.String())
+
+	out, err = run("@"+server, "--zone", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, "Zone: example.com. is the apex of zone example.com.\n", out.String())
+
+	out, err = run("@"+server, "--zone", "--format=json", "--json-compact", "www.example.com", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), `"zone":{"name":"www.example.com.","zone":"example.com.","depth":1}`)
+}
+
+func TestMainChaosDefaults(t *testing.T) {
+	var mu sync.Mutex
+	var questions []string
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		q := r.Question[0]
+		questions = append(questions, fmt.Sprintf("%s %s %s", q.Name, dns.ClassToString[q.Qclass], dns.TypeToString[q.Qtype]))
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+	asked := func(args ...string) []string {
+		mu.Lock()
+		questions = nil
+		mu.Unlock()
+		_, err := run(append([]string{"@" + server}, args...)...)
+		assert.Nil(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		return questions
+	}
+
+	assert.Equal(t, []string{"version.bind. CH TXT"}, asked("--chaos"))
+	assert.Equal(t, []string{"hostname.bind. CH TXT"}, asked("--class", "chaos", "hostname.bind"))
+	assert.Equal(t, []string{"id.server. CH TXT"}, asked("-C", "ch", "id.server"))
+	assert.Equal(t, []string{"version.bind. CH NS"}, asked("-C", "3", "version.bind", "NS"))
+	assert.Equal(t, []string{"example.com. HS A"}, asked("--class", "Hesiod", "example.com", "A"))
+}
+
+func TestMainProbeQNAMEMinimization(t *testing.T) {
+	var text atomic.Value
+	text.Store("HOORAY - QNAME minimisation is enabled on your resolver :)!")
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		assert.Equal(t, "qnamemintest.internet.nl.", r.Question[0].Name)
+		assert.Equal(t, dns.TypeTXT, r.Question[0].Qtype)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+			Txt: []string{text.Load().(string)},
+		})
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--probe-qname-minimization")
+	assert.Nil(t, err)
+	assert.Equal(t, server+" qnamemintest.internet.nl. minimized ("+text.Load().(string)+")\n", out.String())
+
+	text.Store("NO - QNAME minimisation is NOT enabled on your resolver :(")
+	out, err = run("@"+server, "--probe-qname-minimization")
+	assert.Nil(t, err)
+	assert.Equal(t, server+" qnamemintest.internet.nl. not minimized ("+text.Load().(string)+")\n", out.String())
+}
+
+func TestMainDNSSECOverhead(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.IPv4(192, 0, 2, 1),
+		})
+		if opt := r.IsEdns0(); opt != nil && opt.Do() {
+			m.Answer = append(m.Answer, &dns.RRSIG{
+				Hdr:         dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 60},
+				TypeCovered: dns.TypeA,
+				Algorithm:   dns.ECDSAP256SHA256,
+				Labels:      2,
+				OrigTtl:     60,
+				SignerName:  r.Question[0].Name,
+				Signature:   "dGVzdHNpZ25hdHVyZXRlc3RzaWduYXR1cmV0ZXN0c2lnbmF0dXJldGVzdHNpZ25hdHVyZQ==",
+			})
+			m.SetEdns0(1232, true)
+		} else if opt != nil {
+			m.SetEdns0(1232, false)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--dnssec-overhead", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Regexp(t, `^example\.com\. A: (\d+) B without DO, (\d+) B with DO \(\+\d+ B, \+\d+\.\d%\), 1 RRSIGs\n$`, out.String())
+}
+
+func TestMainServerParams(t *testing.T) {
+	var last atomic.Pointer[dns.Msg]
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		last.Store(r)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	_, err := run("@"+server+"?dnssec=1&nsid&udp-buffer=4096&rd=0&unknown=1", "example.com", "A")
+	assert.Nil(t, err)
+	query := last.Load()
+	opt := query.IsEdns0()
+	if assert.NotNil(t, opt) {
+		assert.True(t, opt.Do())
+		assert.Equal(t, uint16(4096), opt.UDPSize())
+		_, ok := util.EDNSOption[*dns.EDNS0_NSID](query)
+		assert.True(t, ok)
+	}
+	assert.False(t, query.RecursionDesired)
+	assert.Equal(t, []string{server}, opts.Server)
+
+	_, err = run("@"+server+"?udp-buffer=large", "example.com", "A")
+	assert.ErrorContains(t, err, "invalid option udp-buffer=large in server "+server)
+
+	// Options would leak into the other servers
+	_, err = run("-s", server+"?dnssec=1&timeout=1s", "-s", server, "example.com", "A")
+	assert.ErrorContains(t, err, "option dnssec in server "+server+" would apply to every server, set --dnssec instead")
+}
+
+func TestMainOverallTimeout(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(time.Second)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	start := time.Now()
+	_, err := run("@"+server, "--overall-timeout", "200ms", "example.com", "A")
+	assert.EqualError(t, err, "overall timeout of 200ms exceeded")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestMainTimeoutPerQueryInLongModes(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(100 * time.Millisecond)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+	names := filepath.Join(t.TempDir(), "names.txt")
+	assert.Nil(t, os.WriteFile(names, []byte("a.example.com\nb.example.com\nc.example.com\n"), 0o644))
+
+	// Six queries take longer than --timeout, but each of them is within it
+	start := time.Now()
+	out, err := run("@"+server, "--timeout", "300ms", "--measure-cache-hit-ratio", names)
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "Cache hit ratio:")
+	assert.Greater(t, time.Since(start), 300*time.Millisecond)
+
+	_, err = run("@"+server, "--timeout", "300ms", "--overall-timeout", "250ms", "--measure-cache-hit-ratio", names)
+	assert.EqualError(t, err, "overall timeout of 250ms exceeded")
+}
+
+func TestMainRetryBackoffLongerThanTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		if attempts.Add(1) < 3 {
+			m.SetRcode(r, dns.RcodeServerFailure)
+		} else {
+			m.SetReply(r)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	// Each attempt is within --timeout, but the retries and their backoff aren't
+	_, err := run("@"+server, "--timeout", "100ms", "--retry=2", "--retry-servfail", "--retry-backoff=80ms", "example.com", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestMainSaveReplay(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		})
+		_ = w.WriteMsg(m)
+	})
+	saved := filepath.Join(t.TempDir(), "responses.json")
+
+	for _, format := range []string{"pretty", "json"} {
+		live, err := run("@"+server, "--format", format, "--save", saved, "example.com", "A")
+		assert.Nil(t, err)
+
+		// Replay against an unreachable server to make sure nothing is queried
+		replayed, err := run("@127.0.0.1:1", "--format", format, "--replay", saved, "example.com", "A")
+		assert.Nil(t, err)
+		assert.Equal(t, live.String(), replayed.String())
+	}
+}
+
+func TestMainSourceIP(t *testing.T) {
+	var remote atomic.Value
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		host, _, _ := net.SplitHostPort(w.RemoteAddr().String())
+		remote.Store(host)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	// Any address in 127.0.0.0/8 can reach the server on Linux, so queries come from the source IP
+	if runtime.GOOS == "linux" {
+		_, err := run("@"+server, "--source-ip=127.0.0.2", "example.com", "A")
+		assert.Nil(t, err)
+		assert.Equal(t, "127.0.0.2", remote.Load())
+
+		_, err = run("@tcp://"+server, "--source-ip=127.0.0.3", "example.com", "A")
+		assert.Nil(t, err)
+		assert.Equal(t, "127.0.0.3", remote.Load())
+	}
+
+	_, err := run("@"+server, "--source-ip=::1", "example.com", "A")
+	assert.ErrorContains(t, err, "source IP ::1 is IPv6 but server "+server+" is IPv4")
+
+	_, err = run("@quic://"+server, "--source-ip=127.0.0.1", "example.com", "A")
+	assert.ErrorContains(t, err, "quic transport can't be used with --source-ip or --interface")
+
+	_, err = run("@"+server, "--source-ip=invalid", "example.com", "A")
+	assert.ErrorContains(t, err, "invalid source IP invalid")
+
+	_, err = run("@"+server, "--interface=q-nonexistent0", "example.com", "A")
+	assert.ErrorContains(t, err, "invalid interface q-nonexistent0")
+}
+
+func TestMainColor(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.IPv4(192, 0, 2, 1),
+		})
+		_ = w.WriteMsg(m)
+	})
+	t.Cleanup(func() {
+		_ = util.SetTheme("default")
+		util.UseColor = false
+	})
+
+	// Output that isn't a terminal isn't colored by default
+	out, err := run("--color=auto", "@"+server, "example.com", "A")
+	assert.Nil(t, err)
+	assert.NotContains(t, out.String(), "\033[")
+
+	for _, args := range [][]string{{"--color"}, {"--color=always"}, {"--color", "always"}, {"+color"}, {"--color=true"}} {
+		clearOpts()
+		var buf bytes.Buffer
+		assert.Nil(t, driver(append(args, "@"+server, "example.com", "A"), &buf))
+		assert.Contains(t, buf.String(), "\033[1;32m", args)
+	}
+
+	for _, args := range [][]string{{"--color=never"}, {"--color", "never"}, {"--color", "auto"}, {"--color=always", "+nocolor"}, {"--color=false"}} {
+		clearOpts()
+		var buf bytes.Buffer
+		assert.Nil(t, driver(append(args, "@"+server, "example.com", "A"), &buf))
+		assert.NotContains(t, buf.String(), "\033[", args)
+	}
+
+	// A bare --color doesn't take the name as its mode
+	clearOpts()
+	var buf bytes.Buffer
+	assert.Nil(t, driver([]string{"@" + server, "--color", "example.com", "A"}, &buf))
+	assert.Contains(t, buf.String(), "\033[1;32m")
+
+	clearOpts()
+	buf.Reset()
+	assert.Nil(t, driver([]string{"--color=always", "--theme=colorblind", "@" + server, "example.com", "A"}, &buf))
+	assert.Contains(t, buf.String(), "\033[1;38;5;32m")
+	assert.NotContains(t, buf.String(), "\033[1;32m")
+
+	clearOpts()
+	err = driver([]string{"--color=sometimes", "@" + server, "example.com", "A"}, &buf)
+	assert.ErrorContains(t, err, "invalid color mode sometimes. expected: never, auto, or always")
+
+	_, err = run("--theme=neon", "@"+server, "example.com", "A")
+	assert.ErrorContains(t, err, "invalid theme neon")
+}
+
+func TestMainIDSequential(t *testing.T) {
+	var mu sync.Mutex
+	var ids []int
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		ids = append(ids, int(r.Id))
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+	queried := func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		sorted := ids
+		sort.Ints(sorted)
+		ids = nil
+		return sorted
+	}
+
+	_, err := run("@"+server, "--id-sequential", "--id-base=1000", "example.com", "A", "AAAA", "MX")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1000, 1001, 1002}, queried())
+
+	// IDs wrap around after 65535
+	_, err = run("@"+server, "--id-sequential", "--id-base=65535", "example.com", "A", "AAAA")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 65535}, queried())
+
+	_, err = run("@"+server, "--id-sequential", "--id-base=65536", "example.com", "A")
+	assert.ErrorContains(t, err, "--id-base 65536 is out of range, expected 0 to 65535")
+
+	_, err = run("@"+server, "--id-sequential", "--qid=5", "example.com", "A")
+	assert.ErrorContains(t, err, "--qid and --id-sequential can't be used together")
+}
+
+func TestMainValidateTargets(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		q := r.Question[0]
+		m := new(dns.Msg)
+		m.SetReply(r)
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+		switch {
+		case q.Name == "example." && q.Qtype == dns.TypeNS:
+			for _, ns := range []string{"ns1.example.", "ns2.example.", "ns3.example.net."} {
+				m.Answer = append(m.Answer, &dns.NS{Hdr: hdr, Ns: ns})
+			}
+		case q.Name == "example." && q.Qtype == dns.TypeMX:
+			m.Answer = append(m.Answer, &dns.MX{Hdr: hdr, Preference: 10, Mx: "mail.example."})
+		case q.Name == "ns1.example." && q.Qtype == dns.TypeA:
+			m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: net.IPv4(192, 0, 2, 1)})
+		case q.Name == "ns2.example." && q.Qtype == dns.TypeA:
+			m.Answer = append(m.Answer,
+				&dns.CNAME{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60}, Target: "ns.example.net."},
+				&dns.A{Hdr: dns.RR_Header{Name: "ns.example.net.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60}, A: net.IPv4(192, 0, 2, 2)},
+			)
+		case q.Name == "ns3.example.net.":
+			m.Rcode = dns.RcodeNameError
+		case q.Name == "mail.example." && q.Qtype == dns.TypeA:
+			m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: net.IPv4(10, 0, 0, 1)})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	out, err := run("@"+server, "--validate-targets", "example.", "NS", "MX")
+	assert.ErrorContains(t, err, "3 of 4 targets failed validation")
+	assert.Regexp(t, `example\. NS +ns1\.example\. +in +192\.0\.2\.1 +OK`, out.String())
+	assert.Regexp(t, `example\. NS +ns2\.example\. +in +192\.0\.2\.2 +CNAME to ns\.example\.net\.`, out.String())
+	assert.Regexp(t, `example\. NS +ns3\.example\.net\. +out +- +doesn't resolve \(NXDOMAIN\)`, out.String())
+	assert.Regexp(t, `example\. MX +mail\.example\. +in +10\.0\.0\.1 +private address 10\.0\.0\.1`, out.String())
+
+	_, err = run("@"+server, "--validate-targets", "example.", "A")
+	assert.ErrorContains(t, err, "--validate-targets requires an NS or MX query")
+}
+
+func TestMainKeyTag(t *testing.T) {
+	var option atomic.Value
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		if opt := r.IsEdns0(); opt != nil {
+			for _, o := range opt.Option {
+				if o.Option() == 14 {
+					option.Store(o)
+				}
+			}
+		}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})
+
+	_, err := run("@"+server, "--key-tag=20326, 38696", "example.com", "A")
+	assert.Nil(t, err)
+	local, ok := option.Load().(*dns.EDNS0_LOCAL)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x4f, 0x66, 0x97, 0x28}, local.Data)
+
+	_, err = run("@"+server, "--key-tag=20326,65536", "example.com", "A")
+	assert.ErrorContains(t, err, "invalid key tag 65536, expected a number from 0 to 65535")
+}
+
+func TestMainCheckSecondaries(t *testing.T) {
+	server := localServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		q := r.Question[0]
+		m := new(dns.Msg)
+		m.SetReply(r)
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+		switch {
+		case q.Name == "example." && q.Qtype == dns.TypeNS:
+			m.Answer = append(m.Answer, &dns.NS{Hdr: hdr, Ns: "ns1.example."})
+		case q.Name == "ns1.example." && q.Qtype == dns.TypeA:
+			m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: net.IPv4(127, 0, 0, 1)})
+		case q.Name == "example." && q.Qtype == dns.TypeSOA:
+			// Serial 0 is valid
+			m.Answer = append(m.Answer, &dns.SOA{Hdr: hdr, Ns: "ns1.example.", Mbox: "hostmaster.example.", Serial: 0})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	_, port, err := net.SplitHostPort(server)
+	assert.Nil(t, err)
+	out, err := run("@"+server, "--check-secondaries", "example.", "--secondary-port", port)
+	assert.Nil(t, err)
+	assert.Regexp(t, `ns1\.example\. +127\.0\.0\.1 +0 +not returned +\n`, out.String())
+}
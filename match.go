@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/util"
+)
+
+// matchRdata returns the rdata of a record to match against, with TXT strings concatenated so patterns can span them
+func matchRdata(rr dns.RR) string {
+	if txt, ok := rr.(*dns.TXT); ok {
+		return strings.Join(txt.Txt, "")
+	}
+	return strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+}
+
+// matchAnswers returns the first answer record whose rdata matches a pattern, only considering records of rrType if
+// it isn't zero
+func matchAnswers(entries []*output.Entry, pattern *regexp.Regexp, rrType uint16) (dns.RR, bool) {
+	for _, e := range entries {
+		for _, reply := range e.Replies {
+			for _, rr := range reply.Answer {
+				if rrType != 0 && rr.Header().Rrtype != rrType {
+					continue
+				}
+				if pattern.MatchString(matchRdata(rr)) {
+					return rr, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// answerCount returns the number of answer records of a reply with the type of its question
+func answerCount(reply *dns.Msg) int {
+	var n int
+	for _, rr := range reply.Answer {
+		if rr.Header().Rrtype == reply.Question[0].Qtype {
+			n++
+		}
+	}
+	return n
+}
+
+// checkCount asserts that each reply has exactly --expect-count answers of the queried type, or a number of them
+// within --expect-min-count and --expect-max-count
+func checkCount(entries []*output.Entry) error {
+	for _, e := range entries {
+		for _, reply := range e.Replies {
+			if len(reply.Question) == 0 {
+				continue
+			}
+			n := answerCount(reply)
+
+			var expected string
+			switch {
+			case opts.ExpectCount >= 0 && n != opts.ExpectCount:
+				expected = fmt.Sprintf("exactly %d", opts.ExpectCount)
+			case n < opts.ExpectMinCount:
+				expected = fmt.Sprintf("at least %d", opts.ExpectMinCount)
+			case opts.ExpectMaxCount >= 0 && n > opts.ExpectMaxCount:
+				expected = fmt.Sprintf("at most %d", opts.ExpectMaxCount)
+			default:
+				continue
+			}
+			return fmt.Errorf("expected %s %s answers for %s from %s, got %d",
+				expected, dns.TypeToString[reply.Question[0].Qtype], reply.Question[0].Name, e.Server, n)
+		}
+	}
+	return nil
+}
+
+// checkECSScope asserts that each reply returns an EDNS0 client subnet option, listing the servers that ignore ECS
+func checkECSScope(entries []*output.Entry) error {
+	var missing []string
+	for _, e := range entries {
+		for _, reply := range e.Replies {
+			if _, ok := util.EDNSOption[*dns.EDNS0_SUBNET](reply); ok || len(reply.Question) == 0 {
+				continue
+			}
+			missing = append(missing, fmt.Sprintf("%s (%s)", e.Server, reply.Question[0].Name))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no ECS scope returned by %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkEntry checks the assertions that apply to each server's replies on their own, so that --fail-fast can stop at
+// the first server that fails one
+func checkEntry(e *output.Entry) error {
+	entries := []*output.Entry{e}
+	if err := checkCount(entries); err != nil {
+		return err
+	}
+	if opts.RequireECSScope {
+		return checkECSScope(entries)
+	}
+	return nil
+}
+
+// checkMatch asserts that an answer record matches --match, or that none does with --no-match
+func checkMatch(entries []*output.Entry, pattern *regexp.Regexp, rrType uint16) error {
+	scope := "answer"
+	if rrType != 0 {
+		scope = dns.TypeToString[rrType] + " answer"
+	}
+
+	rr, matched := matchAnswers(entries, pattern, rrType)
+	switch {
+	case opts.NoMatch && matched:
+		return fmt.Errorf("%s matched /%s/: %s", scope, pattern, rr)
+	case !opts.NoMatch && !matched:
+		return fmt.Errorf("no %s matched /%s/", scope, pattern)
+	}
+	return nil
+}
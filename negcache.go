@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// negativeCacheDelay is the time to wait between the two queries so that a cached negative TTL has decremented
+const negativeCacheDelay = time.Second
+
+// negativeProbe stores the result of a single query for a nonexistent name
+type negativeProbe struct {
+	rcode   int
+	rtt     time.Duration
+	ttl     uint32 // Negative TTL, min(SOA TTL, SOA MINIMUM) per RFC 2308 section 5
+	minimum uint32
+	hasSOA  bool
+}
+
+// probeNegative sends a query and records its latency and the negative TTL from the authority section
+func probeNegative(ctx context.Context, txp *transport.Transport, name string) (negativeProbe, error) {
+	start := time.Now()
+	reply, err := queryType(ctx, txp, name, dns.TypeA)
+	if err != nil {
+		return negativeProbe{}, err
+	}
+	probe := negativeProbe{rcode: reply.Rcode, rtt: time.Since(start)}
+
+	for _, rr := range reply.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			probe.hasSOA = true
+			probe.minimum = soa.Minttl
+			probe.ttl = min(soa.Hdr.Ttl, soa.Minttl)
+		}
+	}
+	return probe, nil
+}
+
+// classifyNegativeCache describes a resolver's negative caching behavior from two consecutive probes
+func classifyNegativeCache(first, second negativeProbe) (string, bool) {
+	switch {
+	case first.rcode != dns.RcodeNameError || second.rcode != dns.RcodeNameError:
+		return fmt.Sprintf("expected NXDOMAIN, got %s and %s", dns.RcodeToString[first.rcode], dns.RcodeToString[second.rcode]), false
+	case !first.hasSOA || !second.hasSOA:
+		return "no SOA in authority section, negative response can't be cached (RFC 2308 section 5)", false
+	case second.ttl < first.ttl:
+		return fmt.Sprintf("cached, negative TTL decremented from %d to %d", first.ttl, second.ttl), true
+	case second.ttl > first.ttl:
+		return fmt.Sprintf("negative TTL increased from %d to %d", first.ttl, second.ttl), false
+	case second.rtt < first.rtt/2:
+		return "second response was faster but the negative TTL didn't decrement", false
+	default:
+		return "not cached, second response wasn't faster and the negative TTL didn't decrement", false
+	}
+}
+
+// negativeCacheTest queries a random nonexistent name in a zone twice and reports how the resolver cached the NXDOMAIN
+func negativeCacheTest(ctx context.Context, zone string, txp *transport.Transport, out io.Writer) error {
+	name := fmt.Sprintf("q-nxdomain-%08x.%s", rand.Uint32(), dns.Fqdn(zone))
+
+	first, err := probeNegative(ctx, txp, name)
+	if err != nil {
+		return fmt.Errorf("first query for %s: %s", name, err)
+	}
+	if err := sleep(ctx, negativeCacheDelay); err != nil {
+		return err
+	}
+	second, err := probeNegative(ctx, txp, name)
+	if err != nil {
+		return fmt.Errorf("second query for %s: %s", name, err)
+	}
+
+	description, ok := classifyNegativeCache(first, second)
+	color := util.ColorGreen
+	if !ok {
+		color = util.ColorRed
+	}
+	util.MustWritef(out, "%s: %s\n", name, util.Color(color, description))
+	for i, probe := range []negativeProbe{first, second} {
+		util.MustWritef(out, "  Query %d: %s in %s, negative TTL %d (SOA minimum %d)\n",
+			i+1, dns.RcodeToString[probe.rcode], probe.rtt.Round(time.Microsecond), probe.ttl, probe.minimum)
+	}
+	return nil
+}
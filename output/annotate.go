@@ -0,0 +1,96 @@
+package output
+
+import (
+	"strconv"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+// rcodeDescriptions explains the common response codes (RFC 1035, RFC 2136)
+var rcodeDescriptions = map[int]string{
+	dns.RcodeSuccess:        "no error",
+	dns.RcodeFormatError:    "the server couldn't interpret the query",
+	dns.RcodeServerFailure:  "the server couldn't process the query",
+	dns.RcodeNameError:      "the name doesn't exist",
+	dns.RcodeNotImplemented: "the server doesn't support this kind of query",
+	dns.RcodeRefused:        "the server refused to answer",
+	dns.RcodeYXDomain:       "a name exists that shouldn't",
+	dns.RcodeYXRrset:        "an RR set exists that shouldn't",
+	dns.RcodeNXRrset:        "an RR set that should exist doesn't",
+	dns.RcodeNotAuth:        "the server isn't authoritative for the zone",
+	dns.RcodeNotZone:        "the name isn't in the zone",
+}
+
+// numericComment returns a comment function that names a numeric value, leaving other values without a comment
+func numericComment(name func(int) string) func(string) string {
+	return func(value string) string {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return ""
+		}
+		return name(n)
+	}
+}
+
+// keyComments returns the comment for the value of each annotated YAML key
+var keyComments = map[string]func(string) string{
+	"id":         func(string) string { return "ID: chosen by the client and echoed in the reply" },
+	"qr":         func(string) string { return "response" },
+	"aa":         func(string) string { return "authoritative answer" },
+	"tc":         func(string) string { return "truncated, retry over TCP" },
+	"rd":         func(string) string { return "recursion desired" },
+	"ra":         func(string) string { return "recursion available" },
+	"z":          func(string) string { return "reserved, must be zero" },
+	"ad":         func(string) string { return "authentic data, validated with DNSSEC" },
+	"cd":         func(string) string { return "checking disabled, don't validate DNSSEC" },
+	"ttl":        func(string) string { return "seconds the record may be cached for" },
+	"question":   func(string) string { return "question section: what's being asked" },
+	"answer":     func(string) string { return "answer section: records answering the question" },
+	"authority":  func(string) string { return "authority section: records pointing to the authoritative servers" },
+	"additional": func(string) string { return "additional section: related records such as glue" },
+	"opcode": numericComment(func(n int) string {
+		return dns.OpcodeToString[n]
+	}),
+	"rcode": numericComment(func(n int) string {
+		name := dns.RcodeToString[n]
+		if desc, ok := rcodeDescriptions[n]; ok {
+			return name + ": " + desc
+		}
+		return name
+	}),
+}
+
+// annotateNode adds explanatory line comments to the known keys of every mapping in a YAML tree
+func annotateNode(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			comment, ok := keyComments[key.Value]
+			if !ok {
+				continue
+			}
+			if c := comment(value.Value); c != "" {
+				// Comments on scalars and empty collections follow the value, otherwise they follow the key
+				if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+					value.LineComment = c
+				} else {
+					key.LineComment = c
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		annotateNode(child)
+	}
+}
+
+// marshalAnnotatedYAML marshals v as YAML with comments explaining header flags, codes, and sections
+func marshalAnnotatedYAML(v any) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	annotateNode(&node)
+	return yaml.Marshal(&node)
+}
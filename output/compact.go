@@ -0,0 +1,67 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// Denial is the interpretation of an empty NOERROR reply that proves its answer doesn't exist with an NSEC record
+type Denial struct {
+	Question string
+	Kind     string // NXDOMAIN or NODATA
+	Compact  bool   // NXDOMAIN signaled with an NXNAME NSEC record (RFC 9824)
+}
+
+// denial interprets a reply as a compact NXDOMAIN or a NODATA response, returning false if it's neither
+func denial(reply *dns.Msg) (Denial, bool) {
+	if reply.Rcode != dns.RcodeSuccess || len(reply.Answer) > 0 || len(reply.Question) == 0 {
+		return Denial{}, false
+	}
+	q := reply.Question[0]
+
+	for _, rr := range reply.Ns {
+		nsec, ok := rr.(*dns.NSEC)
+		if !ok || !strings.EqualFold(nsec.Hdr.Name, q.Name) {
+			continue
+		}
+		d := Denial{Question: fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype]), Kind: "NODATA"}
+		// Compact answers return NOERROR for names that don't exist, marking them with the NXNAME pseudo-type
+		if slices.Contains(nsec.TypeBitMap, dns.TypeNXNAME) {
+			d.Kind = "NXDOMAIN"
+			d.Compact = true
+		}
+		return d, true
+	}
+	return Denial{}, false
+}
+
+// printDenial shows whether an empty NOERROR reply is a compact NXDOMAIN or a real NODATA response
+func (p Printer) printDenial(reply *dns.Msg) {
+	if p.Opts.ValueOnly {
+		return
+	}
+	d, ok := denial(reply)
+	switch {
+	case !ok:
+		return
+	case d.Compact:
+		util.MustWritef(p.Out, "Denial: %s\n", util.Color(util.ColorRed, "NXDOMAIN (compact denial, NSEC with NXNAME)"))
+	default:
+		util.MustWritef(p.Out, "Denial: %s\n", util.Color(util.ColorYellow, "NODATA (name exists without the type)"))
+	}
+}
+
+// LoadDenials populates an entry's interpreted denial of existence responses from its replies
+func (e *Entry) LoadDenials() {
+	e.Denials = nil
+	for _, reply := range e.Replies {
+		if d, ok := denial(reply); ok {
+			e.Denials = append(e.Denials, d)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// comparedRecord is a record and which servers returned it
+type comparedRecord struct {
+	Record  string
+	Servers []bool
+}
+
+// comparison is the answers of each server to a single question
+type comparison struct {
+	Question string
+	Rcodes   []string // Rcode from each server, "error" if it couldn't be queried
+	Records  []comparedRecord
+}
+
+// compareRecord formats an answer record for comparison, ignoring its TTL and omitting its owner name if it's the question name
+func compareRecord(rr dns.RR, qName string) string {
+	record := fmt.Sprintf("%s %s", dns.TypeToString[rr.Header().Rrtype], rrValue(rr))
+	if !strings.EqualFold(rr.Header().Name, qName) {
+		record = strings.ToLower(rr.Header().Name) + " " + record
+	}
+	return record
+}
+
+// compareServers lists the distinct servers of entries in the order they appear
+func compareServers(entries []*Entry) []string {
+	var servers []string
+	for _, e := range entries {
+		if !slices.Contains(servers, e.Server) {
+			servers = append(servers, e.Server)
+		}
+	}
+	return servers
+}
+
+// compareAnswers aligns the answers of each server by question, in the order the questions were asked
+func compareAnswers(entries []*Entry, servers []string) []comparison {
+	var comparisons []*comparison
+	index := make(map[string]*comparison)
+	get := func(question string) *comparison {
+		if c, ok := index[question]; ok {
+			return c
+		}
+		c := &comparison{Question: question, Rcodes: make([]string, len(servers))}
+		index[question] = c
+		comparisons = append(comparisons, c)
+		return c
+	}
+
+	for _, e := range entries {
+		col := slices.Index(servers, e.Server)
+		if e.Error != "" {
+			for _, q := range e.Queries {
+				if len(q.Question) > 0 {
+					get(fmt.Sprintf("%s %s", q.Question[0].Name, dns.TypeToString[q.Question[0].Qtype])).Rcodes[col] = "error"
+				}
+			}
+			continue
+		}
+
+		for _, reply := range e.Replies {
+			if len(reply.Question) == 0 {
+				continue
+			}
+			q := reply.Question[0]
+			c := get(fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype]))
+			c.Rcodes[col] = dns.RcodeToString[reply.Rcode]
+			for _, rr := range reply.Answer {
+				record := compareRecord(rr, q.Name)
+				i := slices.IndexFunc(c.Records, func(r comparedRecord) bool { return r.Record == record })
+				if i == -1 {
+					i = len(c.Records)
+					c.Records = append(c.Records, comparedRecord{Record: record, Servers: make([]bool, len(servers))})
+				}
+				c.Records[i].Servers[col] = true
+			}
+		}
+	}
+
+	out := make([]comparison, len(comparisons))
+	for i, c := range comparisons {
+		sort.SliceStable(c.Records, func(i, j int) bool {
+			return c.Records[i].Record < c.Records[j].Record
+		})
+		out[i] = *c
+	}
+	return out
+}
+
+// differs returns true if the servers that answered don't all agree, ignoring servers that couldn't be queried
+func (c comparison) differs(values []string) bool {
+	var first string
+	for i, v := range values {
+		if c.Rcodes[i] == "error" || c.Rcodes[i] == "" {
+			continue
+		}
+		if first == "" {
+			first = v
+		} else if v != first {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintCompare prints a side-by-side table of each server's answers per question, marking rows where they differ
+func (p Printer) PrintCompare(entries []*Entry) {
+	servers := compareServers(entries)
+	for n, c := range compareAnswers(entries, servers) {
+		table := [][]string{append([]string{"Record"}, servers...)}
+		var differs []bool
+
+		table = append(table, append([]string{"rcode"}, c.Rcodes...))
+		differs = append(differs, c.differs(c.Rcodes))
+		for _, r := range c.Records {
+			row := []string{r.Record}
+			for i, present := range r.Servers {
+				switch {
+				case c.Rcodes[i] == "error" || c.Rcodes[i] == "":
+					row = append(row, "?")
+				case present:
+					row = append(row, "✓")
+				default:
+					row = append(row, "✗")
+				}
+			}
+			table = append(table, row)
+			differs = append(differs, c.differs(row[1:]))
+		}
+
+		widths := make([]int, len(table[0]))
+		for _, row := range table {
+			for i, col := range row {
+				widths[i] = max(widths[i], utf8.RuneCountInString(col))
+			}
+		}
+
+		if n > 0 {
+			util.MustWriteln(p.Out, "")
+		}
+		util.MustWriteln(p.Out, util.Color(util.ColorPurple, c.Question))
+		for i, row := range table {
+			marker := "  "
+			if i > 0 && differs[i-1] {
+				marker = util.Color(util.ColorRed, "≠ ")
+			}
+
+			var line string
+			for j, col := range row {
+				padded := col + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(col))
+				switch {
+				case i == 0:
+					padded = util.Color(util.ColorWhite, padded)
+				case col == "✗" || col == "error":
+					padded = util.Color(util.ColorRed, padded)
+				case col == "✓":
+					padded = util.Color(util.ColorGreen, padded)
+				}
+				line += padded + " "
+			}
+			util.MustWriteln(p.Out, marker+strings.TrimRight(line, " "))
+		}
+	}
+
+	for _, e := range entries {
+		p.printError(e)
+	}
+}
@@ -0,0 +1,33 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputPrintCompare(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	query := new(dns.Msg)
+	query.SetQuestion("example.com.", dns.TypeA)
+
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+	p.PrintCompare([]*Entry{
+		answerEntry("192.0.2.10", "example.com. 300 IN A 192.0.2.1", "example.com. 300 IN A 192.0.2.2"),
+		answerEntry("192.0.2.11", "EXAMPLE.com. 60 IN A 192.0.2.1"),
+		{Server: "192.0.2.12", Queries: []dns.Msg{*query}, Error: "exchange: timeout"},
+	})
+	assert.Equal(t, `example.com. A
+  Record      192.0.2.10 192.0.2.11 192.0.2.12
+  rcode       NOERROR    NOERROR    error
+  A 192.0.2.1 ✓          ✓          ?
+≠ A 192.0.2.2 ✓          ✗          ?
+Error from 192.0.2.12: exchange: timeout
+`, buf.String())
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// cookieParts splits a hex cookie into its 8 byte client cookie and the server cookie after it, if any
+func cookieParts(cookie string) (string, string) {
+	if len(cookie) <= 16 {
+		return cookie, ""
+	}
+	return cookie[:16], cookie[16:]
+}
+
+// cookieEcho describes whether a reply echoed the client cookie of a query and returned a valid 8 to 32 byte
+// server cookie (RFC 7873 section 4), and whether that's what the client expects
+func cookieEcho(sent, received string) (string, bool) {
+	client, _ := cookieParts(sent)
+	receivedClient, server := cookieParts(received)
+	switch {
+	case received == "":
+		return "no cookie in reply", false
+	case receivedClient != client:
+		return "client cookie mismatch", false
+	case server == "":
+		return "client cookie echoed without a server cookie", false
+	case len(server)/2 < 8 || len(server)/2 > 32:
+		return fmt.Sprintf("client cookie echoed with an invalid %d byte server cookie", len(server)/2), false
+	}
+	return "client cookie echoed", true
+}
+
+// cookieLines formats the cookie sent in a query and the one returned in its reply as hex, with the client and server
+// cookies separated, followed by whether the reply echoed the client cookie. It returns false if no cookie was sent.
+func cookieLines(query, reply *dns.Msg) ([]string, bool) {
+	sent, ok := util.EDNSOption[*dns.EDNS0_COOKIE](query)
+	if !ok || len(sent.Cookie) < 16 {
+		return nil, false
+	}
+
+	format := func(cookie string) string {
+		client, server := cookieParts(cookie)
+		s := "client " + util.Color(util.ColorPurple, client)
+		if server != "" {
+			s += fmt.Sprintf(" | server %s (%d bytes)", util.Color(util.ColorTeal, server), len(server)/2)
+		}
+		return s
+	}
+
+	lines := []string{"Cookie sent:     " + format(sent.Cookie)}
+	var received string
+	if r, ok := util.EDNSOption[*dns.EDNS0_COOKIE](reply); ok {
+		received = r.Cookie
+		lines = append(lines, "Cookie received: "+format(received))
+	}
+	status, ok := cookieEcho(sent.Cookie, received)
+	color := util.ColorGreen
+	if !ok {
+		color = util.ColorYellow
+	}
+	lines = append(lines, "Cookie status:   "+util.Color(color, status))
+	return lines, true
+}
+
+// printCookie shows the cookie exchanged in a query and its reply if --cookie is set
+func (p Printer) printCookie(query, reply *dns.Msg) {
+	if p.Opts.Cookie == "" || p.Opts.ValueOnly {
+		return
+	}
+	lines, ok := cookieLines(query, reply)
+	if !ok {
+		return
+	}
+	for _, line := range lines {
+		util.MustWriteln(p.Out, line)
+	}
+}
+
+// CookieHandshake is a query that the server answered with BADCOOKIE and a fresh server cookie, and that was retried
+// with it (RFC 7873 section 5.3)
+type CookieHandshake struct {
+	Question     string `json:"question" yaml:"question"`
+	ServerCookie string `json:"server_cookie" yaml:"server_cookie"` // Hex server cookie the query was retried with
+}
+
+// printCookieHandshakes prints the queries of an entry that were retried after a BADCOOKIE reply, unless only record
+// values are shown
+func (p Printer) printCookieHandshakes(e *Entry) {
+	if p.Opts.ValueOnly {
+		return
+	}
+	for _, h := range e.CookieHandshakes {
+		util.MustWritef(p.Out, "Cookie: BADCOOKIE for %s, retried with server cookie %s\n",
+			h.Question, util.Color(util.ColorTeal, h.ServerCookie))
+	}
+}
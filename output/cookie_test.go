@@ -0,0 +1,64 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func cookieMsg(cookie string) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	msg.SetEdns0(1232, false)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie})
+	return msg
+}
+
+func TestOutputPrettyCookie(t *testing.T) {
+	util.UseColor = false
+	query := cookieMsg("0102030405060708")
+	for _, tc := range []struct {
+		reply    *dns.Msg
+		expected string
+	}{
+		{
+			reply: cookieMsg("0102030405060708aabbccddeeff0011"),
+			expected: "Cookie sent:     client 0102030405060708\n" +
+				"Cookie received: client 0102030405060708 | server aabbccddeeff0011 (8 bytes)\n" +
+				"Cookie status:   client cookie echoed\n",
+		},
+		{
+			reply: cookieMsg("1112131415161718aabbccddeeff0011"),
+			expected: "Cookie sent:     client 0102030405060708\n" +
+				"Cookie received: client 1112131415161718 | server aabbccddeeff0011 (8 bytes)\n" +
+				"Cookie status:   client cookie mismatch\n",
+		},
+		{
+			reply: cookieMsg("0102030405060708aabb"),
+			expected: "Cookie sent:     client 0102030405060708\n" +
+				"Cookie received: client 0102030405060708 | server aabb (2 bytes)\n" +
+				"Cookie status:   client cookie echoed with an invalid 2 byte server cookie\n",
+		},
+		{
+			reply: new(dns.Msg),
+			expected: "Cookie sent:     client 0102030405060708\n" +
+				"Cookie status:   no cookie in reply\n",
+		},
+	} {
+		var buf bytes.Buffer
+		p := Printer{Out: &buf, Opts: &cli.Flags{Cookie: "auto"}}
+		p.PrintPretty([]*Entry{{Queries: []dns.Msg{*query}, Replies: []*dns.Msg{tc.reply}}})
+		assert.Equal(t, tc.expected, buf.String())
+
+		// Column output shows the same cookie exchange below the records
+		buf.Reset()
+		p.PrintColumn([]*Entry{{Queries: []dns.Msg{*query}, Replies: []*dns.Msg{tc.reply}}})
+		assert.Equal(t, tc.expected, buf.String())
+	}
+}
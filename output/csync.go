@@ -0,0 +1,63 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// CSYNC flags (RFC 7477 section 2.1.1.2)
+const (
+	csyncImmediate  = 1 << 0
+	csyncSOAMinimum = 1 << 1
+)
+
+// SyncRequest is a decoded CSYNC record (RFC 7477)
+type SyncRequest struct {
+	Name       string
+	Serial     uint32
+	Immediate  bool     // The parent may process the request without waiting for the SOA serial
+	SOAMinimum bool     // The child's SOA serial must be at least Serial
+	Types      []string // Record types the parent should synchronize
+}
+
+// decodeCSYNC decodes a CSYNC record's flags and type bitmap
+func decodeCSYNC(csync *dns.CSYNC) SyncRequest {
+	s := SyncRequest{
+		Name:       csync.Hdr.Name,
+		Serial:     csync.Serial,
+		Immediate:  csync.Flags&csyncImmediate != 0,
+		SOAMinimum: csync.Flags&csyncSOAMinimum != 0,
+		Types:      []string{},
+	}
+	for _, t := range csync.TypeBitMap {
+		s.Types = append(s.Types, dns.Type(t).String())
+	}
+	return s
+}
+
+// prettyCSYNC renders a CSYNC record with its serial, named flags, and the record types to synchronize
+func prettyCSYNC(csync *dns.CSYNC) string {
+	s := decodeCSYNC(csync)
+
+	var flags []string
+	if s.Immediate {
+		flags = append(flags, "immediate")
+	}
+	if s.SOAMinimum {
+		flags = append(flags, "soaminimum")
+	}
+	if unknown := csync.Flags &^ (csyncImmediate | csyncSOAMinimum); unknown != 0 {
+		flags = append(flags, fmt.Sprintf("0x%04x", unknown))
+	}
+	if len(flags) == 0 {
+		flags = append(flags, "none")
+	}
+
+	val := fmt.Sprintf("serial %d flags %s", s.Serial, strings.Join(flags, ","))
+	if len(s.Types) > 0 {
+		val += " types " + strings.Join(s.Types, " ")
+	}
+	return val
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// AnswerSet returns a normalized, sorted list of answer records across replies, ignoring TTLs and owner name case.
+// Replies without answers are represented by their rcode so that negative answers can be compared too.
+func AnswerSet(replies []*dns.Msg) []string {
+	var set []string
+	for _, reply := range replies {
+		if len(reply.Answer) == 0 {
+			var qType string
+			if len(reply.Question) > 0 {
+				qType = dns.TypeToString[reply.Question[0].Qtype] + " "
+			}
+			set = append(set, fmt.Sprintf("%s%s (no answers)", qType, dns.RcodeToString[reply.Rcode]))
+			continue
+		}
+		for _, rr := range reply.Answer {
+			set = append(set, fmt.Sprintf("%s %s %s",
+				strings.ToLower(rr.Header().Name),
+				dns.TypeToString[rr.Header().Rrtype],
+				rrValue(rr),
+			))
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// AnswerGroup is a distinct answer set and the servers that returned it
+type AnswerGroup struct {
+	Answers []string `json:"answers" yaml:"answers"`
+	Servers []string `json:"servers" yaml:"servers"`
+}
+
+// groupByAnswers groups entries by identical answer sets, largest group first
+func groupByAnswers(entries []*Entry) []AnswerGroup {
+	var groups []AnswerGroup
+	index := make(map[string]int)
+	for _, e := range entries {
+		answers := AnswerSet(e.Replies)
+		if e.Error != "" {
+			answers = []string{"error: " + e.Error}
+		}
+		key := strings.Join(answers, "\n")
+		if i, ok := index[key]; ok {
+			groups[i].Servers = append(groups[i].Servers, e.Server)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, AnswerGroup{Answers: answers, Servers: []string{e.Server}})
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Servers) > len(groups[j].Servers)
+	})
+	return groups
+}
+
+// PrintDedup prints each distinct answer set once with the list of servers that returned it
+func (p Printer) PrintDedup(entries []*Entry) {
+	groups := groupByAnswers(entries)
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(groups)
+		return
+	}
+
+	for i, g := range groups {
+		noun := "servers"
+		if len(g.Servers) == 1 {
+			noun = "server"
+		}
+		util.MustWritef(p.Out, "%s %s\n",
+			util.Color(util.ColorWhite, fmt.Sprintf("%d %s:", len(g.Servers), noun)),
+			util.Color(util.ColorTeal, strings.Join(g.Servers, ", ")),
+		)
+		for _, answer := range g.Answers {
+			util.MustWriteln(p.Out, "  "+answer)
+		}
+		if i != len(groups)-1 {
+			util.MustWriteln(p.Out, "")
+		}
+	}
+}
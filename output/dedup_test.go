@@ -0,0 +1,47 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+// answerEntry creates an entry for a server with a single reply containing the given records
+func answerEntry(server string, records ...string) *Entry {
+	reply := new(dns.Msg)
+	reply.SetQuestion("example.com.", dns.TypeA)
+	for _, r := range records {
+		rr, err := dns.NewRR(r)
+		if err != nil {
+			panic(err)
+		}
+		reply.Answer = append(reply.Answer, rr)
+	}
+	return &Entry{Server: server, Replies: []*dns.Msg{reply}}
+}
+
+func TestOutputPrintDedup(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+	p.PrintDedup([]*Entry{
+		answerEntry("192.0.2.10", "example.com. 300 IN A 192.0.2.1"),
+		answerEntry("192.0.2.11", "example.com. 60 IN A 192.0.2.2"),
+		answerEntry("192.0.2.12", "EXAMPLE.com. 10 IN A 192.0.2.1"),
+		answerEntry("192.0.2.13"),
+	})
+	assert.Equal(t, `2 servers: 192.0.2.10, 192.0.2.12
+  example.com. A 192.0.2.1
+
+1 server: 192.0.2.11
+  example.com. A 192.0.2.2
+
+1 server: 192.0.2.13
+  A NOERROR (no answers)
+`, buf.String())
+}
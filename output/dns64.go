@@ -0,0 +1,71 @@
+package output
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// dns64Embedded extracts the IPv4 address embedded in an IPv6 address by a DNS64 prefix (RFC 6052 section 2.2).
+// Bits 64-71 (the "u" octet) are skipped for prefixes shorter than /96.
+func dns64Embedded(ip net.IP, prefix netip.Prefix) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok || !addr.Is6() || addr.Is4In6() || !prefix.Contains(addr) {
+		return netip.Addr{}, false
+	}
+
+	a := addr.As16()
+	var v4 [4]byte
+	switch prefix.Bits() {
+	case 32:
+		copy(v4[:], a[4:8])
+	case 40:
+		copy(v4[:], append(a[5:8:8], a[9]))
+	case 48:
+		copy(v4[:], append(a[6:8:8], a[9:11]...))
+	case 56:
+		copy(v4[:], append(a[7:8:8], a[9:12]...))
+	case 64:
+		copy(v4[:], a[9:13])
+	case 96:
+		copy(v4[:], a[12:16])
+	default:
+		return netip.Addr{}, false
+	}
+	return netip.AddrFrom4(v4), true
+}
+
+// ParseDNS64Prefixes parses a list of DNS64 prefixes, which must be IPv6 prefixes of a length allowed by RFC 6052
+func ParseDNS64Prefixes(prefixes []string) ([]netip.Prefix, error) {
+	var parsed []netip.Prefix
+	for _, p := range prefixes {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("parsing DNS64 prefix %s: %s", p, err)
+		}
+		switch prefix.Bits() {
+		case 32, 40, 48, 56, 64, 96:
+		default:
+			return nil, fmt.Errorf("invalid DNS64 prefix length /%d, expected /32, /40, /48, /56, /64, or /96", prefix.Bits())
+		}
+		if !prefix.Addr().Is6() {
+			return nil, fmt.Errorf("DNS64 prefix %s is not an IPv6 prefix", p)
+		}
+		parsed = append(parsed, prefix.Masked())
+	}
+	return parsed, nil
+}
+
+// dns64Annotation returns the IPv4 address embedded in a synthesized AAAA address if it matches any of the DNS64 prefixes
+func dns64Annotation(ip net.IP, prefixes []string) (string, bool) {
+	parsed, err := ParseDNS64Prefixes(prefixes)
+	if err != nil {
+		return "", false
+	}
+	for _, prefix := range parsed {
+		if v4, ok := dns64Embedded(ip, prefix); ok {
+			return v4.String(), true
+		}
+	}
+	return "", false
+}
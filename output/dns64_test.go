@@ -0,0 +1,49 @@
+package output
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputDNS64Embedded(t *testing.T) {
+	for _, tc := range []struct {
+		prefix, addr string
+	}{ // RFC 6052 section 2.4 examples for 192.0.2.33
+		{"2001:db8::/32", "2001:db8:c000:221::"},
+		{"2001:db8:100::/40", "2001:db8:1c0:2:21::"},
+		{"2001:db8:122::/48", "2001:db8:122:c000:2:2100::"},
+		{"2001:db8:122:300::/56", "2001:db8:122:3c0:0:221::"},
+		{"2001:db8:122:344::/64", "2001:db8:122:344:c0:2:2100:0"},
+		{"2001:db8:122:344::/96", "2001:db8:122:344::192.0.2.33"},
+		{"64:ff9b::/96", "64:ff9b::192.0.2.33"},
+	} {
+		v4, ok := dns64Embedded(net.ParseIP(tc.addr), netip.MustParsePrefix(tc.prefix))
+		assert.True(t, ok, tc.prefix)
+		assert.Equal(t, "192.0.2.33", v4.String(), tc.prefix)
+	}
+
+	_, ok := dns64Embedded(net.ParseIP("2001:db8::1"), netip.MustParsePrefix("64:ff9b::/96"))
+	assert.False(t, ok)
+
+	_, err := ParseDNS64Prefixes([]string{"64:ff9b::/80"})
+	assert.NotNil(t, err)
+}
+
+func TestOutputPrettyDNS64(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	rr, err := dns.NewRR("example.com. 60 IN AAAA 64:ff9b::c000:201")
+	assert.Nil(t, err)
+
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "column", DNS64Prefixes: []string{"64:ff9b::/96"}}}
+	p.PrintColumn([]*Entry{{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}})
+	assert.Contains(t, buf.String(), "64:ff9b::c000:201 (DNS64 → 192.0.2.1)")
+}
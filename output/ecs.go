@@ -0,0 +1,30 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// ecsScope formats the EDNS0 client subnet scope returned in a reply, or notes that the server didn't return one
+func ecsScope(reply *dns.Msg) string {
+	subnet, ok := util.EDNSOption[*dns.EDNS0_SUBNET](reply)
+	if !ok {
+		return util.Color(util.ColorYellow, "not returned")
+	}
+	return fmt.Sprintf("%s for %s/%d",
+		util.Color(util.ColorPurple, fmt.Sprintf("/%d", subnet.SourceScope)),
+		subnet.Address, subnet.SourceNetmask,
+	)
+}
+
+// printECSScope prints the client subnet scope of a reply if --require-ecs-scope is set, unless only record values are
+// shown
+func (p Printer) printECSScope(reply *dns.Msg) {
+	if !p.Opts.RequireECSScope || p.Opts.ValueOnly {
+		return
+	}
+	util.MustWritef(p.Out, "ECS scope: %s\n", ecsScope(reply))
+}
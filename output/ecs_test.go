@@ -0,0 +1,44 @@
+package output
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputECSScope(t *testing.T) {
+	util.UseColor = false
+	withScope := new(dns.Msg)
+	withScope.SetQuestion("example.com.", dns.TypeA)
+	withScope.SetEdns0(1232, false)
+	withScope.IsEdns0().Option = append(withScope.IsEdns0().Option, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: 24,
+		SourceScope:   20,
+		Address:       net.ParseIP("192.0.2.0").To4(),
+	})
+	withoutScope := new(dns.Msg)
+	withoutScope.SetQuestion("example.com.", dns.TypeA)
+	entries := []*Entry{{Replies: []*dns.Msg{withScope, withoutScope}}}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{RequireECSScope: true}}
+	p.PrintPretty(entries)
+	assert.Equal(t, "ECS scope: /20 for 192.0.2.0/24\nECS scope: not returned\n", buf.String())
+
+	buf.Reset()
+	p.PrintColumn(entries)
+	assert.Equal(t, "ECS scope: /20 for 192.0.2.0/24\nECS scope: not returned\n", buf.String())
+
+	buf.Reset()
+	p.Opts.RequireECSScope = false
+	p.PrintPretty(entries)
+	assert.Empty(t, buf.String())
+}
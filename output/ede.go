@@ -0,0 +1,75 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// ExtendedError is an Extended DNS Error option of a reply (RFC 8914)
+type ExtendedError struct {
+	Question string `json:"question" yaml:"question"`
+	Code     uint16 `json:"code" yaml:"code"`
+	Name     string `json:"name" yaml:"name"`
+	Text     string `json:"text,omitempty" yaml:"text,omitempty"` // EXTRA-TEXT, if the server sent any
+}
+
+// String formats an extended error as its code, name, and extra text, e.g. "18 Prohibited (blocked by policy)"
+func (e ExtendedError) String() string {
+	s := util.Color(util.ColorYellow, strconv.Itoa(int(e.Code))+" "+e.Name)
+	if e.Text != "" {
+		s += fmt.Sprintf(" (%s)", e.Text)
+	}
+	return s
+}
+
+// extendedErrors returns every EDE option in the OPT record of a reply
+func extendedErrors(reply *dns.Msg) []ExtendedError {
+	opt := reply.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	question := ""
+	if len(reply.Question) > 0 {
+		question = fmt.Sprintf("%s %s", reply.Question[0].Name, dns.TypeToString[reply.Question[0].Qtype])
+	}
+
+	var errs []ExtendedError
+	for _, o := range opt.Option {
+		if ede, ok := o.(*dns.EDNS0_EDE); ok {
+			name, ok := dns.ExtendedErrorCodeToString[ede.InfoCode]
+			if !ok {
+				name = "Unknown"
+			}
+			errs = append(errs, ExtendedError{
+				Question: question,
+				Code:     ede.InfoCode,
+				Name:     name,
+				Text:     ede.ExtraText,
+			})
+		}
+	}
+	return errs
+}
+
+// printExtendedErrors prints a line for each EDE option of a reply, unless only record values are shown
+func (p Printer) printExtendedErrors(reply *dns.Msg) {
+	if p.Opts.ValueOnly {
+		return
+	}
+	for _, e := range extendedErrors(reply) {
+		util.MustWritef(p.Out, "EDE: %s\n", e)
+	}
+}
+
+// LoadExtendedErrors populates an entry's extended errors from the OPT record of each reply
+func (e *Entry) LoadExtendedErrors() {
+	e.ExtendedErrors = nil
+	for _, reply := range e.Replies {
+		e.ExtendedErrors = append(e.ExtendedErrors, extendedErrors(reply)...)
+	}
+}
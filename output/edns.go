@@ -0,0 +1,104 @@
+package output
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// ednsOptionNames maps EDNS0 option codes to their IANA names
+var ednsOptionNames = map[uint16]string{
+	dns.EDNS0LLQ:          "LLQ",
+	dns.EDNS0UL:           "UL",
+	dns.EDNS0NSID:         "NSID",
+	dns.EDNS0ESU:          "ESU",
+	dns.EDNS0DAU:          "DAU",
+	dns.EDNS0DHU:          "DHU",
+	dns.EDNS0N3U:          "N3U",
+	dns.EDNS0SUBNET:       "ECS",
+	dns.EDNS0EXPIRE:       "EXPIRE",
+	dns.EDNS0COOKIE:       "COOKIE",
+	dns.EDNS0TCPKEEPALIVE: "TCP-KEEPALIVE",
+	dns.EDNS0PADDING:      "PADDING",
+	dns.EDNS0EDE:          "EDE",
+}
+
+// EDNSExchange stores the OPT records of a query and its reply
+type EDNSExchange struct {
+	Query    *OPTInfo `json:",omitempty" yaml:",omitempty"`
+	Response *OPTInfo `json:",omitempty" yaml:",omitempty"`
+}
+
+// OPTInfo stores the decoded fields of an OPT record
+type OPTInfo struct {
+	Version uint8
+	UDPSize uint16
+	DO      bool
+	CO      bool `json:",omitempty" yaml:",omitempty"` // Compact answers OK (RFC 9824)
+	Options []EDNSOption
+}
+
+// EDNSOption stores a single decoded EDNS0 option
+type EDNSOption struct {
+	Code  uint16
+	Name  string
+	Value string
+}
+
+// optInfo decodes the OPT record of a message, returning nil if it doesn't have one
+func optInfo(m *dns.Msg) *OPTInfo {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	info := &OPTInfo{
+		Version: opt.Version(),
+		UDPSize: opt.UDPSize(),
+		DO:      opt.Do(),
+		CO:      opt.Co(),
+		Options: []EDNSOption{},
+	}
+	for _, o := range opt.Option {
+		name, ok := ednsOptionNames[o.Option()]
+		if !ok {
+			name = fmt.Sprintf("OPT%d", o.Option())
+		}
+		info.Options = append(info.Options, EDNSOption{
+			Code:  o.Option(),
+			Name:  name,
+			Value: ednsOptionValue(o),
+		})
+	}
+	return info
+}
+
+// ednsOptionValue decodes an EDNS0 option value, falling back to hex for unknown options
+func ednsOptionValue(o dns.EDNS0) string {
+	switch o := o.(type) {
+	case *dns.EDNS0_NSID:
+		if b, err := hex.DecodeString(o.Nsid); err == nil {
+			return string(b)
+		}
+		return o.Nsid
+	case *dns.EDNS0_PADDING:
+		return fmt.Sprintf("%d bytes", len(o.Padding))
+	case *dns.EDNS0_LOCAL:
+		return hex.EncodeToString(o.Data)
+	}
+	return o.String()
+}
+
+// LoadEDNS populates an entry's EDNS exchanges from the OPT records of each query and reply
+func (e *Entry) LoadEDNS() {
+	e.EDNS = nil
+	for i, reply := range e.Replies {
+		var exchange EDNSExchange
+		if i < len(e.Queries) {
+			exchange.Query = optInfo(&e.Queries[i])
+		}
+		exchange.Response = optInfo(reply)
+		e.EDNS = append(e.EDNS, exchange)
+	}
+}
@@ -0,0 +1,123 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputEDNS(t *testing.T) {
+	query := new(dns.Msg)
+	query.SetQuestion("example.com.", dns.TypeA)
+	query.SetEdns0(1232, true)
+	query.IsEdns0().Option = append(query.IsEdns0().Option,
+		&dns.EDNS0_NSID{Code: dns.EDNS0NSID},
+		&dns.EDNS0_PADDING{Padding: make([]byte, 16)},
+	)
+
+	reply := new(dns.Msg)
+	reply.SetReply(query)
+	reply.SetEdns0(512, false)
+	reply.IsEdns0().Option = append(reply.IsEdns0().Option,
+		&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: "6e7331"},
+		&dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0xde, 0xad}},
+	)
+
+	e := &Entry{Queries: []dns.Msg{*query}, Replies: []*dns.Msg{reply}}
+	e.LoadEDNS()
+	assert.Len(t, e.EDNS, 1)
+	assert.Equal(t, &OPTInfo{
+		UDPSize: 1232,
+		DO:      true,
+		Options: []EDNSOption{
+			{Code: dns.EDNS0NSID, Name: "NSID", Value: ""},
+			{Code: dns.EDNS0PADDING, Name: "PADDING", Value: "16 bytes"},
+		},
+	}, e.EDNS[0].Query)
+	assert.Equal(t, []EDNSOption{
+		{Code: dns.EDNS0NSID, Name: "NSID", Value: "ns1"},
+		{Code: 65001, Name: "OPT65001", Value: "dead"},
+	}, e.EDNS[0].Response.Options)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{e})
+	assert.Contains(t, buf.String(), `"edns":[{"query":{"version":0,"udpsize":1232,"do":true,"options":[{"code":3,"name":"NSID","value":""}`)
+}
+
+func TestOutputExtendedErrors(t *testing.T) {
+	util.UseColor = false
+	query := new(dns.Msg)
+	query.SetQuestion("blocked.example.", dns.TypeA)
+
+	reply := new(dns.Msg)
+	reply.SetReply(query)
+	reply.Rcode = dns.RcodeServerFailure
+	reply.SetEdns0(1232, false)
+	reply.IsEdns0().Option = append(reply.IsEdns0().Option,
+		&dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeProhibited, ExtraText: "blocked by policy"},
+		&dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeDNSBogus},
+	)
+	entries := []*Entry{{Queries: []dns.Msg{*query}, Replies: []*dns.Msg{reply}}}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{ShowAnswer: true}}
+	p.PrintPretty(entries)
+	assert.Equal(t, "EDE: 18 Prohibited (blocked by policy)\nEDE: 6 DNSSEC Bogus\n", buf.String())
+
+	buf.Reset()
+	p.Opts.Format = "column"
+	p.PrintColumn(entries)
+	assert.Equal(t, "EDE: 18 Prohibited (blocked by policy)\nEDE: 6 DNSSEC Bogus\n", buf.String())
+
+	buf.Reset()
+	p.Opts.Format = "json"
+	p.Opts.JSONCompact = true
+	p.PrintStructured(entries)
+	assert.Contains(t, buf.String(), `"extended_errors":[{"question":"blocked.example. A","code":18,"name":"Prohibited","text":"blocked by policy"},{"question":"blocked.example. A","code":6,"name":"DNSSEC Bogus"}]`)
+
+	buf.Reset()
+	p.Opts.Format = "yaml"
+	p.PrintStructured(entries)
+	assert.Contains(t, buf.String(), "extended_errors:\n")
+}
+
+func TestOutputCompactDenial(t *testing.T) {
+	util.UseColor = false
+	query := new(dns.Msg)
+	query.SetQuestion("missing.example.", dns.TypeA)
+
+	nsec := func(types ...uint16) *dns.Msg {
+		reply := new(dns.Msg)
+		reply.SetReply(query)
+		reply.Ns = []dns.RR{&dns.NSEC{
+			Hdr:        dns.RR_Header{Name: "missing.example.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: "\\000.missing.example.",
+			TypeBitMap: types,
+		}}
+		return reply
+	}
+	compact := nsec(dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNXNAME)
+	nodata := nsec(dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC)
+	entries := []*Entry{{Queries: []dns.Msg{*query, *query}, Replies: []*dns.Msg{compact, nodata}}}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{ShowAnswer: true}}
+	p.PrintPretty(entries)
+	assert.Equal(t, "Denial: NXDOMAIN (compact denial, NSEC with NXNAME)\nDenial: NODATA (name exists without the type)\n", buf.String())
+
+	buf.Reset()
+	p.Opts.Format = "json"
+	p.Opts.JSONCompact = true
+	p.PrintStructured(entries)
+	assert.Contains(t, buf.String(), `"denials":[{"question":"missing.example. A","kind":"NXDOMAIN","compact":true},{"question":"missing.example. A","kind":"NODATA","compact":false}]`)
+
+	// An answered reply isn't a denial
+	_, ok := denial(&dns.Msg{Question: query.Question, Answer: []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "missing.example.", Rrtype: dns.TypeA}}}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,56 @@
+package output
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// ExpireTimer is the EDNS0 expire timer of a reply to a query sent with --expire (RFC 7314)
+type ExpireTimer struct {
+	Question string  `json:"question" yaml:"question"`
+	Expire   *uint32 `json:"expire" yaml:"expire"` // Seconds, nil if the server didn't return an EXPIRE option
+}
+
+// String formats an expire timer in seconds and as a duration, or notes that the server didn't return one
+func (t ExpireTimer) String() string {
+	if t.Expire == nil {
+		return util.Color(util.ColorYellow, "not returned")
+	}
+	return fmt.Sprintf("%s (%s)",
+		util.Color(util.ColorPurple, fmt.Sprintf("%d seconds", *t.Expire)),
+		time.Duration(*t.Expire)*time.Second,
+	)
+}
+
+// expireTimer returns the EXPIRE option of a reply. An empty option counts as not returned, since servers that aren't
+// authoritative for the zone echo it back without a timer.
+func expireTimer(reply *dns.Msg) ExpireTimer {
+	var timer ExpireTimer
+	if len(reply.Question) > 0 {
+		timer.Question = fmt.Sprintf("%s %s", reply.Question[0].Name, dns.TypeToString[reply.Question[0].Qtype])
+	}
+	if expire, ok := util.EDNSOption[*dns.EDNS0_EXPIRE](reply); ok && !expire.Empty {
+		timer.Expire = &expire.Expire
+	}
+	return timer
+}
+
+// printExpire prints the expire timer of a reply if --expire is set, unless only record values are shown
+func (p Printer) printExpire(reply *dns.Msg) {
+	if !p.Opts.Expire || p.Opts.ValueOnly {
+		return
+	}
+	util.MustWritef(p.Out, "Expire: %s\n", expireTimer(reply))
+}
+
+// LoadExpireTimers populates an entry's expire timers from the OPT record of each reply
+func (e *Entry) LoadExpireTimers() {
+	e.ExpireTimers = nil
+	for _, reply := range e.Replies {
+		e.ExpireTimers = append(e.ExpireTimers, expireTimer(reply))
+	}
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputExpire(t *testing.T) {
+	util.UseColor = false
+	withExpire := new(dns.Msg)
+	withExpire.SetQuestion("example.com.", dns.TypeSOA)
+	withExpire.SetEdns0(1232, false)
+	withExpire.IsEdns0().Option = append(withExpire.IsEdns0().Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Expire: 604800})
+	withoutExpire := new(dns.Msg)
+	withoutExpire.SetQuestion("example.com.", dns.TypeSOA)
+	entries := []*Entry{{Replies: []*dns.Msg{withExpire, withoutExpire}}}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Expire: true}}
+	p.PrintPretty(entries)
+	assert.Equal(t, "Expire: 604800 seconds (168h0m0s)\nExpire: not returned\n", buf.String())
+
+	buf.Reset()
+	p.PrintColumn(entries)
+	assert.Equal(t, "Expire: 604800 seconds (168h0m0s)\nExpire: not returned\n", buf.String())
+
+	buf.Reset()
+	p.Opts.Format = FormatJSON
+	p.Opts.JSONCompact = true
+	p.PrintStructured(entries)
+	assert.Contains(t, buf.String(), `"expire_timers":[{"question":"example.com. SOA","expire":604800},{"question":"example.com. SOA","expire":null}]`)
+
+	buf.Reset()
+	p.Opts.Expire = false
+	p.PrintStructured([]*Entry{{Replies: []*dns.Msg{withExpire}}})
+	assert.NotContains(t, buf.String(), "expire_timers")
+}
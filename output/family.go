@@ -0,0 +1,86 @@
+package output
+
+import (
+	"slices"
+	"time"
+
+	"github.com/natesales/q/util"
+)
+
+// FamilyResult stores the reply to a query sent over a single address family
+type FamilyResult struct {
+	Family  string        `json:"family" yaml:"family"`
+	Rcode   string        `json:"rcode,omitempty" yaml:"rcode,omitempty"`
+	Answers []string      `json:"answers,omitempty" yaml:"answers,omitempty"`
+	RTT     time.Duration `json:"rtt" yaml:"rtt"`
+	Error   string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// FamilyComparison compares the replies to the same query sent over IPv4 and IPv6
+type FamilyComparison struct {
+	Question     string         `json:"question" yaml:"question"`
+	Results      []FamilyResult `json:"results" yaml:"results"`
+	AnswersMatch bool           `json:"answers_match" yaml:"answers_match"`
+	Faster       string         `json:"faster,omitempty" yaml:"faster,omitempty"` // Empty unless every family replied
+}
+
+// CompareFamilies compares the results of a query across address families
+func CompareFamilies(question string, results []FamilyResult) FamilyComparison {
+	c := FamilyComparison{Question: question, Results: results}
+	if len(results) < 2 || slices.ContainsFunc(results, func(r FamilyResult) bool { return r.Error != "" }) {
+		return c
+	}
+
+	c.AnswersMatch = true
+	fastest := results[0]
+	for _, r := range results[1:] {
+		if r.Rcode != results[0].Rcode || !slices.Equal(r.Answers, results[0].Answers) {
+			c.AnswersMatch = false
+		}
+		if r.RTT < fastest.RTT {
+			fastest = r
+		}
+	}
+	c.Faster = fastest.Family
+	return c
+}
+
+// PrintFamilyComparisons prints the replies and latency of each query per address family
+func (p Printer) PrintFamilyComparisons(comparisons []FamilyComparison) {
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(comparisons)
+		return
+	}
+
+	for _, c := range comparisons {
+		util.MustWriteln(p.Out, util.Color(util.ColorWhite, c.Question))
+		for _, r := range c.Results {
+			if r.Error != "" {
+				util.MustWritef(p.Out, "  %-4s %s (%s)\n", r.Family, util.Color(util.ColorRed, "unavailable"), r.Error)
+				continue
+			}
+			util.MustWritef(p.Out, "  %-4s %s %d answers in %s\n",
+				r.Family, r.Rcode, len(r.Answers), util.Color(util.ColorTeal, r.RTT.Round(10*time.Microsecond)))
+			for _, answer := range r.Answers {
+				util.MustWritef(p.Out, "       %s\n", answer)
+			}
+		}
+
+		if c.Faster == "" {
+			util.MustWriteln(p.Out, util.Color(util.ColorYellow, "  not available over every family, skipping comparison"))
+			continue
+		}
+		var fastest, slowest time.Duration
+		for _, r := range c.Results {
+			slowest = max(slowest, r.RTT)
+			if r.Family == c.Faster {
+				fastest = r.RTT
+			}
+		}
+		match := util.Color(util.ColorGreen, "answers match")
+		if !c.AnswersMatch {
+			match = util.Color(util.ColorRed, "answers differ")
+		}
+		util.MustWritef(p.Out, "  %s faster by %s, %s\n", c.Faster, (slowest - fastest).Round(10*time.Microsecond), match)
+	}
+}
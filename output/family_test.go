@@ -0,0 +1,52 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputCompareFamilies(t *testing.T) {
+	c := CompareFamilies("example.com. A", []FamilyResult{
+		{Family: "IPv4", Rcode: "NOERROR", Answers: []string{"example.com. A 192.0.2.1"}, RTT: 30 * time.Millisecond},
+		{Family: "IPv6", Rcode: "NOERROR", Answers: []string{"example.com. A 192.0.2.1"}, RTT: 10 * time.Millisecond},
+	})
+	assert.True(t, c.AnswersMatch)
+	assert.Equal(t, "IPv6", c.Faster)
+
+	c = CompareFamilies("example.com. A", []FamilyResult{
+		{Family: "IPv4", Rcode: "NOERROR", Answers: []string{"example.com. A 192.0.2.1"}, RTT: 10 * time.Millisecond},
+		{Family: "IPv6", Rcode: "NOERROR", Answers: []string{"example.com. A 192.0.2.2"}, RTT: 30 * time.Millisecond},
+	})
+	assert.False(t, c.AnswersMatch)
+	assert.Equal(t, "IPv4", c.Faster)
+
+	c = CompareFamilies("example.com. A", []FamilyResult{
+		{Family: "IPv4", Rcode: "NOERROR", RTT: 10 * time.Millisecond},
+		{Family: "IPv6", Error: "network is unreachable"},
+	})
+	assert.False(t, c.AnswersMatch)
+	assert.Empty(t, c.Faster)
+}
+
+func TestOutputPrintFamilyComparisons(t *testing.T) {
+	util.UseColor = false
+	var out bytes.Buffer
+	p := Printer{Out: &out, Opts: &cli.Flags{}}
+	p.PrintFamilyComparisons([]FamilyComparison{CompareFamilies("example.com. A", []FamilyResult{
+		{Family: "IPv4", Rcode: "NOERROR", Answers: []string{"example.com. A 192.0.2.1"}, RTT: 30 * time.Millisecond},
+		{Family: "IPv6", Rcode: "NOERROR", Answers: []string{"example.com. A 192.0.2.2"}, RTT: 10 * time.Millisecond},
+	})})
+	assert.Equal(t, `example.com. A
+  IPv4 NOERROR 1 answers in 30ms
+       example.com. A 192.0.2.1
+  IPv6 NOERROR 1 answers in 10ms
+       example.com. A 192.0.2.2
+  IPv6 faster by 20ms, answers differ
+`, out.String())
+}
@@ -0,0 +1,95 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// geoRange is an address range announced by a single ASN
+type geoRange struct {
+	start, end netip.Addr
+	asn        uint32
+	country    string
+}
+
+// GeoDB is a local IP to ASN and country database
+type GeoDB struct {
+	ranges []geoRange // Sorted by start address
+}
+
+// LoadGeoDB reads an IP to ASN database in the tab separated format published by iptoasn.com
+// (range start, range end, AS number, country code, AS description)
+func LoadGeoDB(path string) (*GeoDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening geo database: %w", err)
+	}
+	defer f.Close()
+
+	var db GeoDB
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%s line %d: expected at least 4 tab separated fields", path, line)
+		}
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid AS number %s", path, line, fields[2])
+		}
+		if asn == 0 { // Not routed
+			continue
+		}
+		db.ranges = append(db.ranges, geoRange{start: start.Unmap(), end: end.Unmap(), asn: uint32(asn), country: fields[3]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading geo database: %w", err)
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].start.Less(db.ranges[j].start)
+	})
+	return &db, nil
+}
+
+// lookup returns the range containing an address
+func (db *GeoDB) lookup(addr netip.Addr) (geoRange, bool) {
+	addr = addr.Unmap()
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].start)
+	})
+	if i == 0 {
+		return geoRange{}, false
+	}
+	r := db.ranges[i-1]
+	if r.start.BitLen() != addr.BitLen() || r.end.Less(addr) {
+		return geoRange{}, false
+	}
+	return r, true
+}
+
+// annotation returns the ASN and country of an address, e.g. "AS64500, US"
+func (db *GeoDB) annotation(ip string) (string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", false
+	}
+	r, ok := db.lookup(addr)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("AS%d, %s", r.asn, r.country), true
+}
@@ -0,0 +1,63 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputGeoDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ip2asn.tsv")
+	assert.Nil(t, os.WriteFile(path, []byte(
+		"192.0.2.0\t192.0.2.255\t64500\tUS\tEXAMPLE-NET\n"+
+			"198.51.100.0\t198.51.100.255\t0\tNone\tNot routed\n"+
+			"2001:db8::\t2001:db8::ffff\t64501\tDE\tEXAMPLE-V6\n",
+	), 0644))
+
+	db, err := LoadGeoDB(path)
+	assert.Nil(t, err)
+
+	annotation, ok := db.annotation("192.0.2.1")
+	assert.True(t, ok)
+	assert.Equal(t, "AS64500, US", annotation)
+
+	annotation, ok = db.annotation("2001:db8::1")
+	assert.True(t, ok)
+	assert.Equal(t, "AS64501, DE", annotation)
+
+	_, ok = db.annotation("198.51.100.1")
+	assert.False(t, ok)
+	_, ok = db.annotation("203.0.113.1")
+	assert.False(t, ok)
+
+	_, err = LoadGeoDB(filepath.Join(t.TempDir(), "missing"))
+	assert.NotNil(t, err)
+
+	assert.Nil(t, os.WriteFile(path, []byte("192.0.2.0\n"), 0644))
+	_, err = LoadGeoDB(path)
+	assert.NotNil(t, err)
+}
+
+func TestOutputGeoAnnotation(t *testing.T) {
+	util.UseColor = false
+	path := filepath.Join(t.TempDir(), "ip2asn.tsv")
+	assert.Nil(t, os.WriteFile(path, []byte("192.0.2.0\t192.0.2.255\t64500\tUS\tEXAMPLE-NET\n"), 0644))
+	db, err := LoadGeoDB(path)
+	assert.Nil(t, err)
+
+	rr, err := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+	assert.Nil(t, err)
+
+	e := &Entry{GeoDB: db}
+	assert.Equal(t, "192.0.2.1 (AS64500, US)", e.parseRR(rr, &cli.Flags{}).Value)
+
+	// No annotation without a database
+	e = &Entry{}
+	assert.Equal(t, "192.0.2.1", e.parseRR(rr, &cli.Flags{}).Value)
+}
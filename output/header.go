@@ -0,0 +1,99 @@
+package output
+
+import (
+	"github.com/miekg/dns"
+)
+
+// Header is the header of a reply with each flag and its opcode and rcode by number and name
+type Header struct {
+	ID         uint16 `json:"id" yaml:"id"`
+	QR         bool   `json:"qr" yaml:"qr"`
+	AA         bool   `json:"aa" yaml:"aa"`
+	TC         bool   `json:"tc" yaml:"tc"`
+	RD         bool   `json:"rd" yaml:"rd"`
+	RA         bool   `json:"ra" yaml:"ra"`
+	Z          bool   `json:"z" yaml:"z"`
+	AD         bool   `json:"ad" yaml:"ad"`
+	CD         bool   `json:"cd" yaml:"cd"`
+	Opcode     int    `json:"opcode" yaml:"opcode"`
+	OpcodeName string `json:"opcode_name" yaml:"opcode_name"`
+	Rcode      int    `json:"rcode" yaml:"rcode"`
+	RcodeName  string `json:"rcode_name" yaml:"rcode_name"`
+}
+
+// Question is a question of a reply with its type and class by name
+type Question struct {
+	Name  string `json:"name" yaml:"name"`
+	Type  string `json:"type" yaml:"type"`
+	Class string `json:"class" yaml:"class"`
+}
+
+// Response is the header, sections, and OPT record of a reply (or of a query, in the queries of JSON output)
+type Response struct {
+	Header     Header     `json:"header" yaml:"header"`
+	Question   []Question `json:"question" yaml:"question"`
+	Answer     []Record   `json:"answer" yaml:"answer"`
+	Authority  []Record   `json:"authority" yaml:"authority"`
+	Additional []Record   `json:"additional" yaml:"additional"`
+	EDNS       *OPTInfo   `json:"edns,omitempty" yaml:"edns,omitempty"`
+}
+
+// response decodes the header, sections, and OPT record of a message
+func response(reply *dns.Msg) Response {
+	r := Response{
+		Header: Header{
+			ID:         reply.Id,
+			QR:         reply.Response,
+			AA:         reply.Authoritative,
+			TC:         reply.Truncated,
+			RD:         reply.RecursionDesired,
+			RA:         reply.RecursionAvailable,
+			Z:          reply.Zero,
+			AD:         reply.AuthenticatedData,
+			CD:         reply.CheckingDisabled,
+			Opcode:     reply.Opcode,
+			OpcodeName: dns.OpcodeToString[reply.Opcode],
+			Rcode:      reply.Rcode,
+			RcodeName:  dns.RcodeToString[reply.Rcode],
+		},
+		Question:   []Question{},
+		Answer:     records(reply.Answer),
+		Authority:  records(reply.Ns),
+		Additional: records(reply.Extra),
+		EDNS:       optInfo(reply),
+	}
+	for _, q := range reply.Question {
+		r.Question = append(r.Question, Question{Name: q.Name, Type: dns.TypeToString[q.Qtype], Class: className(q.Qclass)})
+	}
+	return r
+}
+
+// LoadResponses populates an entry's decoded queries and replies
+func (e *Entry) LoadResponses() {
+	e.QueryMessages = nil
+	for i := range e.Queries {
+		e.QueryMessages = append(e.QueryMessages, response(&e.Queries[i]))
+	}
+	e.Responses = nil
+	for _, reply := range e.Replies {
+		e.Responses = append(e.Responses, response(reply))
+	}
+}
+
+// withoutOPT returns a copy of an entry whose replies don't have OPT records in their additional sections, since
+// they're decoded in the responses instead
+func (e *Entry) withoutOPT() *Entry {
+	c := *e
+	c.Replies = make([]*dns.Msg, len(e.Replies))
+	for i, reply := range e.Replies {
+		r := *reply
+		r.Extra = nil
+		for _, rr := range reply.Extra {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				r.Extra = append(r.Extra, rr)
+			}
+		}
+		c.Replies[i] = &r
+	}
+	return &c
+}
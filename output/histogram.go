@@ -0,0 +1,76 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/natesales/q/util"
+)
+
+// histogramWidth is the width of the longest bar in the pretty histogram
+const histogramWidth = 40
+
+// HistogramBucket is the number of queries with a latency in a range
+type HistogramBucket struct {
+	Label string `json:"label" yaml:"label"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+// histogram counts the per-query latencies of all entries into buckets bounded by bounds, which must be sorted
+func histogram(entries []*Entry, bounds []time.Duration) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(bounds)+1)
+	for i := range buckets {
+		switch {
+		case len(bounds) == 0:
+			buckets[i].Label = "all"
+		case i == 0:
+			buckets[i].Label = "0-" + bounds[0].String()
+		case i == len(bounds):
+			buckets[i].Label = bounds[i-1].String() + "+"
+		default:
+			buckets[i].Label = bounds[i-1].String() + "-" + bounds[i].String()
+		}
+	}
+
+	for _, entry := range entries {
+		for _, d := range entry.Durations {
+			i := 0
+			for i < len(bounds) && d >= bounds[i] {
+				i++
+			}
+			buckets[i].Count++
+		}
+	}
+	return buckets
+}
+
+// PrintHistogram prints a latency histogram of every query across all entries
+func (p Printer) PrintHistogram(entries []*Entry) {
+	buckets := histogram(entries, p.Opts.HistogramBuckets)
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(buckets)
+		return
+	}
+
+	longestLabel, largest, total := 0, 0, 0
+	for _, b := range buckets {
+		longestLabel = max(longestLabel, len(b.Label))
+		largest = max(largest, b.Count)
+		total += b.Count
+	}
+
+	util.MustWriteln(p.Out, util.Color(util.ColorWhite, fmt.Sprintf("Latency (%d queries):", total)))
+	for _, b := range buckets {
+		width := 0
+		if largest > 0 {
+			width = b.Count * histogramWidth / largest
+		}
+		util.MustWritef(p.Out, "%-"+strconv.Itoa(longestLabel)+"s %s %d\n",
+			b.Label,
+			util.Color(util.ColorTeal, strings.Repeat("#", width)),
+			b.Count,
+		)
+	}
+}
@@ -0,0 +1,42 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputHistogram(t *testing.T) {
+	util.UseColor = false
+	bounds := []time.Duration{10 * time.Millisecond, 50 * time.Millisecond}
+	histEntries := []*Entry{
+		{Durations: []time.Duration{time.Millisecond, 5 * time.Millisecond, 20 * time.Millisecond}},
+		{Durations: []time.Duration{50 * time.Millisecond}},
+	}
+
+	assert.Equal(t, []HistogramBucket{
+		{Label: "0-10ms", Count: 2},
+		{Label: "10ms-50ms", Count: 1},
+		{Label: "50ms+", Count: 1},
+	}, histogram(histEntries, bounds))
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: FormatPretty, HistogramBuckets: bounds}}
+	p.PrintHistogram(histEntries)
+	assert.Equal(t, `Latency (4 queries):
+0-10ms    ######################################## 2
+10ms-50ms #################### 1
+50ms+     #################### 1
+`, buf.String())
+
+	buf.Reset()
+	p.Opts.Format = FormatJSON
+	p.Opts.JSONCompact = true
+	p.PrintHistogram(histEntries)
+	assert.Equal(t, `[{"label":"0-10ms","count":2},{"label":"10ms-50ms","count":1},{"label":"50ms+","count":1}]`+"\n", buf.String())
+}
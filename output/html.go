@@ -0,0 +1,206 @@
+package output
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+)
+
+// htmlTemplate is a self-contained HTML page with inline CSS for --format html
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.25em; margin-top: 2em; border-bottom: 1px solid #d0d7de; }
+h3 { font-size: 1.05em; font-family: ui-monospace, Menlo, Consolas, monospace; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #d0d7de; padding: 0.3em 0.7em; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+td.value { font-family: ui-monospace, Menlo, Consolas, monospace; word-break: break-all; }
+.ok { color: #1a7f37; }
+.warn { color: #9a6700; }
+.error { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>Generated</th><td>{{.Generated}}</td></tr>
+<tr><th>Servers</th><td>{{.Servers}}</td></tr>
+<tr><th>Responses</th><td>{{.Responses}}</td></tr>
+<tr><th>Answers</th><td>{{.Answers}}</td></tr>
+<tr><th>Errors</th><td{{if .Errors}} class="error"{{end}}>{{.Errors}}</td></tr>
+</table>
+{{- range .Entries}}
+<h2>{{.Server}}{{with .Transport}} over {{.}}{{end}}</h2>
+{{- with .Error}}
+<p class="error">Error: {{.}}</p>
+{{- end}}
+{{- range .Replies}}
+<h3>{{.Question}}</h3>
+<table>
+<tr><th>Status</th><td class="{{.StatusClass}}">{{.Rcode}}</td></tr>
+<tr><th>Flags</th><td>{{.Flags}}</td></tr>
+<tr><th>Time</th><td>{{.Time}}</td></tr>
+<tr><th>DNSSEC</th><td class="{{.DNSSECClass}}">{{.DNSSEC}}</td></tr>
+</table>
+{{- if .Records}}
+<table>
+<tr><th>Section</th><th>Name</th><th>TTL</th><th>Type</th><th>Value</th></tr>
+{{- range .Records}}
+<tr><td>{{.Section}}</td><td>{{.Name}}</td><td>{{.TTL}}</td><td>{{.Type}}</td><td class="value">{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlReport is the data rendered by htmlTemplate
+type htmlReport struct {
+	Title     string
+	Generated string
+	Servers   int
+	Responses int
+	Answers   int
+	Errors    int
+	Entries   []htmlEntry
+}
+
+// htmlEntry is the section of an HTML report for a single server
+type htmlEntry struct {
+	Server    string
+	Transport string
+	Error     string
+	Replies   []htmlReply
+}
+
+// htmlReply is the summary and records of a single reply in an HTML report
+type htmlReply struct {
+	Question    string
+	Rcode       string
+	StatusClass string
+	Flags       string
+	Time        time.Duration
+	DNSSEC      string
+	DNSSECClass string
+	Records     []htmlRecord
+}
+
+// htmlRecord is a row of the records table of a reply in an HTML report
+type htmlRecord struct {
+	Section, Name, TTL, Type, Value string
+}
+
+// dnssecStatus describes whether a reply was validated by the resolver or signed at all, with the CSS class to show
+// it in
+func dnssecStatus(reply *dns.Msg) (string, string) {
+	if reply.AuthenticatedData {
+		return "validated (AD set)", "ok"
+	}
+	for _, section := range [][]dns.RR{reply.Answer, reply.Ns} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeRRSIG {
+				return "signed, not validated", "warn"
+			}
+		}
+	}
+	return "unsigned", ""
+}
+
+// htmlReplies summarizes the replies of an entry for an HTML report, returning the number of answers in them
+func htmlReplies(e *Entry) ([]htmlReply, int) {
+	var replies []htmlReply
+	var answers int
+	for i, reply := range e.Replies {
+		r := htmlReply{
+			Question: questionString(reply),
+			Rcode:    dns.RcodeToString[reply.Rcode],
+			Flags:    flags(reply),
+			Time:     e.Time.Round(100 * time.Microsecond),
+		}
+		if i < len(e.Durations) {
+			r.Time = e.Durations[i].Round(100 * time.Microsecond)
+		}
+		r.StatusClass = "ok"
+		if reply.Rcode != dns.RcodeSuccess {
+			r.StatusClass = "error"
+		}
+		r.DNSSEC, r.DNSSECClass = dnssecStatus(reply)
+
+		for _, section := range []struct {
+			name string
+			rrs  []dns.RR
+		}{{"Answer", reply.Answer}, {"Authority", reply.Ns}, {"Additional", reply.Extra}} {
+			for _, rr := range section.rrs {
+				if rr.Header().Rrtype == dns.TypeOPT {
+					continue
+				}
+				r.Records = append(r.Records, htmlRecord{
+					Section: section.name,
+					Name:    rr.Header().Name,
+					TTL:     durationTTL(rr.Header().Ttl, true),
+					Type:    dns.TypeToString[rr.Header().Rrtype],
+					Value:   rrValue(rr),
+				})
+			}
+		}
+		answers += len(reply.Answer)
+		replies = append(replies, r)
+	}
+	return replies, answers
+}
+
+// questionString formats the first question of a message as its name, class if not IN, and type
+func questionString(m *dns.Msg) string {
+	if len(m.Question) == 0 {
+		return "(no question)"
+	}
+	q := m.Question[0]
+	if q.Qclass != dns.ClassINET {
+		return fmt.Sprintf("%s %s %s", q.Name, className(q.Qclass), dns.TypeToString[q.Qtype])
+	}
+	return fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype])
+}
+
+// PrintHTML prints a self-contained HTML report with a summary of the query and a table of the flags, timing, DNSSEC
+// status, and records of each reply
+func (p Printer) PrintHTML(entries []*Entry) {
+	entries = p.sortEntries(entries)
+
+	report := htmlReport{
+		Title:     "DNS report",
+		Generated: time.Now().Format(time.RFC1123),
+		Servers:   len(entries),
+	}
+	if p.Opts.Name != "" {
+		report.Title += " for " + p.Opts.Name
+	}
+	for _, e := range entries {
+		replies, answers := htmlReplies(e)
+		report.Entries = append(report.Entries, htmlEntry{
+			Server:    e.Server,
+			Transport: e.Transport,
+			Error:     e.Error,
+			Replies:   replies,
+		})
+		report.Responses += len(replies)
+		report.Answers += answers
+		if e.Error != "" {
+			report.Errors++
+		}
+	}
+
+	if err := htmlTemplate.Execute(p.Out, report); err != nil {
+		log.Fatalf("error rendering HTML report: %s", err)
+	}
+}
@@ -0,0 +1,38 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+)
+
+func TestOutputPrintHTML(t *testing.T) {
+	signed := answerEntry("tls://dns.example:853", "example.com. 300 IN A 192.0.2.1", "example.com. 300 IN RRSIG A 13 2 300 20300101000000 20200101000000 12345 example.com. AAAA")
+	signed.Transport = "tls"
+	signed.Durations = []time.Duration{12 * time.Millisecond}
+	signed.Replies[0].Response = true
+
+	failed := &Entry{Server: "192.0.2.53:53", Error: "connection refused"}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Name: "example.com", Format: FormatHTML}}
+	p.PrintHTML([]*Entry{signed, failed})
+	out := buf.String()
+
+	assert.Contains(t, out, "<!DOCTYPE html>")
+	assert.Contains(t, out, "<style>")
+	assert.Contains(t, out, "<title>DNS report for example.com</title>")
+	assert.Contains(t, out, "<tr><th>Servers</th><td>2</td></tr>")
+	assert.Contains(t, out, "<tr><th>Answers</th><td>2</td></tr>")
+	assert.Contains(t, out, `<tr><th>Errors</th><td class="error">1</td></tr>`)
+	assert.Contains(t, out, "<h2>tls://dns.example:853 over tls</h2>")
+	assert.Contains(t, out, `<tr><th>Status</th><td class="ok">NOERROR</td></tr>`)
+	assert.Contains(t, out, "<tr><th>Time</th><td>12ms</td></tr>")
+	assert.Contains(t, out, `<tr><th>DNSSEC</th><td class="warn">signed, not validated</td></tr>`)
+	assert.Contains(t, out, `<tr><td>Answer</td><td>example.com.</td><td>5m</td><td>A</td><td class="value">192.0.2.1</td></tr>`)
+	assert.Contains(t, out, `<p class="error">Error: connection refused</p>`)
+}
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// influxTagEscaper escapes the characters with special meaning in line protocol tag keys and values
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// influxFieldEscaper escapes the characters with special meaning in line protocol string field values
+var influxFieldEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+
+// influxTags formats key value pairs as line protocol tags, skipping empty values
+func influxTags(pairs ...string) string {
+	var tags string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		tags += fmt.Sprintf(",%s=%s", pairs[i], influxTagEscaper.Replace(pairs[i+1]))
+	}
+	return tags
+}
+
+// PrintInflux prints a measurement in InfluxDB line protocol for each reply, tagged by server, transport, question,
+// and rcode, with the latency in seconds, answer count, and size in bytes as fields. Servers that couldn't be queried
+// get a measurement with an error field instead.
+func (p Printer) PrintInflux(entries []*Entry) {
+	timestamp := time.Now().UnixNano()
+	for _, e := range entries {
+		if e.Error != "" {
+			util.MustWritef(p.Out, "dns%s error=\"%s\" %d\n",
+				influxTags("server", e.Server, "transport", e.Transport),
+				influxFieldEscaper.Replace(e.Error),
+				timestamp,
+			)
+		}
+		for i, reply := range e.Replies {
+			var name, qType string
+			if len(reply.Question) > 0 {
+				name = reply.Question[0].Name
+				qType = dns.TypeToString[reply.Question[0].Qtype]
+			}
+
+			latency := e.Time
+			if i < len(e.Durations) {
+				latency = e.Durations[i]
+			}
+
+			util.MustWritef(p.Out, "dns%s latency=%g,answers=%di,size=%di %d\n",
+				influxTags("server", e.Server, "transport", e.Transport, "name", name, "type", qType, "rcode", dns.RcodeToString[reply.Rcode]),
+				latency.Seconds(),
+				len(reply.Answer),
+				reply.Len(),
+				timestamp,
+			)
+		}
+	}
+}
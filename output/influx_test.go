@@ -0,0 +1,32 @@
+package output
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+)
+
+func TestOutputPrintInflux(t *testing.T) {
+	e := answerEntry("dns server:53", "example.com. 300 IN A 192.0.2.1", "example.com. 300 IN A 192.0.2.2")
+	e.Transport = "plain"
+	e.Durations = []time.Duration{12 * time.Millisecond}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+	p.PrintInflux([]*Entry{e})
+	assert.Regexp(t, regexp.MustCompile(`^dns,server=dns\\ server:53,transport=plain,name=example.com.,type=A,rcode=NOERROR latency=0.012,answers=2i,size=\d+i \d+\n$`), buf.String())
+}
+
+func TestOutputPrintInfluxError(t *testing.T) {
+	e := &Entry{Server: "192.0.2.53", Transport: "tls", Error: `dial "192.0.2.53": timeout`}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+	p.PrintInflux([]*Entry{e})
+	assert.Regexp(t, regexp.MustCompile(`^dns,server=192.0.2.53,transport=tls error="dial \\"192.0.2.53\\": timeout" \d+\n$`), buf.String())
+}
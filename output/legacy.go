@@ -0,0 +1,70 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// afsdbSubtypes names the AFSDB server subtypes (RFC 1183 section 1)
+var afsdbSubtypes = map[uint16]string{
+	1: "AFS cell database server",
+	2: "DCE authenticated name server",
+}
+
+// mailbox converts a domain name mailbox (e.g. hostmaster\.dns.example.com.) to an email address by replacing its first
+// unescaped dot with an @, returning the name unchanged if it's the root
+func mailbox(name string) string {
+	if name == "." {
+		return name
+	}
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '\\':
+			i++
+		case '.':
+			local := strings.ReplaceAll(name[:i], `\.`, ".")
+			return local + "@" + strings.TrimSuffix(name[i+1:], ".")
+		}
+	}
+	return name
+}
+
+// prettyHINFO renders a HINFO record with its CPU and OS strings labeled
+func prettyHINFO(hinfo *dns.HINFO) string {
+	return fmt.Sprintf("cpu %q os %q", hinfo.Cpu, hinfo.Os)
+}
+
+// prettyRP renders an RP record (RFC 1183) with its mailbox as an email address and the name of its TXT record
+func prettyRP(rp *dns.RP) string {
+	val := "mailbox " + util.Color(util.ColorTeal, mailbox(rp.Mbox))
+	if rp.Txt != "." {
+		val += " txt " + rp.Txt
+	}
+	return val
+}
+
+// prettyAFSDB renders an AFSDB record (RFC 1183) with the name of its subtype
+func prettyAFSDB(afsdb *dns.AFSDB) string {
+	subtype, ok := afsdbSubtypes[afsdb.Subtype]
+	if !ok {
+		subtype = "unknown"
+	}
+	return fmt.Sprintf("subtype %d (%s) host %s", afsdb.Subtype, subtype, afsdb.Hostname)
+}
+
+// prettyX25 renders an X25 record's PSDN address (RFC 1183)
+func prettyX25(x25 *dns.X25) string {
+	return fmt.Sprintf("psdn address %s", x25.PSDNAddress)
+}
+
+// prettyISDN renders an ISDN record's address and optional subaddress (RFC 1183)
+func prettyISDN(isdn *dns.ISDN) string {
+	if isdn.SubAddress == "" {
+		return fmt.Sprintf("address %s", isdn.Address)
+	}
+	return fmt.Sprintf("address %s subaddress %s", isdn.Address, isdn.SubAddress)
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// Location is the decoded position of a LOC record (RFC 1876)
+type Location struct {
+	Name      string
+	Latitude  float64 // Degrees, north positive
+	Longitude float64 // Degrees, east positive
+	Altitude  float64 // Meters above the WGS 84 reference spheroid
+}
+
+// decodeLOC converts a LOC record's encoded coordinates to decimal degrees and meters
+func decodeLOC(loc *dns.LOC) Location {
+	return Location{
+		Name:      loc.Hdr.Name,
+		Latitude:  (float64(loc.Latitude) - dns.LOC_EQUATOR) / dns.LOC_DEGREES,
+		Longitude: (float64(loc.Longitude) - dns.LOC_PRIMEMERIDIAN) / dns.LOC_DEGREES,
+		Altitude:  float64(loc.Altitude)/100 - dns.LOC_ALTITUDEBASE,
+	}
+}
+
+// dms formats an offset in thousandths of an arc second as degrees, minutes, and seconds with a hemisphere suffix
+func dms(offset int64, positive, negative string) string {
+	hemisphere := positive
+	if offset < 0 {
+		hemisphere = negative
+		offset = -offset
+	}
+	d := offset / dns.LOC_DEGREES
+	m := offset % dns.LOC_DEGREES / dns.LOC_HOURS
+	s := offset % dns.LOC_HOURS
+	return fmt.Sprintf("%d°%02d'%02d.%03d\"%s", d, m, s/1000, s%1000, hemisphere)
+}
+
+// prettyLOC renders a LOC record as degrees, minutes, and seconds with its altitude and decimal coordinates
+func prettyLOC(loc *dns.LOC) string {
+	l := decodeLOC(loc)
+	return fmt.Sprintf("%s %s %.2fm (%.6f, %.6f)",
+		dms(int64(loc.Latitude)-dns.LOC_EQUATOR, "N", "S"),
+		dms(int64(loc.Longitude)-dns.LOC_PRIMEMERIDIAN, "E", "W"),
+		l.Altitude, l.Latitude, l.Longitude,
+	)
+}
+
+// mapLink returns an OpenStreetMap link to a location
+func (l Location) mapLink() string {
+	return fmt.Sprintf("https://www.openstreetmap.org/?mlat=%.6f&mlon=%.6f", l.Latitude, l.Longitude)
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// splitNAPTRRegexp splits a NAPTR substitution expression (delim pattern delim substitution delim flags) into its
+// parts (RFC 3402 section 3.2), returning false if it isn't well formed
+func splitNAPTRRegexp(regexp string) (string, string, string, bool) {
+	if len(regexp) < 3 {
+		return "", "", "", false
+	}
+	delim := regexp[:1]
+	parts := strings.Split(regexp[1:], delim)
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// prettyNAPTR renders a NAPTR record with labeled fields and its regexp split into pattern and substitution
+func prettyNAPTR(naptr *dns.NAPTR) string {
+	val := fmt.Sprintf("order %d pref %d flags %q service %q", naptr.Order, naptr.Preference, naptr.Flags, naptr.Service)
+
+	if pattern, substitution, flags, ok := splitNAPTRRegexp(naptr.Regexp); ok {
+		val += fmt.Sprintf(" regexp %s → %s", util.Color(util.ColorTeal, pattern), util.Color(util.ColorTeal, substitution))
+		if flags != "" {
+			val += " (" + flags + ")"
+		}
+	} else if naptr.Regexp != "" {
+		val += fmt.Sprintf(" regexp %q", naptr.Regexp)
+	}
+
+	if naptr.Replacement != "." {
+		val += " replacement " + naptr.Replacement
+	}
+	return val
+}
@@ -0,0 +1,275 @@
+package output
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// OpenPGP packet tags (RFC 9580 section 5)
+const (
+	pgpTagSignature = 2
+	pgpTagPublicKey = 6
+	pgpTagUserID    = 13
+	pgpTagSubkey    = 14
+)
+
+// pgpAlgorithms maps OpenPGP public key algorithm IDs to their names (RFC 9580 section 9.1)
+var pgpAlgorithms = map[uint8]string{
+	1:  "RSA",
+	2:  "RSA (encrypt only)",
+	3:  "RSA (sign only)",
+	16: "Elgamal",
+	17: "DSA",
+	18: "ECDH",
+	19: "ECDSA",
+	22: "EdDSA",
+	25: "X25519",
+	26: "X448",
+	27: "Ed25519",
+	28: "Ed448",
+}
+
+// pgpCurves maps the hex encoded OIDs of elliptic curves used by OpenPGP keys to their names
+var pgpCurves = map[string]string{
+	"2a8648ce3d030107":     "P-256",
+	"2b81040022":           "P-384",
+	"2b81040023":           "P-521",
+	"2b06010401da470f01":   "Ed25519",
+	"2b060104019755010501": "Curve25519",
+	"2b2403030208010107":   "brainpoolP256r1",
+	"2b240303020801010b":   "brainpoolP384r1",
+	"2b240303020801010d":   "brainpoolP512r1",
+}
+
+// OpenPGPKey is a decoded OPENPGPKEY record (RFC 7929)
+type OpenPGPKey struct {
+	Name        string
+	KeyID       string     `json:"key_id" yaml:"key_id"`
+	Fingerprint string     // Hex encoded v4 or v6 fingerprint
+	Algorithm   string     // Public key algorithm, with the curve or key size if known
+	Created     time.Time  // Key creation time
+	Expires     *time.Time `json:",omitempty" yaml:",omitempty"` // Expiration time from the self-signature, nil if the key doesn't expire
+	UserIDs     []string   `json:"user_ids,omitempty" yaml:"user_ids,omitempty"`
+}
+
+// pgpPacket is a single OpenPGP packet
+type pgpPacket struct {
+	tag  uint8
+	body []byte
+}
+
+// pgpPackets splits a binary OpenPGP message into its packets (RFC 9580 section 4.2)
+func pgpPackets(b []byte) ([]pgpPacket, error) {
+	var packets []pgpPacket
+	for len(b) > 0 {
+		header := b[0]
+		if header&0x80 == 0 {
+			return nil, fmt.Errorf("invalid packet header 0x%02x", header)
+		}
+
+		var tag uint8
+		var length, offset int
+		if header&0x40 != 0 { // OpenPGP format
+			tag = header & 0x3f
+			if len(b) < 2 {
+				return nil, fmt.Errorf("truncated packet header")
+			}
+			switch l := int(b[1]); {
+			case l < 192:
+				length, offset = l, 2
+			case l < 224:
+				if len(b) < 3 {
+					return nil, fmt.Errorf("truncated packet header")
+				}
+				length, offset = (l-192)<<8+int(b[2])+192, 3
+			case l == 255:
+				if len(b) < 6 {
+					return nil, fmt.Errorf("truncated packet header")
+				}
+				length, offset = int(binary.BigEndian.Uint32(b[2:6])), 6
+			default:
+				return nil, fmt.Errorf("unsupported partial body length")
+			}
+		} else { // Legacy format
+			tag = (header >> 2) & 0x0f
+			switch header & 0x03 {
+			case 0:
+				if len(b) < 2 {
+					return nil, fmt.Errorf("truncated packet header")
+				}
+				length, offset = int(b[1]), 2
+			case 1:
+				if len(b) < 3 {
+					return nil, fmt.Errorf("truncated packet header")
+				}
+				length, offset = int(binary.BigEndian.Uint16(b[1:3])), 3
+			case 2:
+				if len(b) < 5 {
+					return nil, fmt.Errorf("truncated packet header")
+				}
+				length, offset = int(binary.BigEndian.Uint32(b[1:5])), 5
+			default:
+				length, offset = len(b)-1, 1
+			}
+		}
+
+		if length < 0 || offset+length > len(b) {
+			return nil, fmt.Errorf("packet length %d exceeds data", length)
+		}
+		packets = append(packets, pgpPacket{tag: tag, body: b[offset : offset+length]})
+		b = b[offset+length:]
+	}
+	return packets, nil
+}
+
+// pgpKeyAlgorithm describes the algorithm of a key from its public key material, naming the curve or key size
+func pgpKeyAlgorithm(alg uint8, material []byte) string {
+	name, ok := pgpAlgorithms[alg]
+	if !ok {
+		name = fmt.Sprintf("algorithm %d", alg)
+	}
+	switch alg {
+	case 1, 2, 3, 16, 17: // The first MPI is the modulus or prime
+		if len(material) >= 2 {
+			name += fmt.Sprintf(" %d", binary.BigEndian.Uint16(material))
+		}
+	case 18, 19, 22: // The key starts with the curve OID
+		if len(material) >= 1 && len(material) >= 1+int(material[0]) {
+			if curve, ok := pgpCurves[hex.EncodeToString(material[1:1+material[0]])]; ok {
+				name += " " + curve
+			}
+		}
+	}
+	return name
+}
+
+// pgpKeyExpiration returns the key expiration time subpacket of a signature in seconds after key creation, if present
+func pgpKeyExpiration(sig []byte) (uint32, bool) {
+	var subpackets []byte
+	switch {
+	case len(sig) >= 6 && sig[0] == 4:
+		n := int(binary.BigEndian.Uint16(sig[4:6]))
+		if 6+n > len(sig) {
+			return 0, false
+		}
+		subpackets = sig[6 : 6+n]
+	case len(sig) >= 8 && sig[0] == 6:
+		n := int(binary.BigEndian.Uint32(sig[4:8]))
+		if n < 0 || 8+n > len(sig) {
+			return 0, false
+		}
+		subpackets = sig[8 : 8+n]
+	default:
+		return 0, false
+	}
+
+	for len(subpackets) > 0 {
+		var length, offset int
+		switch l := int(subpackets[0]); {
+		case l < 192:
+			length, offset = l, 1
+		case l < 255:
+			if len(subpackets) < 2 {
+				return 0, false
+			}
+			length, offset = (l-192)<<8+int(subpackets[1])+192, 2
+		default:
+			if len(subpackets) < 5 {
+				return 0, false
+			}
+			length, offset = int(binary.BigEndian.Uint32(subpackets[1:5])), 5
+		}
+		if length < 1 || offset+length > len(subpackets) {
+			return 0, false
+		}
+		data := subpackets[offset : offset+length]
+		if data[0]&0x7f == 9 && len(data) == 5 { // Key expiration time
+			return binary.BigEndian.Uint32(data[1:]), true
+		}
+		subpackets = subpackets[offset+length:]
+	}
+	return 0, false
+}
+
+// decodeOpenPGPKey decodes the primary key, user IDs, and expiration of an OPENPGPKEY record
+func decodeOpenPGPKey(rr *dns.OPENPGPKEY) (OpenPGPKey, error) {
+	key := OpenPGPKey{Name: rr.Hdr.Name}
+	b, err := base64.StdEncoding.DecodeString(rr.PublicKey)
+	if err != nil {
+		return key, err
+	}
+	packets, err := pgpPackets(b)
+	if err != nil {
+		return key, err
+	}
+	if len(packets) == 0 || packets[0].tag != pgpTagPublicKey {
+		return key, fmt.Errorf("no public key packet")
+	}
+
+	body := packets[0].body
+	switch {
+	case len(body) >= 6 && body[0] == 4:
+		sum := sha1.Sum(append([]byte{0x99, byte(len(body) >> 8), byte(len(body))}, body...))
+		key.Fingerprint = strings.ToUpper(hex.EncodeToString(sum[:]))
+		key.KeyID = key.Fingerprint[24:]
+		key.Algorithm = pgpKeyAlgorithm(body[5], body[6:])
+	case len(body) >= 10 && body[0] == 6:
+		header := []byte{0x9b, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[1:], uint32(len(body)))
+		sum := sha256.Sum256(append(header, body...))
+		key.Fingerprint = strings.ToUpper(hex.EncodeToString(sum[:]))
+		key.KeyID = key.Fingerprint[:16]
+		key.Algorithm = pgpKeyAlgorithm(body[5], body[10:])
+	default:
+		return key, fmt.Errorf("unsupported public key packet version")
+	}
+	key.Created = time.Unix(int64(binary.BigEndian.Uint32(body[1:5])), 0).UTC()
+
+	// Self-signatures follow the user IDs of the primary key, before any subkeys
+	for _, packet := range packets[1:] {
+		if packet.tag == pgpTagSubkey {
+			break
+		}
+		switch packet.tag {
+		case pgpTagUserID:
+			key.UserIDs = append(key.UserIDs, string(packet.body))
+		case pgpTagSignature:
+			if seconds, ok := pgpKeyExpiration(packet.body); ok && seconds > 0 && key.Expires == nil {
+				expires := key.Created.Add(time.Duration(seconds) * time.Second)
+				key.Expires = &expires
+			}
+		}
+	}
+	return key, nil
+}
+
+// prettyOpenPGPKey renders an OPENPGPKEY record as its key ID, algorithm, validity, and user IDs
+func prettyOpenPGPKey(rr *dns.OPENPGPKEY) (string, bool) {
+	key, err := decodeOpenPGPKey(rr)
+	if err != nil {
+		return "", false
+	}
+
+	val := fmt.Sprintf("%s key %s created %s", key.Algorithm, util.Color(util.ColorTeal, "0x"+key.KeyID), key.Created.Format(time.DateOnly))
+	if key.Expires != nil {
+		if key.Expires.Before(time.Now()) {
+			val += " " + util.Color(util.ColorRed, "expired "+key.Expires.Format(time.DateOnly))
+		} else {
+			val += " expires " + key.Expires.Format(time.DateOnly)
+		}
+	}
+	for _, uid := range key.UserIDs {
+		val += " " + util.Color(util.ColorPurple, uid)
+	}
+	return val, true
+}
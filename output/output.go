@@ -1,6 +1,8 @@
 package output
 
 import (
+	"context"
+	"crypto/tls"
 	"io"
 	"time"
 
@@ -13,11 +15,15 @@ import (
 )
 
 var (
-	FormatPretty = "pretty"
-	FormatColumn = "column"
-	FormatJSON   = "json"
-	FormatYAML   = "yaml"
-	FormatRAW    = "raw"
+	FormatPretty  = "pretty"
+	FormatColumn  = "column"
+	FormatJSON    = "json"
+	FormatYAML    = "yaml"
+	FormatRAW     = "raw"
+	FormatCompare = "compare"
+	FormatInflux  = "influx"
+	FormatShort   = "short"
+	FormatHTML    = "html"
 )
 
 // Printer stores global options across multiple entries
@@ -32,19 +38,110 @@ type Printer struct {
 
 // Entry stores the replies from a server
 type Entry struct {
-	Queries []dns.Msg
-	Replies []*dns.Msg
-	Server  string
+	// SchemaVersion is the version of the structured output schema, only set for structured output
+	SchemaVersion int `json:"schema_version" yaml:"schema_version"`
+
+	// Queries and Replies are the messages as sent and received. Structured output has them decoded in QueryMessages
+	// and Responses instead, so that it doesn't depend on the dns library's types.
+	Queries []dns.Msg  `json:"-" yaml:"-"`
+	Replies []*dns.Msg `json:"-" yaml:"-"`
+	Server  string     `json:"server" yaml:"server"`
+
+	// Transport is the type of transport the server was queried over
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+
+	// Error is why the server couldn't be queried, only set when comparing servers or with --continue-on-error
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
 
 	// Time is the total time it took to query this server
-	Time time.Duration
+	Time time.Duration `json:"time" yaml:"time"`
+
+	// Durations is the latency of each exchange
+	Durations []time.Duration `json:"-" yaml:"-"`
+
+	// Timings is the timing breakdown of each exchange, if supported by the transport
+	Timings []transport.Timings `json:"timings,omitempty" yaml:"timings,omitempty"`
+
+	// TLS is the negotiated TLS connection metadata, if a TLS-based transport was used
+	TLS *TLSInfo `json:"tls,omitempty" yaml:"tls,omitempty"`
+
+	// QueryMessages are the decoded queries, only populated for structured output
+	QueryMessages []Response `json:"queries,omitempty" yaml:"queries,omitempty"`
+
+	// Responses are the decoded header, sections, and OPT record of each reply, only populated for structured output
+	Responses []Response `json:"responses,omitempty" yaml:"responses,omitempty"`
+
+	// EDNS is the decoded OPT record of each query and reply, only populated for structured output
+	EDNS []EDNSExchange `json:"edns,omitempty" yaml:"edns,omitempty"`
+
+	// ExtendedErrors are the Extended DNS Error options of the replies, only populated for structured output
+	ExtendedErrors []ExtendedError `json:"extended_errors,omitempty" yaml:"extended_errors,omitempty"`
+
+	// ExpireTimers are the EDNS0 expire timers of the replies, only populated for structured output with --expire
+	ExpireTimers []ExpireTimer `json:"expire_timers,omitempty" yaml:"expire_timers,omitempty"`
+
+	// Denials are the NODATA and compact NXDOMAIN replies proven with NSEC records, only populated for structured output
+	Denials []Denial `json:"denials,omitempty" yaml:"denials,omitempty"`
+
+	// CookieHandshakes are the queries that were retried with the server cookie from a BADCOOKIE reply
+	CookieHandshakes []CookieHandshake `json:"cookie_handshakes,omitempty" yaml:"cookie_handshakes,omitempty"`
+
+	// Inconsistencies are the questions that returned different answers when repeated with --verify
+	Inconsistencies []Inconsistency `json:"inconsistencies,omitempty" yaml:"inconsistencies,omitempty"`
 
-	PTRs        map[string]string `json:"-"` // IP -> PTR value
+	// Zone is the zone the queried name belongs to, found with --zone
+	Zone *ZoneCut `json:"zone,omitempty" yaml:"zone,omitempty"`
+
+	// SSHKeys are the host keys to verify SSHFP records against with --sshfp-verify
+	SSHKeys []SSHHostKey `json:"-" yaml:"-"`
+
+	// GeoDB is the database to annotate addresses from with --geo-db
+	GeoDB *GeoDB `json:"-" yaml:"-"`
+
+	PTRs        map[string]string `json:"-" yaml:"-"` // IP -> PTR value
 	existingRRs map[string]bool
 }
 
+// TLSInfo stores metadata about a negotiated TLS connection
+type TLSInfo struct {
+	ServerName  string `json:"server_name" yaml:"server_name"`                       // SNI sent by the client
+	ALPN        string `json:"alpn" yaml:"alpn"`                                     // Negotiated application protocol
+	Version     string `json:"version,omitempty" yaml:"version,omitempty"`           // Negotiated TLS version, e.g. TLS 1.3
+	CipherSuite string `json:"cipher_suite,omitempty" yaml:"cipher_suite,omitempty"` // Negotiated cipher suite
+	Resumed     bool   `json:"resumed" yaml:"resumed"`                               // Whether the session was resumed
+}
+
+// Inconsistency stores two differing answer sets returned for the same question
+type Inconsistency struct {
+	Question string
+	First    []string
+	Second   []string
+}
+
+// LoadTLS populates an entry's TLS metadata from the transport's connection state
+func (e *Entry) LoadTLS(txp *transport.Transport) {
+	stater, ok := (*txp).(transport.TLSStater)
+	if !ok {
+		return
+	}
+	state := stater.ConnectionState()
+	if state == nil {
+		return
+	}
+
+	e.TLS = &TLSInfo{
+		ServerName:  state.ServerName,
+		ALPN:        state.NegotiatedProtocol,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Resumed:     state.DidResume,
+	}
+	log.Debugf("Negotiated %s with %s, ALPN %s, resumed %t",
+		e.TLS.Version, e.TLS.CipherSuite, orNone(e.TLS.ALPN), e.TLS.Resumed)
+}
+
 // LoadPTRs populates an entry's PTRs map with PTR values for all A/AAAA records
-func (e *Entry) LoadPTRs(txp *transport.Transport) {
+func (e *Entry) LoadPTRs(ctx context.Context, txp *transport.Transport) {
 	// Initialize PTR cache if it doesn't exist
 	if e.PTRs == nil {
 		e.PTRs = make(map[string]string)
@@ -72,7 +169,7 @@ func (e *Entry) LoadPTRs(txp *transport.Transport) {
 			msg.SetQuestion(qname, dns.TypePTR)
 
 			// Resolve qname and cache result
-			resp, err := (*txp).Exchange(&msg)
+			resp, err := (*txp).Exchange(ctx, &msg)
 			if err != nil {
 				log.Warnf("error resolving PTR record: %s", err)
 				continue
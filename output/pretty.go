@@ -53,6 +53,17 @@ func (p Printer) PrettyPrintNSID(entries []*Entry, printPrefix bool) {
 	}
 }
 
+// rrValue returns the presentation format of an RR's rdata without the header fields
+func rrValue(a dns.RR) string {
+	val := a.String()
+	for _, cut := range []string{a.Header().Name, strconv.Itoa(int(a.Header().Ttl)), dns.ClassToString[a.Header().Class], dns.TypeToString[a.Header().Rrtype]} {
+		val = strings.TrimSpace(
+			strings.TrimPrefix(val, cut),
+		)
+	}
+	return val
+}
+
 // parseRR converts an RR into a pretty string and returns the qname, ttl, type, value, and whether to skip printing it because it's a duplicate
 func (e *Entry) parseRR(a dns.RR, opts *cli.Flags) *RR {
 	// Initialize existingRRs map if it doesn't exist
@@ -60,12 +71,7 @@ func (e *Entry) parseRR(a dns.RR, opts *cli.Flags) *RR {
 		e.existingRRs = make(map[string]bool)
 	}
 
-	val := a.String()
-	for _, cut := range []string{a.Header().Name, strconv.Itoa(int(a.Header().Ttl)), dns.ClassToString[a.Header().Class], dns.TypeToString[a.Header().Rrtype]} {
-		val = strings.TrimSpace(
-			strings.TrimPrefix(val, cut),
-		)
-	}
+	val := rrValue(a)
 
 	rrSignature := fmt.Sprintf("%s %d %s %s %s", a.Header().Name, a.Header().Ttl, dns.TypeToString[a.Header().Rrtype], val, e.Server)
 	// Skip if we've already printed this RR
@@ -75,17 +81,20 @@ func (e *Entry) parseRR(a dns.RR, opts *cli.Flags) *RR {
 	e.existingRRs[rrSignature] = true
 
 	ttl := fmt.Sprintf("%d", a.Header().Ttl)
-	if opts.PrettyTTLs {
-		ttl = (time.Duration(a.Header().Ttl) * time.Second).String()
-		if opts.ShortTTLs {
-			ttl = strings.ReplaceAll(ttl, "m0s", "m")
-			ttl = strings.ReplaceAll(ttl, "h0m", "h")
-		}
+	if opts.PrettyTTLs || opts.TTLHuman {
+		ttl = durationTTL(a.Header().Ttl, opts.ShortTTLs || opts.TTLHuman)
 	}
 
 	// Copy val now before modifying it with a suffix
 	valCopy := val
 
+	// Render record types with a more readable representation
+	if !opts.ValueOnly {
+		if pretty, ok := e.prettyValue(a); ok {
+			val = pretty
+		}
+	}
+
 	// Handle whois
 	if opts.Whois && (a.Header().Rrtype == dns.TypeA || a.Header().Rrtype == dns.TypeAAAA) {
 		resp, err := whois.Query(valCopy)
@@ -96,6 +105,25 @@ func (e *Entry) parseRR(a dns.RR, opts *cli.Flags) *RR {
 		}
 	}
 
+	// Annotate addresses with their ASN and country from a local database
+	if e.GeoDB != nil && !opts.ValueOnly && (a.Header().Rrtype == dns.TypeA || a.Header().Rrtype == dns.TypeAAAA) {
+		if annotation, ok := e.GeoDB.annotation(valCopy); ok {
+			val += util.Color(util.ColorTeal, fmt.Sprintf(" (%s)", annotation))
+		}
+	}
+
+	// Annotate DNS64 synthesized addresses with the embedded IPv4 address
+	if aaaa, ok := a.(*dns.AAAA); ok && !opts.ValueOnly {
+		if v4, ok := dns64Annotation(aaaa.AAAA, opts.DNS64Prefixes); ok {
+			val += util.Color(util.ColorTeal, fmt.Sprintf(" (DNS64 → %s)", v4))
+		}
+	}
+
+	// Link LOC records to a map
+	if loc, ok := a.(*dns.LOC); ok && opts.LOCMapLink && !opts.ValueOnly {
+		val += util.Color(util.ColorTeal, " "+decodeLOC(loc).mapLink())
+	}
+
 	// Handle PTR resolution
 	if opts.ResolveIPs && (a.Header().Rrtype == dns.TypeA || a.Header().Rrtype == dns.TypeAAAA) {
 		val += util.Color(util.ColorMagenta, fmt.Sprintf(" (%s)", e.PTRs[valCopy]))
@@ -106,14 +134,113 @@ func (e *Entry) parseRR(a dns.RR, opts *cli.Flags) *RR {
 		val += util.Color(util.ColorTeal, fmt.Sprintf(" (%s)", e.Server))
 	}
 
+	// Highlight TTLs outside the expected range
+	ttlColor := util.ColorGreen
+	if (opts.TTLAbove > 0 && a.Header().Ttl > opts.TTLAbove) || a.Header().Ttl < opts.TTLBelow {
+		ttlColor = util.ColorRed
+	}
+
 	return &RR{
-		util.Color(util.ColorPurple, a.Header().Name),
-		util.Color(util.ColorGreen, ttl),
-		util.Color(util.ColorMagenta, dns.TypeToString[a.Header().Rrtype]),
-		val,
+		Name:  util.Color(util.ColorPurple, a.Header().Name),
+		TTL:   util.Color(ttlColor, ttl),
+		Type:  util.Color(util.ColorMagenta, dns.TypeToString[a.Header().Rrtype]),
+		Value: val,
+		Class: className(a.Header().Class),
+		ttl:   a.Header().Ttl,
+	}
+}
+
+// SortByTTL orders the answers of every reply by ascending TTL, keeping the order of records with equal TTLs
+func SortByTTL(entries []*Entry) {
+	for _, e := range entries {
+		for _, reply := range e.Replies {
+			sort.SliceStable(reply.Answer, func(i, j int) bool {
+				return reply.Answer[i].Header().Ttl < reply.Answer[j].Header().Ttl
+			})
+		}
+	}
+}
+
+// sortRecords orders records by name, type, and value, keeping OPT and TSIG pseudo-records after them in their original
+// order since a TSIG record must stay last
+func sortRecords(rrs []dns.RR) []dns.RR {
+	var records, pseudo []dns.RR
+	for _, rr := range rrs {
+		if t := rr.Header().Rrtype; t == dns.TypeOPT || t == dns.TypeTSIG {
+			pseudo = append(pseudo, rr)
+		} else {
+			records = append(records, rr)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i].Header(), records[j].Header()
+		if nameA, nameB := strings.ToLower(a.Name), strings.ToLower(b.Name); nameA != nameB {
+			return nameA < nameB
+		}
+		if a.Rrtype != b.Rrtype {
+			return a.Rrtype < b.Rrtype
+		}
+		return rrValue(records[i]) < rrValue(records[j])
+	})
+	return append(records, pseudo...)
+}
+
+// SortSections orders the records in every section of every reply by name, type, and value, since servers often
+// rotate the order of records in an RRset
+func SortSections(entries []*Entry) {
+	for _, e := range entries {
+		for _, reply := range e.Replies {
+			reply.Answer = sortRecords(reply.Answer)
+			reply.Ns = sortRecords(reply.Ns)
+			reply.Extra = sortRecords(reply.Extra)
+		}
 	}
 }
 
+// sortEntries returns entries with the record orders requested by --sort and --sort-ttl, with TTL order taking
+// precedence. The replies are copied before sorting so that the entries keep the replies as received.
+func (p Printer) sortEntries(entries []*Entry) []*Entry {
+	if !p.Opts.Sort && !p.Opts.SortTTL {
+		return entries
+	}
+
+	sorted := make([]*Entry, len(entries))
+	for i, e := range entries {
+		c := *e
+		c.Replies = make([]*dns.Msg, len(e.Replies))
+		for j, reply := range e.Replies {
+			c.Replies[j] = reply.Copy()
+		}
+		sorted[i] = &c
+	}
+
+	if p.Opts.Sort {
+		SortSections(sorted)
+	}
+	if p.Opts.SortTTL {
+		SortByTTL(sorted)
+	}
+	return sorted
+}
+
+// durationTTL formats a TTL as a duration, optionally removing zero components (24h0m0s -> 24h)
+func durationTTL(ttl uint32, short bool) string {
+	s := (time.Duration(ttl) * time.Second).String()
+	if short {
+		s = strings.ReplaceAll(s, "m0s", "m")
+		s = strings.ReplaceAll(s, "h0m", "h")
+	}
+	return s
+}
+
+// className returns the name of a DNS class
+func className(class uint16) string {
+	if s, ok := dns.ClassToString[class]; ok {
+		return s
+	}
+	return "CLASS" + strconv.Itoa(int(class))
+}
+
 // sortSlices sorts a slice of slices of strings by the nth element of each slice
 func sortSlices(s [][]string, n int) [][]string {
 	sort.Slice(s, func(i, j int) bool {
@@ -153,6 +280,11 @@ func sortToPrint(s [][]string) [][]string {
 
 type RR struct {
 	Name, TTL, Type, Value string
+
+	// Class is the uncolored class name, only printed when a section contains non-IN records
+	Class string
+
+	ttl uint32 // Unformatted TTL for --sort-ttl
 }
 
 func toRRs(rrs []dns.RR, e *Entry, p *Printer) []RR {
@@ -170,6 +302,14 @@ func toRRs(rrs []dns.RR, e *Entry, p *Printer) []RR {
 func (p Printer) printSection(rrs []RR) {
 	var toPrint [][]string
 
+	if p.Opts.SortTTL {
+		sort.SliceStable(rrs, func(i, j int) bool {
+			return rrs[i].ttl < rrs[j].ttl
+		})
+	}
+
+	showClass := false
+	longestClass := 0
 	for _, a := range rrs {
 		if p.Opts.ValueOnly {
 			util.MustWriteln(p.Out, a.Value)
@@ -182,24 +322,39 @@ func (p Printer) printSection(rrs []RR) {
 		if len(a.Type) > p.longestRRType {
 			p.longestRRType = len(a.Type)
 		}
+		if len(a.Class) > longestClass {
+			longestClass = len(a.Class)
+		}
+		if a.Class != "IN" {
+			showClass = true
+		}
 
-		toPrint = append(toPrint, []string{a.Name, a.TTL, a.Type, a.Value})
+		toPrint = append(toPrint, []string{a.Name, a.TTL, a.Type, a.Value, a.Class})
 	}
 
-	// Sort by record type
-	toPrint = sortToPrint(toPrint)
+	// Sort by record type unless already sorted by TTL or by name
+	if !p.Opts.SortTTL && !p.Opts.Sort {
+		toPrint = sortToPrint(toPrint)
+	}
 
 	for _, a := range toPrint {
+		// Only show the class column if there are non-IN records
+		class := ""
+		if showClass {
+			class = util.Color(util.ColorTeal, fmt.Sprintf("%-"+strconv.Itoa(longestClass)+"s", a[4])) + " "
+		}
+
 		if p.Opts.Format == "column" {
-			util.MustWritef(p.Out, "%"+strconv.Itoa(p.longestRRType)+"s %-"+strconv.Itoa(p.longestTTL)+"s %s\n", a[2], a[1], a[3])
+			util.MustWritef(p.Out, "%"+strconv.Itoa(p.longestRRType)+"s %-"+strconv.Itoa(p.longestTTL)+"s %s%s\n", a[2], a[1], class, a[3])
 		} else {
-			util.MustWritef(p.Out, "%s %s %s %s\n", a[0], a[1], a[2], a[3])
+			util.MustWritef(p.Out, "%s %s %s%s %s\n", a[0], a[1], class, a[2], a[3])
 		}
 	}
 }
 
 // PrintColumn prints an entry slice in column format
 func (p Printer) PrintColumn(entries []*Entry) {
+	entries = p.sortEntries(entries)
 	var answers []RR
 	for _, e := range entries {
 		for _, r := range e.Replies {
@@ -209,6 +364,26 @@ func (p Printer) PrintColumn(entries []*Entry) {
 	}
 
 	p.printSection(answers)
+	for _, e := range entries {
+		p.printError(e)
+		for i := range e.Replies {
+			p.printAnnotations(e, i)
+		}
+	}
+}
+
+// printAnnotations prints the metadata of an entry's i-th reply below its records, for pretty and column output
+func (p Printer) printAnnotations(e *Entry, i int) {
+	reply := e.Replies[i]
+	p.printExtendedErrors(reply)
+	p.printDenial(reply)
+	p.printValidation(reply)
+	p.printTSIG(reply)
+	p.printExpire(reply)
+	p.printECSScope(reply)
+	if i < len(e.Queries) {
+		p.printCookie(&e.Queries[i], reply)
+	}
 }
 
 // flags returns a string of flags from a dns.Msg
@@ -241,14 +416,81 @@ func flags(m *dns.Msg) string {
 	return strings.TrimSuffix(out, " ")
 }
 
+// headerLine summarizes a message's header flags and section counts like dig
+func headerLine(m *dns.Msg) string {
+	return fmt.Sprintf(";; flags: %s; QUERY: %d, ANSWER: %d, AUTHORITY: %d, ADDITIONAL: %d",
+		util.Color(util.ColorPurple, flags(m)), len(m.Question), len(m.Answer), len(m.Ns), len(m.Extra))
+}
+
+// optLine summarizes a message's EDNS version, flags, and UDP payload size like dig, or returns false if it has no OPT record
+func optLine(m *dns.Msg) (string, bool) {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return "", false
+	}
+	var ednsFlags []string
+	if opt.Do() {
+		ednsFlags = append(ednsFlags, "do")
+	}
+	if opt.Co() {
+		ednsFlags = append(ednsFlags, "co")
+	}
+	return fmt.Sprintf(";; EDNS: version: %d, flags: %s; udp: %d",
+		opt.Version(), util.Color(util.ColorPurple, strings.Join(ednsFlags, " ")), opt.UDPSize()), true
+}
+
+// printValidation shows whether a reply has the AD bit set when validation was requested by setting AD on the query
+func (p Printer) printValidation(reply *dns.Msg) {
+	if !p.Opts.AuthenticData || p.Opts.ValueOnly {
+		return
+	}
+	if reply.AuthenticatedData {
+		util.MustWritef(p.Out, "AD: requested → %s\n", util.Color(util.ColorGreen, "set (validated)"))
+	} else {
+		util.MustWritef(p.Out, "AD: requested → %s\n", util.Color(util.ColorYellow, "clear (not validated)"))
+	}
+}
+
+// printTSIG shows whether the reply to a TSIG signed query was signed by the server
+func (p Printer) printTSIG(reply *dns.Msg) {
+	if p.Opts.TSIG == "" || p.Opts.ValueOnly {
+		return
+	}
+	if t := reply.IsTsig(); t != nil {
+		util.MustWritef(p.Out, "TSIG: %s\n", util.Color(util.ColorGreen, fmt.Sprintf("verified (%s %s)", t.Hdr.Name, strings.TrimSuffix(t.Algorithm, "."))))
+	} else {
+		util.MustWritef(p.Out, "TSIG: %s\n", util.Color(util.ColorYellow, "reply not signed"))
+	}
+}
+
+// printError shows the error of an entry for a server that couldn't be queried
+func (p Printer) printError(e *Entry) {
+	if e.Error != "" {
+		util.MustWritef(p.Out, "%s %s: %s\n", util.Color(util.ColorRed, "Error from"), e.Server, e.Error)
+	}
+}
+
 func (p Printer) PrintPretty(entries []*Entry) {
+	entries = p.sortEntries(entries)
 	for _, entry := range entries {
+		p.printError(entry)
 		for i, reply := range entry.Replies {
+			if p.Opts.ShowHeader {
+				util.MustWriteln(p.Out, headerLine(reply))
+				if line, ok := optLine(reply); ok {
+					util.MustWriteln(p.Out, line)
+				}
+			}
 			if p.Opts.ShowQuestion {
 				util.MustWriteln(p.Out, util.Color(util.ColorWhite, "Question:"))
 				for _, a := range reply.Question {
-					util.MustWritef(p.Out, "%s %s\n",
+					class := ""
+					if a.Qclass != dns.ClassINET {
+						class = util.Color(util.ColorTeal, className(a.Qclass)) + " "
+					}
+					util.MustWritef(p.Out, "%s %s%s\n",
 						util.Color(util.ColorPurple, a.Name),
+						class,
 						util.Color(util.ColorMagenta, dns.TypeToString[a.Qtype]),
 					)
 				}
@@ -267,6 +509,7 @@ func (p Printer) PrintPretty(entries []*Entry) {
 				util.MustWriteln(p.Out, util.Color(util.ColorWhite, "Additional:"))
 				p.printSection(toRRs(reply.Extra, entry, &p))
 			}
+			p.printAnnotations(entry, i)
 
 			// Print separator if there is more than one query
 			if (p.Opts.ShowQuestion || p.Opts.ShowAuthority || p.Opts.ShowAdditional) &&
@@ -293,7 +536,71 @@ func (p Printer) PrintPretty(entries []*Entry) {
 					util.Color(util.ColorTeal, fmt.Sprintf("%d", len(reply.Ns))),
 					util.Color(util.ColorMagenta, fmt.Sprintf("%d", len(reply.Extra))),
 				)
+
+				// Show the EDNS version the query was answered with, which may have been downgraded
+				if i < len(entry.Queries) {
+					if opt := entry.Queries[i].IsEdns0(); opt != nil && reply.IsEdns0() != nil {
+						util.MustWritef(p.Out, "EDNS version: %s\n", util.Color(util.ColorGreen, opt.Version()))
+					}
+				}
+			}
+
+			if p.Opts.ShowTimings && i < len(entry.Timings) {
+				t := entry.Timings[i]
+				util.MustWriteln(p.Out, util.Color(util.ColorWhite, "Timings:"))
+				if p.Opts.VerboseTiming {
+					util.MustWritef(p.Out, "Connect %s Handshake %s ",
+						util.Color(util.ColorPurple, t.Connect.Round(10*time.Microsecond)),
+						util.Color(util.ColorPurple, t.Handshake.Round(10*time.Microsecond)),
+					)
+				}
+				util.MustWritef(p.Out, "First byte %s Total %s\n",
+					util.Color(util.ColorTeal, t.FirstByte.Round(10*time.Microsecond)),
+					util.Color(util.ColorTeal, t.Total.Round(10*time.Microsecond)),
+				)
+			}
+		}
+
+		if p.Opts.ShowMeta && entry.TLS != nil {
+			util.MustWriteln(p.Out, util.Color(util.ColorWhite, "Meta:"))
+			util.MustWritef(p.Out, "TLS SNI: %s ALPN: %s\n",
+				util.Color(util.ColorPurple, orNone(entry.TLS.ServerName)),
+				util.Color(util.ColorGreen, orNone(entry.TLS.ALPN)),
+			)
+			if entry.TLS.Version != "" {
+				util.MustWritef(p.Out, "TLS version: %s Cipher: %s Resumed: %t\n",
+					util.Color(util.ColorPurple, entry.TLS.Version),
+					util.Color(util.ColorTeal, entry.TLS.CipherSuite),
+					entry.TLS.Resumed,
+				)
 			}
 		}
+
+		p.printCookieHandshakes(entry)
+
+		if p.Opts.Verify {
+			if len(entry.Inconsistencies) == 0 {
+				util.MustWritef(p.Out, "Verify: %s\n", util.Color(util.ColorGreen, "answers consistent across repeated queries"))
+			}
+			for _, inc := range entry.Inconsistencies {
+				util.MustWritef(p.Out, "Verify: %s\n", util.Color(util.ColorRed, "inconsistent answers for "+inc.Question))
+				for _, answer := range inc.First {
+					util.MustWriteln(p.Out, "  - "+answer)
+				}
+				for _, answer := range inc.Second {
+					util.MustWriteln(p.Out, "  + "+answer)
+				}
+			}
+		}
+
+		p.printZone(entry)
 	}
 }
+
+// orNone returns s, or "none" if s is empty
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
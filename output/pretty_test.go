@@ -2,11 +2,15 @@ package output
 
 import (
 	"bytes"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/natesales/q/cli"
+	"github.com/natesales/q/transport"
 	"github.com/natesales/q/util"
 )
 
@@ -21,3 +25,209 @@ func TestOutputPrettyPrintColumn(t *testing.T) {
 	assert.Contains(t, buf.String(), `NS 86400 b.iana-servers.net.`)
 	assert.Contains(t, buf.String(), `TXT 86400 "v=spf1 -all"`)
 }
+
+func TestOutputPrettyPrintMeta(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	p := Printer{Out: &buf, Opts: &cli.Flags{ShowMeta: true}}
+	p.PrintPretty([]*Entry{{
+		Replies: replies()[:1],
+		Server:  "dns.example:853",
+		TLS:     &TLSInfo{ServerName: "dns.example", ALPN: "dot"},
+	}})
+	assert.Contains(t, buf.String(), "Meta:\nTLS SNI: dns.example ALPN: dot\n")
+
+	buf.Reset()
+	p.PrintPretty([]*Entry{{
+		Replies: replies()[:1],
+		Server:  "dns.example:443",
+		TLS:     &TLSInfo{ServerName: "dns.example", ALPN: "h2", Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256", Resumed: true},
+	}})
+	assert.Contains(t, buf.String(), "TLS SNI: dns.example ALPN: h2\nTLS version: TLS 1.3 Cipher: TLS_AES_128_GCM_SHA256 Resumed: true\n")
+}
+
+func TestOutputPrettyPrintColumnTTLHuman(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "column", TTLHuman: true}}
+	p.PrintColumn([]*Entry{{Replies: replies(), Server: "192.0.2.10"}})
+	assert.Contains(t, buf.String(), `A 24h 192.0.2.2`)
+}
+
+func TestOutputPrettyHighlightTTL(t *testing.T) {
+	util.UseColor = true
+	defer func() { util.UseColor = false }()
+
+	rr, err := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+	assert.Nil(t, err)
+
+	for _, tc := range []struct {
+		opts  cli.Flags
+		color string
+	}{
+		{cli.Flags{}, util.ColorGreen},
+		{cli.Flags{TTLAbove: 3600}, util.ColorGreen},
+		{cli.Flags{TTLAbove: 60}, util.ColorRed},
+		{cli.Flags{TTLBelow: 60}, util.ColorGreen},
+		{cli.Flags{TTLBelow: 3600}, util.ColorRed},
+		{cli.Flags{TTLAbove: 3600, TTLBelow: 60}, util.ColorGreen},
+	} {
+		e := &Entry{}
+		assert.Equal(t, util.Color(tc.color, "300"), e.parseRR(rr, &tc.opts).TTL)
+	}
+}
+
+func TestOutputPrettyValidation(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	reply := new(dns.Msg)
+	reply.SetQuestion("example.com.", dns.TypeA)
+	reply.AuthenticatedData = true
+	p := Printer{Out: &buf, Opts: &cli.Flags{AuthenticData: true}}
+	p.PrintPretty([]*Entry{{Replies: []*dns.Msg{reply}}})
+	assert.Equal(t, "AD: requested → set (validated)\n", buf.String())
+
+	buf.Reset()
+	reply.AuthenticatedData = false
+	p.PrintPretty([]*Entry{{Replies: []*dns.Msg{reply}}})
+	assert.Equal(t, "AD: requested → clear (not validated)\n", buf.String())
+
+	// Not shown unless AD was set on the query
+	buf.Reset()
+	p.Opts.AuthenticData = false
+	p.PrintPretty([]*Entry{{Replies: []*dns.Msg{reply}}})
+	assert.Empty(t, buf.String())
+}
+
+func TestOutputSortTTL(t *testing.T) {
+	util.UseColor = false
+	reply := func() *dns.Msg {
+		m := new(dns.Msg)
+		for _, s := range []string{
+			"example.com. 300 IN A 192.0.2.1",
+			"example.com. 60 IN TXT \"a\"",
+			"example.com. 3600 IN MX 0 .",
+			"example.com. 60 IN A 192.0.2.2",
+		} {
+			rr, err := dns.NewRR(s)
+			assert.Nil(t, err)
+			m.Answer = append(m.Answer, rr)
+		}
+		return m
+	}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "column", SortTTL: true}}
+	p.PrintColumn([]*Entry{{Replies: []*dns.Msg{reply()}}})
+	assert.Equal(t, "TXT 60   \"a\"\n  A 60   192.0.2.2\n  A 300  192.0.2.1\n MX 3600 0 .\n", buf.String())
+
+	buf.Reset()
+	p.Opts.Format = "json"
+	p.Opts.JSONCompact = true
+	p.PrintStructured([]*Entry{{Replies: []*dns.Msg{reply()}}})
+	out := buf.String()
+	assert.Less(t, strings.Index(out, `"a"`), strings.Index(out, "192.0.2.2"))
+	assert.Less(t, strings.Index(out, "192.0.2.2"), strings.Index(out, "192.0.2.1"))
+	assert.Less(t, strings.Index(out, "192.0.2.1"), strings.Index(out, `"type":"MX"`))
+}
+
+func TestOutputSortSections(t *testing.T) {
+	util.UseColor = false
+	reply := func() *dns.Msg {
+		m := new(dns.Msg)
+		for _, s := range []string{
+			"www.example.com. 300 IN A 192.0.2.2",
+			"example.com. 300 IN A 192.0.2.9",
+			"www.example.com. 300 IN A 192.0.2.1",
+			"Example.com. 300 IN MX 10 mail.example.com.",
+		} {
+			rr, err := dns.NewRR(s)
+			assert.Nil(t, err)
+			m.Answer = append(m.Answer, rr)
+		}
+		return m
+	}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "raw", Sort: true, ShowAnswer: true}}
+	p.PrintRaw([]*Entry{{Replies: []*dns.Msg{reply()}}})
+	out := buf.String()
+	assert.Less(t, strings.Index(out, "192.0.2.9"), strings.Index(out, "mail.example.com."))
+	assert.Less(t, strings.Index(out, "mail.example.com."), strings.Index(out, "192.0.2.1"))
+	assert.Less(t, strings.Index(out, "192.0.2.1"), strings.Index(out, "192.0.2.2"))
+
+	buf.Reset()
+	p.Opts.Format = "json"
+	p.Opts.JSONCompact = true
+	p.PrintStructured([]*Entry{{Replies: []*dns.Msg{reply()}}})
+	out = buf.String()
+	assert.Less(t, strings.Index(out, "192.0.2.9"), strings.Index(out, "mail.example.com."))
+	assert.Less(t, strings.Index(out, "192.0.2.1"), strings.Index(out, "192.0.2.2"))
+
+	// Without --sort, the order of the reply is kept
+	buf.Reset()
+	p.Opts.Sort = false
+	p.PrintStructured([]*Entry{{Replies: []*dns.Msg{reply()}}})
+	out = buf.String()
+	assert.Less(t, strings.Index(out, "192.0.2.2"), strings.Index(out, "192.0.2.9"))
+
+	// OPT and TSIG records are left at the end of the additional section, and the entry keeps the reply as received
+	m := reply()
+	for _, s := range []string{"z.example.com. 300 IN A 192.0.2.3", "a.example.com. 300 IN A 192.0.2.4"} {
+		rr, err := dns.NewRR(s)
+		assert.Nil(t, err)
+		m.Extra = append(m.Extra, rr)
+	}
+	m.SetEdns0(1232, false)
+	m.Extra = append(m.Extra, &dns.TSIG{Hdr: dns.RR_Header{Name: "key.", Rrtype: dns.TypeTSIG, Class: dns.ClassANY}, Algorithm: dns.HmacSHA256})
+	extraTypes := func(m *dns.Msg) []string {
+		var types []string
+		for _, rr := range m.Extra {
+			types = append(types, rr.Header().Name+" "+dns.TypeToString[rr.Header().Rrtype])
+		}
+		return types
+	}
+
+	entry := &Entry{Replies: []*dns.Msg{m}}
+	p.Opts.Sort = true
+	sorted := p.sortEntries([]*Entry{entry})
+	assert.Equal(t, []string{"a.example.com. A", "z.example.com. A", ". OPT", "key. TSIG"}, extraTypes(sorted[0].Replies[0]))
+	assert.Equal(t, []string{"z.example.com. A", "a.example.com. A", ". OPT", "key. TSIG"}, extraTypes(entry.Replies[0]))
+	assert.Equal(t, "192.0.2.2", entry.Replies[0].Answer[0].(*dns.A).A.String())
+}
+
+func TestOutputPrettyVerboseTiming(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	e := &Entry{
+		Replies: replies()[:1],
+		Timings: []transport.Timings{{Connect: time.Millisecond, Handshake: 2 * time.Millisecond, FirstByte: 3 * time.Millisecond, Total: 4 * time.Millisecond}},
+	}
+	p := Printer{Out: &buf, Opts: &cli.Flags{ShowTimings: true}}
+	p.PrintPretty([]*Entry{e})
+	assert.Contains(t, buf.String(), "Timings:\nFirst byte 3ms Total 4ms\n")
+
+	buf.Reset()
+	p.Opts.VerboseTiming = true
+	p.PrintPretty([]*Entry{e})
+	assert.Contains(t, buf.String(), "Timings:\nConnect 1ms Handshake 2ms First byte 3ms Total 4ms\n")
+}
+
+func TestOutputPrettyHeader(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	reply := new(dns.Msg)
+	reply.SetQuestion("example.com.", dns.TypeA)
+	reply.Response, reply.Authoritative, reply.RecursionAvailable = true, true, true
+	rr, err := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+	assert.Nil(t, err)
+	reply.Answer = append(reply.Answer, rr)
+	p := Printer{Out: &buf, Opts: &cli.Flags{ShowHeader: true}}
+	p.PrintPretty([]*Entry{{Replies: []*dns.Msg{reply}}})
+	assert.Equal(t, ";; flags: qr aa rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 0\n", buf.String())
+
+	buf.Reset()
+	reply.SetEdns0(1232, true)
+	p.PrintPretty([]*Entry{{Replies: []*dns.Msg{reply}}})
+	assert.Equal(t, ";; flags: qr aa rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 1\n;; EDNS: version: 0, flags: do; udp: 1232\n", buf.String())
+}
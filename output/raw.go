@@ -25,7 +25,11 @@ func rrSection(s, label string, rrs []dns.RR) string {
 
 // PrintRaw a slice of entries in raw (dig-style) format
 func (p Printer) PrintRaw(entries []*Entry) {
+	entries = p.sortEntries(entries)
 	for _, entry := range entries {
+		if entry.Error != "" {
+			util.MustWritef(p.Out, ";; error from %s: %s\n", entry.Server, entry.Error)
+		}
 		for i, reply := range entry.Replies {
 			s := reply.MsgHdr.String() + " "
 			s += "QUERY: " + strconv.Itoa(len(reply.Question)) + ", "
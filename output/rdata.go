@@ -0,0 +1,153 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"unicode"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+// Record is a resource record in structured output, with its rdata fields in a typed object keyed by snake_case field
+// name (e.g. {"preference": 10, "mx": "mail.example.com."} for an MX record)
+type Record struct {
+	Name  string `json:"name" yaml:"name"`
+	TTL   uint32 `json:"ttl" yaml:"ttl"`
+	Class string `json:"class" yaml:"class"`
+	Type  string `json:"type" yaml:"type"`
+	Rdata Rdata  `json:"rdata" yaml:"rdata"`
+}
+
+// RdataField is a single named field of a record's rdata
+type RdataField struct {
+	Name  string
+	Value any
+}
+
+// Rdata is the rdata of a record, marshaled as an object whose keys are in the order of the record's fields
+type Rdata []RdataField
+
+// MarshalJSON encodes rdata as a JSON object
+func (r Rdata) MarshalJSON() ([]byte, error) {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, field := range r {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// MarshalYAML encodes rdata as a YAML mapping
+func (r Rdata) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range r {
+		var value yaml.Node
+		if err := value.Encode(field.Value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.Name}, &value)
+	}
+	return node, nil
+}
+
+// snakeCase converts a Go field name to snake_case, keeping acronyms together (e.g. PublicKey -> public_key,
+// AAAA -> aaaa, SOAMinTTL -> soa_min_ttl)
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// rdataValue converts an rdata field to a value that marshals the same way in JSON and YAML
+func rdataValue(v reflect.Value) any {
+	switch v := v.Interface().(type) {
+	case net.IP:
+		return v.String()
+	case []dns.SVCBKeyValue:
+		params := make(map[string]string, len(v))
+		for _, kv := range v {
+			params[kv.Key().String()] = kv.String()
+		}
+		return params
+	case []dns.EDNS0:
+		var options []string
+		for _, o := range v {
+			options = append(options, o.String())
+		}
+		return options
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v.Interface()
+}
+
+// addRdata appends the exported fields of an RR struct other than its header to its rdata, flattening embedded structs
+// (e.g. the DS in a CDS)
+func addRdata(rdata *Rdata, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Type == reflect.TypeOf(dns.RR_Header{}) {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addRdata(rdata, v.Field(i))
+			continue
+		}
+		*rdata = append(*rdata, RdataField{Name: snakeCase(field.Name), Value: rdataValue(v.Field(i))})
+	}
+}
+
+// record converts an RR to a Record
+func record(rr dns.RR) Record {
+	r := Record{
+		Name:  rr.Header().Name,
+		TTL:   rr.Header().Ttl,
+		Class: className(rr.Header().Class),
+		Type:  dns.TypeToString[rr.Header().Rrtype],
+		Rdata: Rdata{},
+	}
+	if v := reflect.ValueOf(rr); v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct {
+		addRdata(&r.Rdata, v.Elem())
+	}
+	return r
+}
+
+// records converts a section of a message to Records, leaving out OPT records since they're decoded separately
+func records(rrs []dns.RR) []Record {
+	section := []Record{}
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeOPT {
+			continue
+		}
+		section = append(section, record(rr))
+	}
+	return section
+}
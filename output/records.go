@@ -0,0 +1,118 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// algorithmName returns the name of a DNSSEC algorithm
+func algorithmName(alg uint8) string {
+	if s, ok := dns.AlgorithmToString[alg]; ok {
+		return s
+	}
+	return strconv.Itoa(int(alg))
+}
+
+// digestName returns the name of a DS digest type
+func digestName(digestType uint8) string {
+	if s, ok := dns.HashToString[digestType]; ok {
+		return s
+	}
+	return strconv.Itoa(int(digestType))
+}
+
+// findDNSKEY returns the DNSKEY with a given owner name and key tag from any of an entry's replies
+func (e *Entry) findDNSKEY(name string, keyTag uint16) *dns.DNSKEY {
+	for _, reply := range e.Replies {
+		for _, section := range [][]dns.RR{reply.Answer, reply.Ns, reply.Extra} {
+			for _, rr := range section {
+				if key, ok := rr.(*dns.DNSKEY); ok && strings.EqualFold(key.Hdr.Name, name) && key.KeyTag() == keyTag {
+					return key
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// prettyDS renders a DS record with algorithm and digest type names, checking the digest against a matching DNSKEY if one is present
+func (e *Entry) prettyDS(ds *dns.DS) string {
+	val := fmt.Sprintf("%d %s %s %s", ds.KeyTag, algorithmName(ds.Algorithm), digestName(ds.DigestType), strings.ToUpper(ds.Digest))
+
+	if key := e.findDNSKEY(ds.Hdr.Name, ds.KeyTag); key != nil {
+		if computed := key.ToDS(ds.DigestType); computed != nil && strings.EqualFold(computed.Digest, ds.Digest) {
+			val += util.Color(util.ColorGreen, " (matches DNSKEY)")
+		} else {
+			val += util.Color(util.ColorRed, " (DNSKEY digest mismatch)")
+		}
+	}
+
+	return val
+}
+
+// prettyCDS renders a CDS record like a DS, or as a delete request if it uses algorithm 0 (RFC 8078 section 4)
+func (e *Entry) prettyCDS(cds *dns.CDS) string {
+	if cds.Algorithm == 0 {
+		return util.Color(util.ColorRed, "delete DS (RFC 8078)")
+	}
+	return e.prettyDS(&cds.DS)
+}
+
+// prettyCDNSKEY renders a CDNSKEY record with its key tag and algorithm name, or as a delete request if it uses algorithm 0
+func prettyCDNSKEY(key *dns.CDNSKEY) string {
+	if key.Algorithm == 0 {
+		return util.Color(util.ColorRed, "delete DS (RFC 8078)")
+	}
+	return fmt.Sprintf("%d %d %s %s (key tag %d)", key.Flags, key.Protocol, algorithmName(key.Algorithm), key.PublicKey, key.KeyTag())
+}
+
+// prettyURI renders a URI record (RFC 7553) with aligned priority and weight and an unquoted target
+func prettyURI(uri *dns.URI) string {
+	return fmt.Sprintf("%-5d %-5d %s", uri.Priority, uri.Weight, util.Color(util.ColorTeal, uri.Target))
+}
+
+// prettyValue returns a human readable rendering of an RR's rdata for record types that have one
+func (e *Entry) prettyValue(rr dns.RR) (string, bool) {
+	switch rr := rr.(type) {
+	case *dns.DS:
+		return e.prettyDS(rr), true
+	case *dns.CDS:
+		return e.prettyCDS(rr), true
+	case *dns.CDNSKEY:
+		return prettyCDNSKEY(rr), true
+	case *dns.URI:
+		return prettyURI(rr), true
+	case *dns.LOC:
+		return prettyLOC(rr), true
+	case *dns.NAPTR:
+		return prettyNAPTR(rr), true
+	case *dns.CSYNC:
+		return prettyCSYNC(rr), true
+	case *dns.SSHFP:
+		return e.prettySSHFP(rr), true
+	case *dns.SVCB:
+		return prettySVCB(rr), true
+	case *dns.HTTPS:
+		return prettySVCB(&rr.SVCB), true
+	case *dns.OPENPGPKEY:
+		return prettyOpenPGPKey(rr)
+	case *dns.SMIMEA:
+		return prettySMIMEA(rr)
+	case *dns.HINFO:
+		return prettyHINFO(rr), true
+	case *dns.RP:
+		return prettyRP(rr), true
+	case *dns.AFSDB:
+		return prettyAFSDB(rr), true
+	case *dns.X25:
+		return prettyX25(rr), true
+	case *dns.ISDN:
+		return prettyISDN(rr), true
+	}
+	return "", false
+}
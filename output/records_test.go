@@ -0,0 +1,295 @@
+package output
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+// signingKey generates a DNSKEY for testing
+func signingKey(t *testing.T) *dns.DNSKEY {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	_, err := key.Generate(256)
+	assert.Nil(t, err)
+	return key
+}
+
+func TestOutputPrettyDS(t *testing.T) {
+	util.UseColor = false
+	key := signingKey(t)
+	ds := key.ToDS(dns.SHA256)
+
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{ds}}}}
+	val, ok := e.prettyValue(ds)
+	assert.True(t, ok)
+	assert.Regexp(t, `^\d+ ECDSAP256SHA256 SHA256 [0-9A-F]+$`, val)
+
+	e.Replies = append(e.Replies, &dns.Msg{Answer: []dns.RR{key}})
+	val, _ = e.prettyValue(ds)
+	assert.Contains(t, val, "(matches DNSKEY)")
+
+	ds.Digest = strings.Repeat("0", len(ds.Digest))
+	val, _ = e.prettyValue(ds)
+	assert.Contains(t, val, "(DNSKEY digest mismatch)")
+}
+
+func TestOutputPrettyCDS(t *testing.T) {
+	util.UseColor = false
+	key := signingKey(t)
+	cds := &dns.CDS{DS: *key.ToDS(dns.SHA256)}
+	cds.Hdr.Rrtype = dns.TypeCDS
+
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{cds, key}}}}
+	val, ok := e.prettyValue(cds)
+	assert.True(t, ok)
+	assert.Regexp(t, `^\d+ ECDSAP256SHA256 SHA256 [0-9A-F]+ \(matches DNSKEY\)$`, val)
+
+	cdnskey := &dns.CDNSKEY{DNSKEY: *key}
+	cdnskey.Hdr.Rrtype = dns.TypeCDNSKEY
+	val, ok = e.prettyValue(cdnskey)
+	assert.True(t, ok)
+	assert.Contains(t, val, "257 3 ECDSAP256SHA256")
+	assert.Contains(t, val, fmt.Sprintf("(key tag %d)", key.KeyTag()))
+
+	val, _ = e.prettyValue(&dns.CDS{DS: dns.DS{Hdr: dns.RR_Header{Rrtype: dns.TypeCDS}, DigestType: 0, Digest: "00"}})
+	assert.Equal(t, "delete DS (RFC 8078)", val)
+}
+
+func TestOutputPrettyURI(t *testing.T) {
+	util.UseColor = false
+	rr, err := dns.NewRR(`_http._tcp.example.com. 3600 IN URI 10 1 "https://www.example.com/path"`)
+	assert.Nil(t, err)
+
+	e := &Entry{}
+	val, ok := e.prettyValue(rr)
+	assert.True(t, ok)
+	assert.Equal(t, "10    1     https://www.example.com/path", val)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}})
+	assert.Contains(t, buf.String(), `"priority":10,"weight":1,"target":"https://www.example.com/path"`)
+}
+
+func TestOutputPrettyLOC(t *testing.T) {
+	util.UseColor = false
+	rr, err := dns.NewRR("example.com. 3600 IN LOC 52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m")
+	assert.Nil(t, err)
+
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}
+	val, ok := e.prettyValue(rr)
+	assert.True(t, ok)
+	assert.Equal(t, `52°22'23.000"N 4°53'32.000"E -2.00m (52.373056, 4.892222)`, val)
+
+	rr, err = dns.NewRR("example.com. 3600 IN LOC 33 51 35.9 S 151 12 40.0 W 10m")
+	assert.Nil(t, err)
+	val, _ = e.prettyValue(rr)
+	assert.Equal(t, `33°51'35.900"S 151°12'40.000"W 10.00m (-33.859972, -151.211111)`, val)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{e})
+	assert.Contains(t, buf.String(), `"type":"LOC","rdata":{"version":0,"size":0,"horiz_pre":22,"vert_pre":19,"latitude":2336026648,"longitude":2165095648,"altitude":9999800}`)
+}
+
+func TestOutputPrettySSHFP(t *testing.T) {
+	util.UseColor = false
+
+	// SSH wire format of an Ed25519 public key: string "ssh-ed25519", string key
+	blob := append([]byte("\x00\x00\x00\x0bssh-ed25519\x00\x00\x00\x20"), bytes.Repeat([]byte{0x42}, 32)...)
+	sum := sha256.Sum256(blob)
+	fp := &dns.SSHFP{
+		Hdr:         dns.RR_Header{Name: "host.example.com.", Rrtype: dns.TypeSSHFP, Class: dns.ClassINET, Ttl: 3600},
+		Algorithm:   4,
+		Type:        2,
+		FingerPrint: hex.EncodeToString(sum[:]),
+	}
+
+	e := &Entry{}
+	val, ok := e.prettyValue(fp)
+	assert.True(t, ok)
+	assert.Equal(t, "Ed25519 SHA-256 "+strings.ToUpper(fp.FingerPrint), val)
+
+	keyFile := filepath.Join(t.TempDir(), "ssh_host_ed25519_key.pub")
+	assert.Nil(t, os.WriteFile(keyFile, []byte("ssh-ed25519 "+base64.StdEncoding.EncodeToString(blob)+" root@host\n"), 0644))
+	e.SSHKeys, _ = ReadSSHKeys(keyFile)
+	assert.Len(t, e.SSHKeys, 1)
+	val, _ = e.prettyValue(fp)
+	assert.True(t, strings.HasSuffix(val, " (matches host key)"))
+
+	// known_hosts lines start with the host name
+	other := append([]byte("\x00\x00\x00\x0bssh-ed25519\x00\x00\x00\x20"), bytes.Repeat([]byte{0x43}, 32)...)
+	assert.Nil(t, os.WriteFile(keyFile, []byte("host.example.com ssh-ed25519 "+base64.StdEncoding.EncodeToString(other)+"\n"), 0644))
+	e.SSHKeys, _ = ReadSSHKeys(keyFile)
+	assert.Len(t, e.SSHKeys, 1)
+	val, _ = e.prettyValue(fp)
+	assert.True(t, strings.HasSuffix(val, " (no matching host key)"))
+
+	_, err := ReadSSHKeys(filepath.Join(t.TempDir(), "missing"))
+	assert.NotNil(t, err)
+}
+
+func TestOutputPrettyCSYNC(t *testing.T) {
+	rr, err := dns.NewRR("example.com. 3600 IN CSYNC 2024010101 3 A NS AAAA")
+	assert.Nil(t, err)
+
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}
+	val, ok := e.prettyValue(rr)
+	assert.True(t, ok)
+	assert.Equal(t, "serial 2024010101 flags immediate,soaminimum types A NS AAAA", val)
+
+	val, _ = e.prettyValue(&dns.CSYNC{Hdr: dns.RR_Header{Rrtype: dns.TypeCSYNC}, Serial: 1, Flags: 4})
+	assert.Equal(t, "serial 1 flags 0x0004", val)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{e})
+	assert.Contains(t, buf.String(), `"type":"CSYNC","rdata":{"serial":2024010101,"flags":3,"type_bit_map":[1,2,28]}`)
+}
+
+func TestOutputPrettyNAPTR(t *testing.T) {
+	util.UseColor = false
+	rr, err := dns.NewRR(`4.3.2.1.5.5.5.0.0.8.1.e164.arpa. 3600 IN NAPTR 100 10 "u" "E2U+sip" "!^.*$!sip:info@example.com!" .`)
+	assert.Nil(t, err)
+
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}
+	val, ok := e.prettyValue(rr)
+	assert.True(t, ok)
+	assert.Equal(t, `order 100 pref 10 flags "u" service "E2U+sip" regexp ^.*$ → sip:info@example.com`, val)
+
+	rr, err = dns.NewRR(`example.com. 3600 IN NAPTR 10 0 "s" "SIP+D2U" "" _sip._udp.example.com.`)
+	assert.Nil(t, err)
+	val, _ = e.prettyValue(rr)
+	assert.Equal(t, `order 10 pref 0 flags "s" service "SIP+D2U" replacement _sip._udp.example.com.`, val)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{e})
+	assert.Contains(t, buf.String(), `"type":"NAPTR","rdata":{"order":100,"preference":10,"flags":"u","service":"E2U+sip","regexp":"!^.*$!sip:info@example.com!","replacement":"."}`)
+}
+
+func TestOutputPrettySVCB(t *testing.T) {
+	util.UseColor = false
+	rr, err := dns.NewRR(`example.com. 300 IN HTTPS 1 . alpn="h3,h2" port=8443 ipv4hint="192.0.2.1,192.0.2.2" ipv6hint="2001:db8::1" ech="AEX+DQBB" key65000="\001\002"`)
+	assert.Nil(t, err)
+
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}
+	val, ok := e.prettyValue(rr)
+	assert.True(t, ok)
+	assert.Equal(t, "1 .\n    alpn=h3,h2\n    port=8443\n    ipv4hint=192.0.2.1,192.0.2.2\n    ipv6hint=2001:db8::1\n    ech=AEX+DQBB\n    key65000=0102", val)
+
+	rr, err = dns.NewRR(`_dns.example.com. 300 IN SVCB 0 svc.example.net.`)
+	assert.Nil(t, err)
+	val, _ = e.prettyValue(rr)
+	assert.Equal(t, "0 svc.example.net. (alias)", val)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{e})
+	assert.Contains(t, buf.String(), `"type":"HTTPS","rdata":{"priority":1,"target":".","value":{"alpn":"h3,h2","ech":"AEX+DQBB","ipv4hint":"192.0.2.1,192.0.2.2","ipv6hint":"2001:db8::1","key65000":"\\001\\002","port":"8443"}}`)
+}
+
+func TestOutputPrettyOpenPGPKey(t *testing.T) {
+	util.UseColor = false
+	// Ed25519 key for "Alice <alice@example.com>" created 2024-01-01 and expiring after 2 years
+	rr := &dns.OPENPGPKEY{
+		Hdr:       dns.RR_Header{Name: "2bd806c97f0e00af1a1fc3328fa763a9269723c8db8fac4f93af71db._openpgpkey.example.com.", Rrtype: dns.TypeOPENPGPKEY, Class: dns.ClassINET, Ttl: 3600},
+		PublicKey: "mDMEZZIAgBYJKwYBBAHaRw8BAQdAdZh2CKn80sBZzayZIUDoeuO26r620+OkRl9VPYAhD2S0GUFsaWNlIDxhbGljZUBleGFtcGxlLmNvbT6IlgQTFggAPhYhBEyuWaWHA0rKZTgergPi8ml75aXlBQJlkgCAAhsDBQkDwmcABQsJCAcCBhUKCQgLAgQWAgMBAh4BAheAAAoJEAPi8ml75aXlth4BAM6Xmp3PgX9d324frB2g/MmBPNd/OGbfO3HPhRC2QRvhAQDHTdMiFPxnbEJzjZBMIvZkyN6vBk5t6h9S9XNPCMj/BQ==",
+	}
+
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}
+	val, ok := e.prettyValue(rr)
+	assert.True(t, ok)
+	assert.Equal(t, "EdDSA Ed25519 key 0x03E2F2697BE5A5E5 created 2024-01-01 expired 2025-12-31 Alice <alice@example.com>", val)
+
+	// Undecodable keys fall back to the raw record
+	_, ok = e.prettyValue(&dns.OPENPGPKEY{Hdr: rr.Hdr, PublicKey: "AAAA"})
+	assert.False(t, ok)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{e})
+	assert.Contains(t, buf.String(), `"type":"OPENPGPKEY","rdata":{"public_key":"`+rr.PublicKey+`"}`)
+}
+
+func TestOutputPrettySMIMEA(t *testing.T) {
+	util.UseColor = false
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: "Alice"},
+		EmailAddresses: []string{"alice@example.com"},
+		NotBefore:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.Nil(t, err)
+
+	hdr := dns.RR_Header{Name: "_smimecert.example.com.", Rrtype: dns.TypeSMIMEA, Class: dns.ClassINET, Ttl: 3600}
+	rr := &dns.SMIMEA{Hdr: hdr, Usage: 3, Selector: 0, MatchingType: 0, Certificate: hex.EncodeToString(der)}
+	e := &Entry{Replies: []*dns.Msg{{Answer: []dns.RR{rr}}}}
+	val, ok := e.prettyValue(rr)
+	assert.True(t, ok)
+	assert.Equal(t, "DANE-EE Cert Full Ed25519 cert CN=Alice issued by CN=Alice alice@example.com valid 2024-01-01 → 2025-01-01 (not currently valid)", val)
+
+	cert, _ := x509.ParseCertificate(der)
+	val, _ = e.prettyValue(&dns.SMIMEA{Hdr: hdr, Usage: 3, Selector: 1, MatchingType: 0, Certificate: hex.EncodeToString(cert.RawSubjectPublicKeyInfo)})
+	assert.Equal(t, "DANE-EE SPKI Full ed25519.PublicKey public key", val)
+
+	sum := sha256.Sum256(der)
+	val, _ = e.prettyValue(&dns.SMIMEA{Hdr: hdr, Usage: 3, Selector: 0, MatchingType: 1, Certificate: hex.EncodeToString(sum[:])})
+	assert.Equal(t, "DANE-EE Cert SHA2-256 "+hex.EncodeToString(sum[:]), val)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{e})
+	assert.Contains(t, buf.String(), `"type":"SMIMEA","rdata":{"usage":3,"selector":0,"matching_type":0,"certificate":"`+rr.Certificate+`"}`)
+}
+
+func TestOutputPrettyLegacyTypes(t *testing.T) {
+	util.UseColor = false
+	e := &Entry{}
+	for _, tc := range []struct {
+		rr  string
+		val string
+	}{
+		{`example.com. 3600 IN HINFO "INTEL-386" "Windows NT"`, `cpu "INTEL-386" os "Windows NT"`},
+		{`example.com. 3600 IN RP john\.doe.example.com. info.example.com.`, `mailbox john.doe@example.com txt info.example.com.`},
+		{`example.com. 3600 IN RP hostmaster.example.com. .`, `mailbox hostmaster@example.com`},
+		{`example.com. 3600 IN AFSDB 1 afsdb.example.com.`, `subtype 1 (AFS cell database server) host afsdb.example.com.`},
+		{`example.com. 3600 IN AFSDB 3 afsdb.example.com.`, `subtype 3 (unknown) host afsdb.example.com.`},
+		{`example.com. 3600 IN X25 311061700956`, `psdn address 311061700956`},
+		{`example.com. 3600 IN ISDN 150862028003217 004`, `address 150862028003217 subaddress 004`},
+		{`example.com. 3600 IN ISDN 150862028003217`, `address 150862028003217`},
+	} {
+		rr, err := dns.NewRR(tc.rr)
+		if assert.Nil(t, err, tc.rr) {
+			val, ok := e.prettyValue(rr)
+			assert.True(t, ok)
+			assert.Equal(t, tc.val, val)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/natesales/q/util"
+)
+
+// ReplayResult stores how the response to a query replayed from a capture compares to the captured response
+type ReplayResult struct {
+	Question   string `json:"question" yaml:"question"`
+	Rcode      string `json:"rcode,omitempty" yaml:"rcode,omitempty"` // Rcode of the replayed response
+	Match      bool   `json:"match" yaml:"match"`
+	Difference string `json:"difference,omitempty" yaml:"difference,omitempty"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// PrintReplay prints whether each replayed response matched the captured one and how many matched in total
+func (p Printer) PrintReplay(results []ReplayResult) {
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(results)
+		return
+	}
+
+	var matched int
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			util.MustWritef(p.Out, "%s %s %s\n", util.Color(util.ColorYellow, "?"), r.Question, util.Color(util.ColorYellow, r.Error))
+		case r.Match:
+			matched++
+			util.MustWritef(p.Out, "%s %s %s\n", util.Color(util.ColorGreen, "✓"), r.Question, r.Rcode)
+		default:
+			util.MustWritef(p.Out, "%s %s %s\n", util.Color(util.ColorRed, "✗"), r.Question, util.Color(util.ColorRed, r.Difference))
+		}
+	}
+
+	util.MustWritef(p.Out, "%s replayed responses matched the capture\n",
+		util.Color(util.ColorGreen, fmt.Sprintf("%d/%d", matched, len(results))),
+	)
+}
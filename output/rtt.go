@@ -0,0 +1,94 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// ServerRTT summarizes the replies from a single server
+type ServerRTT struct {
+	Server  string        `json:"server" yaml:"server"`
+	Rcode   string        `json:"rcode" yaml:"rcode"`
+	Answers int           `json:"answers" yaml:"answers"`
+	RTT     time.Duration `json:"rtt" yaml:"rtt"`
+	Agrees  bool          `json:"agrees" yaml:"agrees"` // Whether the answers match the most common answer set
+}
+
+// serverRTTs summarizes each entry, sorted by RTT
+func serverRTTs(entries []*Entry) []ServerRTT {
+	var majority string
+	if groups := groupByAnswers(entries); len(groups) > 0 {
+		majority = strings.Join(groups[0].Answers, "\n")
+	}
+
+	var rows []ServerRTT
+	for _, e := range entries {
+		var rcodes []string
+		answers := 0
+		for _, reply := range e.Replies {
+			if rcode := dns.RcodeToString[reply.Rcode]; !slices.Contains(rcodes, rcode) {
+				rcodes = append(rcodes, rcode)
+			}
+			answers += len(reply.Answer)
+		}
+		rows = append(rows, ServerRTT{
+			Server:  e.Server,
+			Rcode:   strings.Join(rcodes, ","),
+			Answers: answers,
+			RTT:     e.Time,
+			Agrees:  strings.Join(AnswerSet(e.Replies), "\n") == majority,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].RTT < rows[j].RTT
+	})
+	return rows
+}
+
+// PrintRTTTable prints a table of each server's rcode, answer count, and RTT, fastest first
+func (p Printer) PrintRTTTable(entries []*Entry) {
+	rows := serverRTTs(entries)
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(rows)
+		return
+	}
+
+	table := [][]string{{"Server", "Rcode", "Answers", "RTT"}}
+	for _, r := range rows {
+		table = append(table, []string{r.Server, r.Rcode, strconv.Itoa(r.Answers), r.RTT.Round(10 * time.Microsecond).String()})
+	}
+
+	widths := make([]int, len(table[0]))
+	for _, row := range table {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	for i, row := range table {
+		var line string
+		for j, col := range row {
+			line += fmt.Sprintf("%-*s ", widths[j], col)
+		}
+		if i == 0 {
+			util.MustWriteln(p.Out, util.Color(util.ColorWhite, line+"Answer set"))
+			continue
+		}
+
+		if rows[i-1].Agrees {
+			line += util.Color(util.ColorGreen, "agrees")
+		} else {
+			line += util.Color(util.ColorRed, "differs")
+		}
+		util.MustWriteln(p.Out, line)
+	}
+}
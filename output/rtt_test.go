@@ -0,0 +1,32 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputPrintRTTTable(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+
+	slow := answerEntry("192.0.2.10", "example.com. 300 IN A 192.0.2.1")
+	slow.Time = 30 * time.Millisecond
+	fast := answerEntry("192.0.2.11", "example.com. 60 IN A 192.0.2.1")
+	fast.Time = 5 * time.Millisecond
+	different := answerEntry("192.0.2.12", "example.com. 60 IN A 192.0.2.2")
+	different.Time = 10 * time.Millisecond
+
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+	p.PrintRTTTable([]*Entry{slow, fast, different})
+	assert.Equal(t, `Server     Rcode   Answers RTT  Answer set
+192.0.2.11 NOERROR 1       5ms  agrees
+192.0.2.12 NOERROR 1       10ms differs
+192.0.2.10 NOERROR 1       30ms agrees
+`, buf.String())
+}
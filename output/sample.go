@@ -0,0 +1,76 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// LatencySample is the result of a single query taken with --roundtrip-over-time
+type LatencySample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Server    string    `json:"server"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Rcode     string    `json:"rcode,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// sampleHeader is the CSV header row of a latency series
+var sampleHeader = []string{"timestamp", "server", "name", "type", "rcode", "latency_ms", "error"}
+
+// SampleWriter writes latency samples as CSV or NDJSON, one line per sample as it's taken
+type SampleWriter struct {
+	out    io.Writer
+	csv    *csv.Writer // nil for NDJSON
+	header bool
+}
+
+// NewSampleWriter creates a writer for a latency series in the given format (csv or ndjson)
+func NewSampleWriter(out io.Writer, format string) (*SampleWriter, error) {
+	switch format {
+	case "csv":
+		return &SampleWriter{out: out, csv: csv.NewWriter(out)}, nil
+	case "ndjson":
+		return &SampleWriter{out: out}, nil
+	default:
+		return nil, fmt.Errorf("invalid sample format %s (expected csv or ndjson)", format)
+	}
+}
+
+// Write writes a single sample, preceded by the header row on the first CSV sample
+func (w *SampleWriter) Write(s LatencySample) error {
+	if w.csv == nil {
+		b, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(s)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "%s\n", b)
+		return err
+	}
+
+	if !w.header {
+		if err := w.csv.Write(sampleHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	if err := w.csv.Write([]string{
+		s.Timestamp.Format(time.RFC3339Nano),
+		s.Server,
+		s.Name,
+		s.Type,
+		s.Rcode,
+		strconv.FormatFloat(s.LatencyMs, 'f', 3, 64),
+		s.Error,
+	}); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
@@ -0,0 +1,119 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/transport"
+)
+
+// SavedEntry is an entry saved with --save, with its queries and replies in DNS wire format (base64 encoded in JSON)
+// so that --replay prints them exactly as they were received
+type SavedEntry struct {
+	Server          string              `json:"server"`
+	Transport       string              `json:"transport,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	Time            time.Duration       `json:"time"`
+	Durations       []time.Duration     `json:"durations,omitempty"`
+	Timings         []transport.Timings `json:"timings,omitempty"`
+	TLS             *TLSInfo            `json:"tls,omitempty"`
+	PTRs            map[string]string   `json:"ptrs,omitempty"`
+	Inconsistencies []Inconsistency     `json:"inconsistencies,omitempty"`
+	Zone            *ZoneCut            `json:"zone,omitempty"`
+	Queries         [][]byte            `json:"queries"`
+	Replies         [][]byte            `json:"replies"`
+}
+
+// SaveEntries writes entries with their queries, replies, server, and timing to a JSON file
+func SaveEntries(path string, entries []*Entry) error {
+	saved := make([]SavedEntry, 0, len(entries))
+	for _, e := range entries {
+		s := SavedEntry{
+			Server:          e.Server,
+			Transport:       e.Transport,
+			Error:           e.Error,
+			Time:            e.Time,
+			Durations:       e.Durations,
+			Timings:         e.Timings,
+			TLS:             e.TLS,
+			PTRs:            e.PTRs,
+			Inconsistencies: e.Inconsistencies,
+			Zone:            e.Zone,
+			Queries:         [][]byte{},
+			Replies:         [][]byte{},
+		}
+		for i := range e.Queries {
+			b, err := e.Queries[i].Pack()
+			if err != nil {
+				return fmt.Errorf("packing query to %s: %s", e.Server, err)
+			}
+			s.Queries = append(s.Queries, b)
+		}
+		for _, reply := range e.Replies {
+			b, err := reply.Pack()
+			if err != nil {
+				return fmt.Errorf("packing response from %s: %s", e.Server, err)
+			}
+			s.Replies = append(s.Replies, b)
+		}
+		saved = append(saved, s)
+	}
+
+	b, err := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling saved responses: %s", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing saved responses: %s", err)
+	}
+	return nil
+}
+
+// LoadEntries reads the entries saved to a JSON file by SaveEntries
+func LoadEntries(path string) ([]*Entry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading saved responses: %s", err)
+	}
+	var saved []SavedEntry
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(b, &saved); err != nil {
+		return nil, fmt.Errorf("parsing saved responses in %s: %s", path, err)
+	}
+
+	entries := make([]*Entry, 0, len(saved))
+	for _, s := range saved {
+		e := &Entry{
+			Server:          s.Server,
+			Transport:       s.Transport,
+			Error:           s.Error,
+			Time:            s.Time,
+			Durations:       s.Durations,
+			Timings:         s.Timings,
+			TLS:             s.TLS,
+			PTRs:            s.PTRs,
+			Inconsistencies: s.Inconsistencies,
+			Zone:            s.Zone,
+		}
+		for _, b := range s.Queries {
+			var msg dns.Msg
+			if err := msg.Unpack(b); err != nil {
+				return nil, fmt.Errorf("unpacking saved query to %s: %s", s.Server, err)
+			}
+			// Queries are copies when sent, so copy them again for their empty sections to match
+			e.Queries = append(e.Queries, *msg.Copy())
+		}
+		for _, b := range s.Replies {
+			msg := new(dns.Msg)
+			if err := msg.Unpack(b); err != nil {
+				return nil, fmt.Errorf("unpacking saved response from %s: %s", s.Server, err)
+			}
+			e.Replies = append(e.Replies, msg)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
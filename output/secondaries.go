@@ -0,0 +1,67 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/natesales/q/util"
+)
+
+// SecondaryStatus stores the SOA serial and EDNS0 expire timer reported by a single authoritative server
+type SecondaryStatus struct {
+	Nameserver     string  `json:"nameserver" yaml:"nameserver"`
+	Address        string  `json:"address" yaml:"address"`
+	Serial         uint32  `json:"serial" yaml:"serial"`
+	Expire         *uint32 `json:"expire" yaml:"expire"` // Seconds, nil if the server didn't return an EXPIRE option
+	SerialMismatch bool    `json:"serial_mismatch" yaml:"serial_mismatch"`
+	Error          string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// PrintSecondaries prints a table of SOA serials and expire timers per authoritative server
+func (p Printer) PrintSecondaries(statuses []SecondaryStatus) {
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(statuses)
+		return
+	}
+
+	rows := [][]string{{"Nameserver", "Address", "Serial", "Expire"}}
+	for _, s := range statuses {
+		if s.Error != "" {
+			rows = append(rows, []string{s.Nameserver, s.Address, "-", "-"})
+			continue
+		}
+		expire := "not returned"
+		if s.Expire != nil {
+			expire = (time.Duration(*s.Expire) * time.Second).String()
+		}
+		rows = append(rows, []string{s.Nameserver, s.Address, strconv.FormatUint(uint64(s.Serial), 10), expire})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	for i, row := range rows {
+		var line string
+		for j, col := range row {
+			line += fmt.Sprintf("%-*s ", widths[j], col)
+		}
+		if i == 0 {
+			util.MustWriteln(p.Out, util.Color(util.ColorWhite, line))
+			continue
+		}
+
+		s := statuses[i-1]
+		switch {
+		case s.Error != "":
+			line += util.Color(util.ColorRed, s.Error)
+		case s.SerialMismatch:
+			line += util.Color(util.ColorRed, "SERIAL MISMATCH")
+		}
+		util.MustWriteln(p.Out, line)
+	}
+}
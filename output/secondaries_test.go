@@ -0,0 +1,27 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputPrintSecondaries(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+
+	expire := uint32(604800)
+	p.PrintSecondaries([]SecondaryStatus{
+		{Nameserver: "a.example.", Address: "192.0.2.1", Serial: 2, Expire: &expire},
+		{Nameserver: "b.example.", Address: "192.0.2.2", Serial: 1, SerialMismatch: true},
+		{Nameserver: "c.example.", Error: "no addresses"},
+	})
+	assert.Contains(t, buf.String(), "a.example. 192.0.2.1 2      168h0m0s")
+	assert.Regexp(t, `b\.example\. 192\.0\.2\.2 1 +not returned SERIAL MISMATCH`, buf.String())
+	assert.Regexp(t, `c\.example\. +- +- +no addresses`, buf.String())
+}
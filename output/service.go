@@ -0,0 +1,57 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/natesales/q/util"
+)
+
+// ServiceEndpoint is a host and port an instance is reachable at, from its SRV record
+type ServiceEndpoint struct {
+	Target    string   `json:"target" yaml:"target"`
+	Port      uint16   `json:"port" yaml:"port"`
+	Priority  uint16   `json:"priority" yaml:"priority"`
+	Weight    uint16   `json:"weight" yaml:"weight"`
+	Addresses []string `json:"addresses" yaml:"addresses"`
+}
+
+// ServiceInstance is a DNS-SD service instance (RFC 6763)
+type ServiceInstance struct {
+	Name      string            `json:"name" yaml:"name"`
+	Endpoints []ServiceEndpoint `json:"endpoints" yaml:"endpoints"`
+	TXT       []string          `json:"txt" yaml:"txt"` // Key/value metadata (RFC 6763 section 6)
+	Error     string            `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// PrintServices prints the instances of a DNS-SD service as a tree of instances, endpoints, and metadata
+func (p Printer) PrintServices(service string, instances []ServiceInstance) {
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(instances)
+		return
+	}
+
+	util.MustWriteln(p.Out, util.Color(util.ColorWhite, service))
+	if len(instances) == 0 {
+		util.MustWriteln(p.Out, "  no instances")
+		return
+	}
+
+	for _, instance := range instances {
+		util.MustWritef(p.Out, "  %s\n", util.Color(util.ColorPurple, instance.Name))
+		if instance.Error != "" {
+			util.MustWritef(p.Out, "    %s\n", util.Color(util.ColorRed, instance.Error))
+		}
+		for _, e := range instance.Endpoints {
+			addrs := "no addresses"
+			if len(e.Addresses) > 0 {
+				addrs = strings.Join(e.Addresses, ", ")
+			}
+			util.MustWritef(p.Out, "    %s priority %d weight %d (%s)\n",
+				util.Color(util.ColorTeal, fmt.Sprintf("%s:%d", e.Target, e.Port)), e.Priority, e.Weight, addrs)
+		}
+		if len(instance.TXT) > 0 {
+			util.MustWritef(p.Out, "    %s\n", strings.Join(instance.TXT, " "))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package output
+
+import (
+	"github.com/natesales/q/util"
+)
+
+// HasAnswers returns true if any reply of entries has an answer record
+func HasAnswers(entries []*Entry) bool {
+	for _, e := range entries {
+		for _, reply := range e.Replies {
+			if len(reply.Answer) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PrintShort prints the rdata of each answer record, one per line and without colors, for use in scripts
+func (p Printer) PrintShort(entries []*Entry) {
+	entries = p.sortEntries(entries)
+	for _, e := range entries {
+		for _, reply := range e.Replies {
+			for _, rr := range reply.Answer {
+				util.MustWriteln(p.Out, rrValue(rr))
+			}
+		}
+	}
+}
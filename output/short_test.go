@@ -0,0 +1,24 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+)
+
+func TestOutputPrintShort(t *testing.T) {
+	entries := []*Entry{
+		answerEntry("192.0.2.53", "example.com. 300 IN A 192.0.2.1", "example.com. 300 IN A 192.0.2.2"),
+		answerEntry("192.0.2.53", "example.com. 300 IN MX 10 mail.example.com."),
+	}
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+	p.PrintShort(entries)
+	assert.Equal(t, "192.0.2.1\n192.0.2.2\n10 mail.example.com.\n", buf.String())
+	assert.True(t, HasAnswers(entries))
+	assert.False(t, HasAnswers([]*Entry{answerEntry("192.0.2.53")}))
+}
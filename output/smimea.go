@@ -0,0 +1,123 @@
+package output
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// daneUsages maps DANE certificate usages to their acronyms (RFC 7218)
+var daneUsages = map[uint8]string{
+	0: "PKIX-TA",
+	1: "PKIX-EE",
+	2: "DANE-TA",
+	3: "DANE-EE",
+}
+
+// daneSelectors maps DANE selectors to their acronyms
+var daneSelectors = map[uint8]string{
+	0: "Cert",
+	1: "SPKI",
+}
+
+// daneMatchingTypes maps DANE matching types to their acronyms
+var daneMatchingTypes = map[uint8]string{
+	0: "Full",
+	1: "SHA2-256",
+	2: "SHA2-512",
+}
+
+// SMIMECert is a decoded SMIMEA record (RFC 8162)
+type SMIMECert struct {
+	Name         string
+	Usage        string
+	Selector     string
+	MatchingType string     `json:"matching_type" yaml:"matching_type"`
+	Digest       string     `json:",omitempty" yaml:",omitempty"` // Hex encoded hash, if the record doesn't hold the full data
+	KeyAlgorithm string     `json:"key_algorithm,omitempty" yaml:"key_algorithm,omitempty"`
+	Subject      string     `json:",omitempty" yaml:",omitempty"`
+	Issuer       string     `json:",omitempty" yaml:",omitempty"`
+	Emails       []string   `json:",omitempty" yaml:",omitempty"`
+	NotBefore    *time.Time `json:"not_before,omitempty" yaml:"not_before,omitempty"`
+	NotAfter     *time.Time `json:"not_after,omitempty" yaml:"not_after,omitempty"`
+}
+
+// daneName returns the acronym of a DANE field, or its number if it's unknown
+func daneName(names map[uint8]string, n uint8) string {
+	if s, ok := names[n]; ok {
+		return s
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// decodeSMIMEA decodes the certificate or public key of an SMIMEA record that holds full data
+func decodeSMIMEA(rr *dns.SMIMEA) (SMIMECert, error) {
+	c := SMIMECert{
+		Name:         rr.Hdr.Name,
+		Usage:        daneName(daneUsages, rr.Usage),
+		Selector:     daneName(daneSelectors, rr.Selector),
+		MatchingType: daneName(daneMatchingTypes, rr.MatchingType),
+	}
+	if rr.MatchingType != 0 {
+		c.Digest = strings.ToLower(rr.Certificate)
+		return c, nil
+	}
+
+	der, err := hex.DecodeString(rr.Certificate)
+	if err != nil {
+		return c, err
+	}
+	switch rr.Selector {
+	case 0:
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return c, err
+		}
+		c.KeyAlgorithm = cert.PublicKeyAlgorithm.String()
+		c.Subject = cert.Subject.String()
+		c.Issuer = cert.Issuer.String()
+		c.Emails = cert.EmailAddresses
+		notBefore, notAfter := cert.NotBefore.UTC(), cert.NotAfter.UTC()
+		c.NotBefore, c.NotAfter = &notBefore, &notAfter
+	case 1:
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return c, err
+		}
+		c.KeyAlgorithm = strings.TrimPrefix(fmt.Sprintf("%T", key), "*")
+	}
+	return c, nil
+}
+
+// prettySMIMEA renders an SMIMEA record with named parameters and a summary of its certificate, key, or digest
+func prettySMIMEA(rr *dns.SMIMEA) (string, bool) {
+	c, err := decodeSMIMEA(rr)
+	if err != nil {
+		return "", false
+	}
+
+	val := fmt.Sprintf("%s %s %s", c.Usage, c.Selector, c.MatchingType)
+	switch {
+	case c.Digest != "":
+		val += " " + util.Color(util.ColorTeal, c.Digest)
+	case c.Subject != "":
+		val += fmt.Sprintf(" %s cert %s issued by %s", c.KeyAlgorithm, util.Color(util.ColorPurple, c.Subject), c.Issuer)
+		for _, email := range c.Emails {
+			val += " " + util.Color(util.ColorTeal, email)
+		}
+		validity := fmt.Sprintf("valid %s → %s", c.NotBefore.Format(time.DateOnly), c.NotAfter.Format(time.DateOnly))
+		if now := time.Now(); now.Before(*c.NotBefore) || now.After(*c.NotAfter) {
+			validity = util.Color(util.ColorRed, validity+" (not currently valid)")
+		}
+		val += " " + validity
+	default:
+		val += fmt.Sprintf(" %s public key", c.KeyAlgorithm)
+	}
+	return val, true
+}
@@ -0,0 +1,119 @@
+package output
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// sshfpAlgorithms maps SSHFP algorithm numbers to their names (RFC 4255, RFC 6594, RFC 7479, RFC 8709)
+var sshfpAlgorithms = map[uint8]string{
+	1: "RSA",
+	2: "DSA",
+	3: "ECDSA",
+	4: "Ed25519",
+	6: "Ed448",
+}
+
+// sshfpTypes maps SSHFP fingerprint types to their names
+var sshfpTypes = map[uint8]string{
+	1: "SHA-1",
+	2: "SHA-256",
+}
+
+// sshKeyAlgorithms maps OpenSSH public key types to SSHFP algorithm numbers
+var sshKeyAlgorithms = map[string]uint8{
+	"ssh-rsa":             1,
+	"ssh-dss":             2,
+	"ecdsa-sha2-nistp256": 3,
+	"ecdsa-sha2-nistp384": 3,
+	"ecdsa-sha2-nistp521": 3,
+	"ssh-ed25519":         4,
+	"ssh-ed448":           6,
+}
+
+// SSHHostKey stores an SSH public key blob and its SSHFP algorithm number
+type SSHHostKey struct {
+	Algorithm uint8
+	Key       []byte
+}
+
+// fingerprint returns the hex encoded SSHFP fingerprint of a key, or an empty string for unknown fingerprint types
+func (k SSHHostKey) fingerprint(fpType uint8) string {
+	switch fpType {
+	case 1:
+		sum := sha1.Sum(k.Key)
+		return hex.EncodeToString(sum[:])
+	case 2:
+		sum := sha256.Sum256(k.Key)
+		return hex.EncodeToString(sum[:])
+	}
+	return ""
+}
+
+// ReadSSHKeys reads SSH public keys from an OpenSSH public key or known_hosts file
+func ReadSSHKeys(path string) ([]SSHHostKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening SSH key file: %w", err)
+	}
+	defer f.Close()
+
+	var keys []SSHHostKey
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i := 0; i+1 < len(fields); i++ {
+			alg, ok := sshKeyAlgorithms[fields[i]]
+			if !ok {
+				continue
+			}
+			key, err := base64.StdEncoding.DecodeString(fields[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("decoding %s key in %s: %w", fields[i], path, err)
+			}
+			keys = append(keys, SSHHostKey{Algorithm: alg, Key: key})
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading SSH key file: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no SSH public keys found in %s", path)
+	}
+	return keys, nil
+}
+
+// sshfpName returns the name from a map of SSHFP parameters, falling back to the number
+func sshfpName(names map[uint8]string, n uint8) string {
+	if s, ok := names[n]; ok {
+		return s
+	}
+	return strconv.Itoa(int(n))
+}
+
+// prettySSHFP renders an SSHFP record with algorithm and fingerprint type names, checking the fingerprint against the entry's SSH keys if any are loaded
+func (e *Entry) prettySSHFP(fp *dns.SSHFP) string {
+	val := fmt.Sprintf("%s %s %s", sshfpName(sshfpAlgorithms, fp.Algorithm), sshfpName(sshfpTypes, fp.Type), strings.ToUpper(fp.FingerPrint))
+	if len(e.SSHKeys) == 0 {
+		return val
+	}
+
+	for _, key := range e.SSHKeys {
+		if key.Algorithm == fp.Algorithm && strings.EqualFold(key.fingerprint(fp.Type), fp.FingerPrint) {
+			return val + util.Color(util.ColorGreen, " (matches host key)")
+		}
+	}
+	return val + util.Color(util.ColorRed, " (no matching host key)")
+}
@@ -5,23 +5,108 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/json-iterator/go/extra"
+	"github.com/miekg/dns"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
 	"github.com/natesales/q/util"
 )
 
+// FlatRecord is a single answer record with its query metadata, for consumers that don't handle nested JSON
+type FlatRecord struct {
+	Server    string  `json:"server"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	TTL       uint32  `json:"ttl"`
+	TTLHuman  string  `json:"ttl_human,omitempty"` // Only set with --ttl-human
+	Rdata     string  `json:"rdata"`
+	Rcode     string  `json:"rcode"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// flatten converts a slice of entries to one FlatRecord per answer record
+func flatten(entries []*Entry, ttlHuman bool) []FlatRecord {
+	var records []FlatRecord
+	for _, entry := range entries {
+		for _, reply := range entry.Replies {
+			for _, rr := range reply.Answer {
+				record := FlatRecord{
+					Server:    entry.Server,
+					Name:      rr.Header().Name,
+					Type:      dns.TypeToString[rr.Header().Rrtype],
+					TTL:       rr.Header().Ttl,
+					Rdata:     rrValue(rr),
+					Rcode:     dns.RcodeToString[reply.Rcode],
+					LatencyMs: float64(entry.Time.Microseconds()) / 1000,
+				}
+				if ttlHuman {
+					record.TTLHuman = durationTTL(rr.Header().Ttl, true)
+				}
+				records = append(records, record)
+			}
+		}
+	}
+	return records
+}
+
+// printFlat prints one JSON object per line for each answer record
+func (p Printer) printFlat(entries []*Entry) {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	for _, record := range flatten(entries, p.Opts.TTLHuman) {
+		b, err := json.Marshal(record)
+		if err != nil {
+			log.Fatalf("error marshaling output: %s", err)
+		}
+		util.MustWriteln(p.Out, string(b))
+	}
+}
+
+// SchemaVersion is the version of the structured output schema, incremented on breaking changes to it
+const SchemaVersion = 1
+
+// PrintStructured prints entries as JSON or YAML
 func (p Printer) PrintStructured(entries []*Entry) {
+	entries = p.sortEntries(entries)
+
+	if p.Opts.JSONFlatten && p.Opts.Format == "json" {
+		p.printFlat(entries)
+		return
+	}
+
+	structured := make([]*Entry, len(entries))
+	for i, entry := range entries {
+		entry.SchemaVersion = SchemaVersion
+		entry.LoadResponses()
+		entry.LoadEDNS()
+		entry.LoadExtendedErrors()
+		entry.LoadDenials()
+		if p.Opts.Expire {
+			entry.LoadExpireTimers()
+		}
+		structured[i] = entry.withoutOPT()
+	}
+	p.printMarshaled(structured)
+}
+
+// printMarshaled prints v as JSON or YAML depending on the output format
+func (p Printer) printMarshaled(v any) {
 	var marshaler func(any) ([]byte, error)
 	if p.Opts.Format == "json" {
 		extra.SetNamingStrategy(strings.ToLower)
 		json := jsoniter.ConfigCompatibleWithStandardLibrary
-		marshaler = json.Marshal
+		marshaler = func(v any) ([]byte, error) {
+			return json.MarshalIndent(v, "", "  ")
+		}
+		if p.Opts.JSONCompact {
+			marshaler = json.Marshal
+		}
+	} else if p.Opts.Annotate { // yaml with comments
+		marshaler = marshalAnnotatedYAML
 	} else { // yaml
 		marshaler = yaml.Marshal
 	}
 
-	b, err := marshaler(entries)
+	b, err := marshaler(v)
 	if err != nil {
 		log.Fatalf("error marshaling output: %s", err)
 	}
@@ -0,0 +1,159 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/transport"
+)
+
+func TestOutputPrintFlatJSON(t *testing.T) {
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONFlatten: true}}
+	p.PrintStructured(entries)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 6)
+	assert.Equal(t, `{"server":"192.0.2.10","name":"example.com.","type":"A","ttl":86400,"rdata":"192.0.2.1","rcode":"NOERROR","latency_ms":2000}`, lines[0])
+	assert.Contains(t, buf.String(), `"type":"MX","ttl":86400,"rdata":"0 ."`)
+}
+
+func TestOutputPrintFlatJSONTTLHuman(t *testing.T) {
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONFlatten: true, TTLHuman: true}}
+	p.PrintStructured(entries)
+	assert.Contains(t, buf.String(), `"ttl":86400,"ttl_human":"24h",`)
+}
+
+func TestOutputPrintAnnotatedYAML(t *testing.T) {
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "yaml", Annotate: true}}
+	p.PrintStructured(entries)
+	assert.Contains(t, buf.String(), "schema_version: 1\n")
+	assert.Contains(t, buf.String(), "rcode: 0 # NOERROR: no error\n")
+	assert.Contains(t, buf.String(), "aa: false # authoritative answer\n")
+	assert.Contains(t, buf.String(), "ttl: 86400 # seconds the record may be cached for\n")
+	assert.Contains(t, buf.String(), "answer: # answer section: records answering the question\n")
+	assert.NotContains(t, buf.String(), "msghdr")
+
+	buf.Reset()
+	p.Opts.Annotate = false
+	p.PrintStructured(entries)
+	assert.NotContains(t, buf.String(), "#")
+}
+
+func TestOutputPrintStructuredResponses(t *testing.T) {
+	reply := new(dns.Msg)
+	reply.SetQuestion("example.com.", dns.TypeA)
+	reply.Id = 1234
+	reply.Response, reply.Authoritative, reply.Rcode = true, true, dns.RcodeNameError
+	reply.SetEdns0(1232, true)
+
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured([]*Entry{{Server: "192.0.2.53", Replies: []*dns.Msg{reply}}})
+	assert.Contains(t, buf.String(), `"responses":[{"header":{"id":1234,"qr":true,"aa":true,"tc":false,"rd":true,"ra":false,"z":false,"ad":false,"cd":false,"opcode":0,"opcode_name":"QUERY","rcode":3,"rcode_name":"NXDOMAIN"},"question":[{"name":"example.com.","type":"A","class":"IN"}],"answer":[],"authority":[],"additional":[],"edns":{"version":0,"udpsize":1232,"do":true,"options":[]}}]`)
+
+	// The reply itself keeps its OPT record
+	assert.NotNil(t, reply.IsEdns0())
+}
+
+func TestOutputPrintStructuredSchema(t *testing.T) {
+	var buf bytes.Buffer
+	p := Printer{Out: &buf, Opts: &cli.Flags{Format: "json", JSONCompact: true}}
+	p.PrintStructured(entries)
+
+	var decoded []map[string]any
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	if assert.Len(t, decoded, 1) {
+		var keys []string
+		for key := range decoded[0] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		assert.Equal(t, []string{"edns", "responses", "schema_version", "server", "time"}, keys)
+		assert.Equal(t, float64(SchemaVersion), decoded[0]["schema_version"])
+	}
+	assert.Contains(t, buf.String(), `{"name":"example.com.","ttl":86400,"class":"IN","type":"MX","rdata":{"preference":0,"mx":"."}}`)
+	assert.Contains(t, buf.String(), `{"name":"example.com.","ttl":86400,"class":"IN","type":"A","rdata":{"a":"192.0.2.1"}}`)
+	assert.NotContains(t, buf.String(), `"hdr"`)
+
+	buf.Reset()
+	p.Opts.JSONCompact = false
+	p.PrintStructured(entries)
+	assert.True(t, strings.HasPrefix(buf.String(), "[\n  {\n    \"schema_version\": 1,\n"))
+
+	// YAML has the same keys, with queries decoded like responses
+	query := new(dns.Msg)
+	query.SetQuestion("example.com.", dns.TypeA)
+	buf.Reset()
+	p.Opts.Format = "yaml"
+	p.PrintStructured([]*Entry{{Server: "192.0.2.53", Queries: []dns.Msg{*query}, Replies: replies()}})
+	var decodedYAML []map[string]any
+	assert.Nil(t, yaml.Unmarshal(buf.Bytes(), &decodedYAML))
+	if assert.Len(t, decodedYAML, 1) {
+		var keys []string
+		for key := range decodedYAML[0] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		assert.Equal(t, []string{"edns", "queries", "responses", "schema_version", "server", "time"}, keys)
+	}
+	assert.NotContains(t, buf.String(), "msghdr")
+}
+
+func TestOutputPrintStructuredKeys(t *testing.T) {
+	expire := uint32(604800)
+	entry := &Entry{
+		SchemaVersion:    SchemaVersion,
+		Server:           "192.0.2.53",
+		Transport:        "tls",
+		Error:            "timeout",
+		Time:             time.Second,
+		Timings:          []transport.Timings{{Connect: time.Millisecond}},
+		TLS:              &TLSInfo{ServerName: "dns.example.com"},
+		QueryMessages:    []Response{{}},
+		Responses:        []Response{{}},
+		EDNS:             []EDNSExchange{{}},
+		ExtendedErrors:   []ExtendedError{{Question: "example.com. A", Code: 18, Name: "Prohibited"}},
+		ExpireTimers:     []ExpireTimer{{Question: "example.com. SOA", Expire: &expire}},
+		Denials:          []Denial{{Question: "example.com. A", Kind: "NODATA"}},
+		CookieHandshakes: []CookieHandshake{{Question: "example.com. A", ServerCookie: "0102030405060708"}},
+		Inconsistencies:  []Inconsistency{{Question: "example.com. A"}},
+		Zone:             &ZoneCut{Name: "www.example.com.", Zone: "example.com.", Depth: 1},
+	}
+	want := []string{
+		"cookie_handshakes", "denials", "edns", "error", "expire_timers", "extended_errors", "inconsistencies", "queries",
+		"responses", "schema_version", "server", "time", "timings", "tls", "transport", "zone",
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		var buf bytes.Buffer
+		p := Printer{Out: &buf, Opts: &cli.Flags{Format: format}}
+		p.printMarshaled([]*Entry{entry})
+
+		var decoded []map[string]any
+		if format == "json" {
+			assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+		} else {
+			assert.Nil(t, yaml.Unmarshal(buf.Bytes(), &decoded))
+		}
+		if assert.Len(t, decoded, 1, format) {
+			var keys []string
+			for key := range decoded[0] {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			assert.Equal(t, want, keys, format)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package output
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// svcbParam formats a single SvcParam as key=value, with unknown keys as keyNNNN=hex
+func svcbParam(kv dns.SVCBKeyValue) string {
+	switch kv := kv.(type) {
+	case *dns.SVCBNoDefaultAlpn, *dns.SVCBOhttp:
+		return kv.Key().String()
+	case *dns.SVCBLocal:
+		return fmt.Sprintf("%s=%s", kv.Key(), hex.EncodeToString(kv.Data))
+	}
+	return fmt.Sprintf("%s=%s", kv.Key(), kv.String())
+}
+
+// prettySVCB renders an SVCB or HTTPS record with its priority and target, followed by each SvcParam on its own line
+func prettySVCB(svcb *dns.SVCB) string {
+	val := fmt.Sprintf("%d %s", svcb.Priority, svcb.Target)
+	if svcb.Priority == 0 {
+		val += util.Color(util.ColorTeal, " (alias)")
+	}
+
+	var params []string
+	for _, kv := range svcb.Value {
+		key, value, _ := strings.Cut(svcbParam(kv), "=")
+		param := util.Color(util.ColorTeal, key)
+		if value != "" {
+			param += "=" + value
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		val += "\n    " + strings.Join(params, "\n    ")
+	}
+	return val
+}
@@ -0,0 +1,35 @@
+package output
+
+import (
+	"strings"
+
+	"github.com/natesales/q/util"
+)
+
+// SweepResult stores the PTR records for a single address in a sweep
+type SweepResult struct {
+	IP   string   `json:"ip" yaml:"ip"`
+	PTRs []string `json:"ptrs" yaml:"ptrs"`
+}
+
+// PrintSweep prints a table of IP to hostname mappings
+func (p Printer) PrintSweep(results []SweepResult) {
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(results)
+		return
+	}
+
+	longestIP := 0
+	for _, r := range results {
+		if len(r.IP) > longestIP {
+			longestIP = len(r.IP)
+		}
+	}
+
+	for _, r := range results {
+		util.MustWritef(p.Out, "%s %s\n",
+			util.Color(util.ColorPurple, r.IP+strings.Repeat(" ", longestIP-len(r.IP))),
+			util.Color(util.ColorGreen, strings.Join(r.PTRs, ", ")),
+		)
+	}
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/miekg/dns"
+)
+
+// SyslogRecord is the result of a single query, formatted for syslog
+type SyslogRecord struct {
+	Server    string   `json:"server"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Rcode     string   `json:"rcode"`
+	Answers   []string `json:"answers"`
+	LatencyMs float64  `json:"latency_ms"`
+}
+
+// String formats a record as a compact key=value line
+func (r SyslogRecord) String() string {
+	return fmt.Sprintf("server=%s name=%s type=%s rcode=%s latency_ms=%.3f answers=%q",
+		r.Server, r.Name, r.Type, r.Rcode, r.LatencyMs, strings.Join(r.Answers, ", "))
+}
+
+// SyslogMessages returns one syslog message per reply across all entries, as compact lines or JSON objects
+func SyslogMessages(entries []*Entry, asJSON bool) ([]string, error) {
+	var messages []string
+	for _, entry := range entries {
+		for i, reply := range entry.Replies {
+			record := SyslogRecord{
+				Server:  entry.Server,
+				Rcode:   dns.RcodeToString[reply.Rcode],
+				Answers: []string{},
+			}
+			if len(reply.Question) > 0 {
+				record.Name = reply.Question[0].Name
+				record.Type = dns.TypeToString[reply.Question[0].Qtype]
+			}
+			if i < len(entry.Durations) {
+				record.LatencyMs = float64(entry.Durations[i].Microseconds()) / 1000
+			}
+			for _, rr := range reply.Answer {
+				record.Answers = append(record.Answers, fmt.Sprintf("%s %s", dns.TypeToString[rr.Header().Rrtype], rrValue(rr)))
+			}
+
+			if !asJSON {
+				messages = append(messages, record.String())
+				continue
+			}
+			b, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(record)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, string(b))
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,23 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputSyslogMessages(t *testing.T) {
+	reply := replies()[0]
+	reply.Question = []dns.Question{{Name: "example.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET}}
+	e := &Entry{Replies: []*dns.Msg{reply}, Server: "192.0.2.10", Durations: []time.Duration{1500 * time.Microsecond}}
+
+	messages, err := SyslogMessages([]*Entry{e}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`server=192.0.2.10 name=example.com. type=A rcode=NOERROR latency_ms=1.500 answers="A 192.0.2.1"`}, messages)
+
+	messages, err = SyslogMessages([]*Entry{e}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"server":"192.0.2.10","name":"example.com.","type":"A","rcode":"NOERROR","answers":["A 192.0.2.1"],"latency_ms":1.5}`}, messages)
+}
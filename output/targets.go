@@ -0,0 +1,64 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/natesales/q/util"
+)
+
+// TargetStatus stores the result of resolving the target of an NS or MX record
+type TargetStatus struct {
+	Name      string   `json:"name" yaml:"name"`
+	Type      string   `json:"type" yaml:"type"`
+	Target    string   `json:"target" yaml:"target"`
+	Bailiwick bool     `json:"in_bailiwick" yaml:"in_bailiwick"` // Whether the target is within the queried name
+	Addresses []string `json:"addresses" yaml:"addresses"`
+	Problems  []string `json:"problems,omitempty" yaml:"problems,omitempty"`
+}
+
+// PrintTargets prints a table of NS and MX targets with their addresses and any problems found resolving them
+func (p Printer) PrintTargets(statuses []TargetStatus) {
+	if p.Opts.Format == FormatJSON || p.Opts.Format == FormatYAML || p.Opts.Format == "yml" {
+		p.printMarshaled(statuses)
+		return
+	}
+
+	rows := [][]string{{"Name", "Type", "Target", "Bailiwick", "Addresses"}}
+	for _, s := range statuses {
+		bailiwick := "out"
+		if s.Bailiwick {
+			bailiwick = "in"
+		}
+		addrs := strings.Join(s.Addresses, ", ")
+		if addrs == "" {
+			addrs = "-"
+		}
+		rows = append(rows, []string{s.Name, s.Type, s.Target, bailiwick, addrs})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+
+	for i, row := range rows {
+		var line string
+		for j, col := range row {
+			line += fmt.Sprintf("%-*s ", widths[j], col)
+		}
+		if i == 0 {
+			util.MustWriteln(p.Out, util.Color(util.ColorWhite, line))
+			continue
+		}
+
+		if problems := statuses[i-1].Problems; len(problems) > 0 {
+			line += util.Color(util.ColorRed, strings.Join(problems, ", "))
+		} else {
+			line += util.Color(util.ColorGreen, "OK")
+		}
+		util.MustWriteln(p.Out, line)
+	}
+}
@@ -0,0 +1,32 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/util"
+)
+
+func TestOutputPrintTargets(t *testing.T) {
+	var buf bytes.Buffer
+	util.UseColor = false
+	p := Printer{Out: &buf, Opts: &cli.Flags{}}
+
+	p.PrintTargets([]TargetStatus{
+		{Name: "example.", Type: "NS", Target: "ns1.example.", Bailiwick: true, Addresses: []string{"192.0.2.1", "2001:db8::1"}},
+		{Name: "example.", Type: "MX", Target: "mail.example.net.", Problems: []string{"doesn't resolve (NXDOMAIN)"}},
+	})
+	assert.Contains(t, buf.String(), "Name     Type Target            Bailiwick Addresses")
+	assert.Contains(t, buf.String(), "example. NS   ns1.example.      in        192.0.2.1, 2001:db8::1 OK")
+	assert.Regexp(t, `example\. MX +mail\.example\.net\. out +- +doesn't resolve \(NXDOMAIN\)`, buf.String())
+
+	buf.Reset()
+	p.Opts.Format = FormatJSON
+	p.Opts.JSONCompact = true
+	p.PrintTargets([]TargetStatus{{Name: "example.", Type: "NS", Target: "ns1.example.", Problems: []string{"CNAME to ns.example.net."}}})
+	assert.Contains(t, buf.String(), `"in_bailiwick":false`)
+	assert.Contains(t, buf.String(), `"problems":["CNAME to ns.example.net."]`)
+}
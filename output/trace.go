@@ -0,0 +1,72 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/natesales/q/util"
+)
+
+// TraceHop stores a single step of an iterative resolution
+type TraceHop struct {
+	Zone        string   // Zone the server was queried as authoritative for
+	Nameserver  string   // Nameserver name, empty for the starting server
+	Address     string   // Address the query was sent to
+	Rcode       string   // Response code, empty if the query failed
+	Referral    string   // Delegated zone, empty if the server didn't refer
+	Nameservers []string // Nameservers of the delegated zone
+	Answers     []string // Answer records of a final response
+	TimedOut    []string // Nameservers of the zone that timed out before one answered
+	Error       string   // Error that stopped the trace at this hop
+}
+
+// dotLabel builds a quoted DOT label from lines
+func dotLabel(lines ...string) string {
+	return fmt.Sprintf("%q", strings.Join(lines, "\n"))
+}
+
+// WriteTraceGraph writes a Graphviz DOT graph of a delegation chain
+func WriteTraceGraph(w io.Writer, question string, hops []TraceHop) {
+	util.MustWriteln(w, "digraph trace {")
+	util.MustWriteln(w, "\trankdir=LR;")
+	util.MustWriteln(w, "\tnode [shape=box];")
+	util.MustWritef(w, "\tclient [shape=ellipse, label=%s];\n", dotLabel("client", question))
+
+	prev := "client"
+	for i, hop := range hops {
+		node := fmt.Sprintf("hop%d", i)
+		server := hop.Address
+		if hop.Nameserver != "" {
+			server = fmt.Sprintf("%s (%s)", hop.Nameserver, hop.Address)
+		}
+		lines := []string{hop.Zone, server}
+		for _, t := range hop.TimedOut {
+			lines = append(lines, "timed out: "+t)
+		}
+		util.MustWritef(w, "\t%s [label=%s];\n", node, dotLabel(lines...))
+
+		// The first edge carries the query, later edges carry the referral that led to the hop
+		edge := dotLabel(question)
+		if i > 0 {
+			edge = dotLabel(append([]string{"referral to " + hops[i-1].Referral}, hops[i-1].Nameservers...)...)
+		}
+		util.MustWritef(w, "\t%s -> %s [label=%s];\n", prev, node, edge)
+		prev = node
+	}
+
+	if len(hops) == 0 {
+		util.MustWriteln(w, "}")
+		return
+	}
+	last := hops[len(hops)-1]
+	switch {
+	case last.Error != "":
+		util.MustWritef(w, "\tresult [shape=octagon, label=%s];\n", dotLabel(last.Error))
+		util.MustWritef(w, "\t%s -> result [label=\"error\"];\n", prev)
+	case last.Referral == "":
+		util.MustWritef(w, "\tresult [shape=note, label=%s];\n", dotLabel(append([]string{last.Rcode}, last.Answers...)...))
+		util.MustWritef(w, "\t%s -> result [label=%s];\n", prev, dotLabel(last.Rcode))
+	}
+	util.MustWriteln(w, "}")
+}
@@ -0,0 +1,45 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputWriteTraceGraph(t *testing.T) {
+	var out bytes.Buffer
+	WriteTraceGraph(&out, "example.com. A", []TraceHop{
+		{Zone: ".", Address: "198.41.0.4:53", Rcode: "NOERROR", Referral: "com.", Nameservers: []string{"a.gtld-servers.net."}},
+		{Zone: "com.", Nameserver: "a.gtld-servers.net.", Address: "192.5.6.30:53", Rcode: "NOERROR", Answers: []string{"example.com.\t300\tIN\tA\t192.0.2.1"}},
+	})
+	assert.Equal(t, `digraph trace {
+	rankdir=LR;
+	node [shape=box];
+	client [shape=ellipse, label="client\nexample.com. A"];
+	hop0 [label=".\n198.41.0.4:53"];
+	client -> hop0 [label="example.com. A"];
+	hop1 [label="com.\na.gtld-servers.net. (192.5.6.30:53)"];
+	hop0 -> hop1 [label="referral to com.\na.gtld-servers.net."];
+	result [shape=note, label="NOERROR\nexample.com.\t300\tIN\tA\t192.0.2.1"];
+	hop1 -> result [label="NOERROR"];
+}
+`, out.String())
+}
+
+func TestOutputWriteTraceGraphError(t *testing.T) {
+	var out bytes.Buffer
+	WriteTraceGraph(&out, "example.com. A", []TraceHop{
+		{Zone: ".", Address: "198.41.0.4:53", Error: "no glue for referral to com."},
+	})
+	assert.Contains(t, out.String(), `result [shape=octagon, label="no glue for referral to com."];`)
+	assert.Contains(t, out.String(), `hop0 -> result [label="error"];`)
+}
+
+func TestOutputWriteTraceGraphTimedOut(t *testing.T) {
+	var out bytes.Buffer
+	WriteTraceGraph(&out, "example.com. A", []TraceHop{
+		{Zone: "com.", Nameserver: "b.gtld-servers.net.", Address: "192.33.14.30:53", Rcode: "NOERROR", TimedOut: []string{"a.gtld-servers.net. (192.5.6.30:53)"}},
+	})
+	assert.Contains(t, out.String(), `hop0 [label="com.\nb.gtld-servers.net. (192.33.14.30:53)\ntimed out: a.gtld-servers.net. (192.5.6.30:53)"];`)
+}
@@ -0,0 +1,35 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/natesales/q/util"
+)
+
+// ZoneCut is the zone a queried name belongs to, found with --zone
+type ZoneCut struct {
+	Name  string `json:"name" yaml:"name"`
+	Zone  string `json:"zone" yaml:"zone"`   // Apex of the zone
+	Depth int    `json:"depth" yaml:"depth"` // Number of labels the name has below the apex
+}
+
+// String describes how far below its zone apex a name is
+func (z ZoneCut) String() string {
+	zone := util.Color(util.ColorPurple, z.Zone)
+	switch z.Depth {
+	case 0:
+		return fmt.Sprintf("%s is the apex of zone %s", z.Name, zone)
+	case 1:
+		return fmt.Sprintf("%s is 1 label under zone %s", z.Name, zone)
+	default:
+		return fmt.Sprintf("%s is %d labels under zone %s", z.Name, z.Depth, zone)
+	}
+}
+
+// printZone prints the zone an entry's name belongs to if it was found, unless only record values are shown
+func (p Printer) printZone(e *Entry) {
+	if e.Zone == nil || p.Opts.ValueOnly {
+		return
+	}
+	util.MustWritef(p.Out, "Zone: %s\n", e.Zone)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+)
+
+// Link types of the pcap file format (https://www.tcpdump.org/linktypes.html)
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRawAlt   = 12 // DLT_RAW on OpenBSD and others
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+)
+
+// pcapMessage is a DNS message captured in a pcap file and the endpoints it was sent between
+type pcapMessage struct {
+	Msg *dns.Msg
+	Src netip.AddrPort
+	Dst netip.AddrPort
+}
+
+// readPcap reads the DNS messages sent to or from port 53 over UDP or TCP in a classic (not pcapng) pcap file.
+// Packets that can't be decoded, IP fragments, and DNS over TCP messages split across segments are skipped.
+func readPcap(path string) ([]pcapMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("reading pcap header: %s", err)
+	}
+	var order binary.ByteOrder
+	switch magic := binary.LittleEndian.Uint32(header); magic {
+	case 0xa1b2c3d4, 0xa1b23c4d: // Microsecond and nanosecond timestamps
+		order = binary.LittleEndian
+	case 0xd4c3b2a1, 0x4d3cb2a1:
+		order = binary.BigEndian
+	case 0x0a0d0d0a:
+		return nil, fmt.Errorf("pcapng files aren't supported, convert with editcap -F pcap")
+	default:
+		return nil, fmt.Errorf("not a pcap file (magic %08x)", magic)
+	}
+	linkType := order.Uint32(header[20:])
+
+	var msgs []pcapMessage
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(f, record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return msgs, nil
+			}
+			return nil, fmt.Errorf("reading packet header: %s", err)
+		}
+		data := make([]byte, order.Uint32(record[8:]))
+		if _, err := io.ReadFull(f, data); err != nil {
+			return nil, fmt.Errorf("reading packet: %s", err)
+		}
+
+		packet, ok := linkPayload(linkType, data)
+		if !ok {
+			continue
+		}
+		msgs = append(msgs, ipMessages(packet)...)
+	}
+}
+
+// linkPayload strips the link layer header from a captured frame, returning false if it doesn't carry IP
+func linkPayload(linkType uint32, frame []byte) ([]byte, bool) {
+	var payload []byte
+	switch linkType {
+	case linkTypeNull:
+		if len(frame) < 4 {
+			return nil, false
+		}
+		payload = frame[4:]
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return nil, false
+		}
+		etherType := binary.BigEndian.Uint16(frame[12:])
+		payload = frame[14:]
+		// Skip an 802.1Q VLAN tag
+		if etherType == 0x8100 && len(payload) >= 4 {
+			etherType, payload = binary.BigEndian.Uint16(payload[2:]), payload[4:]
+		}
+		if etherType != 0x0800 && etherType != 0x86dd {
+			return nil, false
+		}
+	case linkTypeRaw, linkTypeRawAlt:
+		payload = frame
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return nil, false
+		}
+		payload = frame[16:]
+	default:
+		return nil, false
+	}
+	return payload, len(payload) > 0 && (payload[0]>>4 == 4 || payload[0]>>4 == 6)
+}
+
+// ipMessages decodes the DNS messages in the UDP datagram or TCP segment of an IPv4 or IPv6 packet
+func ipMessages(packet []byte) []pcapMessage {
+	var src, dst netip.Addr
+	var proto byte
+	var payload []byte
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < 20 {
+			return nil
+		}
+		headerLen := int(packet[0]&0x0f) * 4
+		totalLen := int(binary.BigEndian.Uint16(packet[2:]))
+		if headerLen < 20 || totalLen < headerLen || len(packet) < totalLen {
+			return nil
+		}
+		// Skip fragments, which would need reassembly
+		if binary.BigEndian.Uint16(packet[6:])&0x3fff != 0 {
+			return nil
+		}
+		proto = packet[9]
+		src = netip.AddrFrom4([4]byte(packet[12:16]))
+		dst = netip.AddrFrom4([4]byte(packet[16:20]))
+		payload = packet[headerLen:totalLen]
+	case 6:
+		if len(packet) < 40 {
+			return nil
+		}
+		payloadLen := int(binary.BigEndian.Uint16(packet[4:]))
+		if len(packet) < 40+payloadLen {
+			return nil
+		}
+		proto = packet[6]
+		src = netip.AddrFrom16([16]byte(packet[8:24]))
+		dst = netip.AddrFrom16([16]byte(packet[24:40]))
+		payload = packet[40 : 40+payloadLen]
+	}
+
+	var data [][]byte
+	switch proto {
+	case 17: // UDP
+		if len(payload) < 8 {
+			return nil
+		}
+		data = [][]byte{payload[8:]}
+	case 6: // TCP, with each message prefixed by its length
+		if len(payload) < 20 || len(payload) < int(payload[12]>>4)*4 {
+			return nil
+		}
+		stream := payload[int(payload[12]>>4)*4:]
+		for len(stream) >= 2 {
+			n := int(binary.BigEndian.Uint16(stream))
+			if len(stream) < 2+n {
+				break
+			}
+			data = append(data, stream[2:2+n])
+			stream = stream[2+n:]
+		}
+	default:
+		return nil
+	}
+	srcPort, dstPort := binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])
+	if srcPort != 53 && dstPort != 53 {
+		return nil
+	}
+
+	var msgs []pcapMessage
+	for _, d := range data {
+		msg := new(dns.Msg)
+		if err := msg.Unpack(d); err != nil {
+			log.Debugf("Skipping undecodable DNS message from %s: %s", src, err)
+			continue
+		}
+		msgs = append(msgs, pcapMessage{
+			Msg: msg,
+			Src: netip.AddrPortFrom(src.Unmap(), srcPort),
+			Dst: netip.AddrPortFrom(dst.Unmap(), dstPort),
+		})
+	}
+	return msgs
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+const (
+	// postureBogusName is a deliberately broken DNSSEC signed name that validating resolvers answer with SERVFAIL
+	postureBogusName = "dnssec-failed.org."
+
+	// postureDuplicates is the number of identical queries sent back to back to check for consistent answers
+	postureDuplicates = 10
+)
+
+// postureCheck is the result of a single anti-poisoning indicator
+type postureCheck struct {
+	indicator string
+	result    string
+	ok        bool
+}
+
+// randomCase randomizes the case of each letter in a name for DNS 0x20 (draft-vixie-dnsext-dns0x20)
+func randomCase(name string) string {
+	return strings.Map(func(r rune) rune {
+		if rand.IntN(2) == 0 {
+			return unicode.ToUpper(r)
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// checkCookies reports whether the resolver returns a server cookie (RFC 7873)
+func checkCookies(ctx context.Context, txp *transport.Transport, msg dns.Msg) postureCheck {
+	check := postureCheck{indicator: "DNS cookies"}
+	reply, err := exchange(ctx, txp, cookieQuery(msg, fmt.Sprintf("%016x", rand.Uint64())))
+	if err != nil {
+		check.result = err.Error()
+		return check
+	}
+	if cookie, ok := util.EDNSOption[*dns.EDNS0_COOKIE](reply); ok && len(cookie.Cookie) > 16 {
+		check.result = "server cookie returned"
+		check.ok = true
+	} else {
+		check.result = "no server cookie"
+	}
+	return check
+}
+
+// check0x20 reports whether the resolver preserves the case of the query name in its reply
+func check0x20(ctx context.Context, txp *transport.Transport, msg dns.Msg) postureCheck {
+	check := postureCheck{indicator: "0x20 case preservation"}
+	query := msg.Copy()
+	query.Question[0].Name = randomCase(query.Question[0].Name)
+	reply, err := exchange(ctx, txp, query)
+	switch {
+	case err != nil:
+		check.result = err.Error()
+	case len(reply.Question) == 0:
+		check.result = "no question in reply"
+	case reply.Question[0].Name == query.Question[0].Name:
+		check.result = fmt.Sprintf("preserved %s", query.Question[0].Name)
+		check.ok = true
+	default:
+		check.result = fmt.Sprintf("sent %s, got %s", query.Question[0].Name, reply.Question[0].Name)
+	}
+	return check
+}
+
+// checkValidation reports whether the resolver rejects a name with a broken DNSSEC chain
+func checkValidation(ctx context.Context, txp *transport.Transport) postureCheck {
+	check := postureCheck{indicator: "DNSSEC validation"}
+	o := opts
+	o.Name = postureBogusName
+	o.DNSSEC = true
+	query := createQuery(o, []uint16{dns.TypeA})[0]
+	reply, err := exchange(ctx, txp, &query)
+	switch {
+	case err != nil:
+		check.result = err.Error()
+	case reply.Rcode == dns.RcodeServerFailure:
+		check.result = fmt.Sprintf("SERVFAIL for bogus %s", postureBogusName)
+		check.ok = true
+	default:
+		check.result = fmt.Sprintf("%s for bogus %s", dns.RcodeToString[reply.Rcode], postureBogusName)
+	}
+	return check
+}
+
+// checkDuplicates sends identical queries back to back and reports whether every reply echoes its ID and has the same answers
+func checkDuplicates(ctx context.Context, txp *transport.Transport, msg dns.Msg) postureCheck {
+	check := postureCheck{indicator: "Duplicate queries"}
+	var first []string
+	for i := 0; i < postureDuplicates; i++ {
+		query := msg.Copy()
+		query.Id = dns.Id()
+		reply, err := exchange(ctx, txp, query)
+		if err != nil {
+			check.result = fmt.Sprintf("query %d: %s", i+1, err)
+			return check
+		}
+		if reply.Id != query.Id {
+			check.result = fmt.Sprintf("query %d: ID mismatch, sent %d got %d", i+1, query.Id, reply.Id)
+			return check
+		}
+		answers := output.AnswerSet([]*dns.Msg{reply})
+		if i == 0 {
+			first = answers
+		} else if !slices.Equal(answers, first) {
+			check.result = fmt.Sprintf("query %d: answers differ from the first reply", i+1)
+			return check
+		}
+	}
+	check.result = fmt.Sprintf("%d replies with matching IDs and answers", postureDuplicates)
+	check.ok = true
+	return check
+}
+
+// poisoningResistance reports the anti-poisoning measures of a resolver that can be observed from the client side.
+// Source port and query ID entropy of the resolver's own upstream queries can't be measured without seeing them.
+func poisoningResistance(ctx context.Context, msgs []dns.Msg, txp *transport.Transport, out io.Writer) error {
+	if len(msgs) == 0 || len(msgs[0].Question) == 0 {
+		return fmt.Errorf("no question to send")
+	}
+	msg := msgs[0]
+
+	checks := []postureCheck{
+		checkCookies(ctx, txp, msg),
+		check0x20(ctx, txp, msg),
+		checkValidation(ctx, txp),
+		checkDuplicates(ctx, txp, msg),
+	}
+
+	width := 0
+	for _, c := range checks {
+		width = max(width, len(c.indicator))
+	}
+	for _, c := range checks {
+		color := util.ColorGreen
+		if !c.ok {
+			color = util.ColorRed
+		}
+		util.MustWritef(out, "%-*s %s\n", width, c.indicator, util.Color(color, c.result))
+	}
+	return nil
+}
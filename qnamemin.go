@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// classifyQNAMEMinimization describes whether the TXT answer of a QNAME minimization test name (such as the default,
+// qnamemintest.internet.nl) shows the resolver minimizing query names (RFC 9156), returning false if it doesn't or
+// the answer is inconclusive
+func classifyQNAMEMinimization(reply *dns.Msg) (string, bool) {
+	var texts []string
+	for _, rr := range reply.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			texts = append(texts, strings.Join(txt.Txt, ""))
+		}
+	}
+	text := strings.Join(texts, " ")
+
+	switch upper := strings.ToUpper(text); {
+	case reply.Rcode != dns.RcodeSuccess:
+		return fmt.Sprintf("inconclusive, test name returned %s", dns.RcodeToString[reply.Rcode]), false
+	case text == "":
+		return "inconclusive, no TXT answer from the test name", false
+	case strings.HasPrefix(upper, "HOORAY") || (strings.Contains(upper, "ENABLED") && !strings.Contains(upper, "NOT ENABLED")):
+		return fmt.Sprintf("minimized (%s)", text), true
+	case strings.HasPrefix(upper, "NO ") || strings.Contains(upper, "NOT ENABLED"):
+		return fmt.Sprintf("not minimized (%s)", text), false
+	default:
+		return fmt.Sprintf("inconclusive (%s)", text), false
+	}
+}
+
+// probeQNAMEMinimization queries the TXT record of a QNAME minimization test name through a resolver and reports
+// whether the resolver minimized the query names it sent to the test name's authoritative servers
+func probeQNAMEMinimization(ctx context.Context, name, server string, txp *transport.Transport, out io.Writer) error {
+	reply, err := queryType(ctx, txp, name, dns.TypeTXT)
+	if err != nil {
+		return fmt.Errorf("QNAME minimization probe: %s", err)
+	}
+
+	result, minimized := classifyQNAMEMinimization(reply)
+	color := util.ColorGreen
+	if !minimized {
+		color = util.ColorYellow
+	}
+	util.MustWritef(out, "%s %s %s\n", server, dns.Fqdn(name), util.Color(color, result))
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// classifyRecursion describes whether a reply to a recursive query shows the server recursing for clients
+func classifyRecursion(reply *dns.Msg) (string, bool) {
+	rcode := dns.RcodeToString[reply.Rcode]
+	switch {
+	case reply.Rcode == dns.RcodeRefused:
+		return "recursion refused (REFUSED)", false
+	case !reply.RecursionAvailable && reply.Authoritative:
+		return fmt.Sprintf("authoritative only, RA not set (%s)", rcode), false
+	case !reply.RecursionAvailable:
+		return fmt.Sprintf("recursion not available, RA not set (%s)", rcode), false
+	case reply.Authoritative:
+		return fmt.Sprintf("RA set, answered authoritatively (%s)", rcode), false
+	case reply.Rcode == dns.RcodeNameError || (reply.Rcode == dns.RcodeSuccess && len(reply.Answer) > 0):
+		return fmt.Sprintf("open to recursion, RA set and recursed (%s)", rcode), true
+	default:
+		return fmt.Sprintf("RA set but did not recurse (%s, %d answers)", rcode, len(reply.Answer)), false
+	}
+}
+
+// checkRecursion sends a recursive query and reports whether the server is open to recursion
+func checkRecursion(ctx context.Context, msgs []dns.Msg, server string, txp *transport.Transport, out io.Writer) error {
+	if len(msgs) == 0 {
+		return fmt.Errorf("no query to send")
+	}
+	msg := msgs[0].Copy()
+	msg.RecursionDesired = true
+
+	reply, err := exchange(ctx, txp, msg)
+	if err != nil {
+		return fmt.Errorf("recursion check: %s", err)
+	}
+
+	result, open := classifyRecursion(reply)
+	color := util.ColorGreen
+	if open {
+		color = util.ColorYellow
+	}
+	util.MustWritef(out, "%s %s %s\n", server, questionName(msg), util.Color(color, result))
+	return nil
+}
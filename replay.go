@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+// capturedExchange is a captured query and the response captured for it, nil if there wasn't one
+type capturedExchange struct {
+	Query    *dns.Msg
+	Response *dns.Msg
+}
+
+// pairCaptured pairs each captured query, in capture order, with the first later response sent back to the same
+// client address and port with the same ID and question
+func pairCaptured(msgs []pcapMessage) []capturedExchange {
+	var exchanges []capturedExchange
+	pending := make(map[string]int)
+	key := func(client string, msg *dns.Msg) string {
+		var question string
+		if len(msg.Question) > 0 {
+			question = strings.ToLower(msg.Question[0].String())
+		}
+		return fmt.Sprintf("%s %d %s", client, msg.Id, question)
+	}
+
+	for _, m := range msgs {
+		if !m.Msg.Response {
+			pending[key(m.Src.String(), m.Msg)] = len(exchanges)
+			exchanges = append(exchanges, capturedExchange{Query: m.Msg})
+			continue
+		}
+		k := key(m.Dst.String(), m.Msg)
+		if i, ok := pending[k]; ok {
+			exchanges[i].Response = m.Msg
+			delete(pending, k)
+		}
+	}
+	return exchanges
+}
+
+// compareReplay describes how a replayed response differs from the captured one in rcode and answers, ignoring
+// TTLs and record order, or returns an empty string if they match
+func compareReplay(captured, replayed *dns.Msg) string {
+	var diffs []string
+	if captured.Rcode != replayed.Rcode {
+		diffs = append(diffs, fmt.Sprintf("rcode %s → %s", dns.RcodeToString[captured.Rcode], dns.RcodeToString[replayed.Rcode]))
+	}
+
+	before, after := output.AnswerSet([]*dns.Msg{captured}), output.AnswerSet([]*dns.Msg{replayed})
+	for _, a := range before {
+		if !slices.Contains(after, a) {
+			diffs = append(diffs, "-"+a)
+		}
+	}
+	for _, a := range after {
+		if !slices.Contains(before, a) {
+			diffs = append(diffs, "+"+a)
+		}
+	}
+	return strings.Join(diffs, ", ")
+}
+
+// replayPcap resends the DNS queries captured in a pcap file to a server and reports whether each response matches
+// the one in the capture
+func replayPcap(ctx context.Context, path string, txp *transport.Transport, out io.Writer) error {
+	msgs, err := readPcap(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %s", path, err)
+	}
+	exchanges := pairCaptured(msgs)
+	if len(exchanges) == 0 {
+		return fmt.Errorf("no DNS queries in %s", path)
+	}
+	log.Debugf("Replaying %d queries from %s", len(exchanges), path)
+
+	var results []output.ReplayResult
+	for _, ex := range exchanges {
+		result := output.ReplayResult{Question: questionName(ex.Query)}
+		if len(ex.Query.Question) > 0 {
+			result.Question += " " + dns.TypeToString[ex.Query.Question[0].Qtype]
+		}
+
+		reply, err := exchange(ctx, txp, ex.Query.Copy())
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case ex.Response == nil:
+			result.Rcode = dns.RcodeToString[reply.Rcode]
+			result.Error = "no response in capture"
+		case ex.Response.Truncated:
+			// The full answer is in the TCP retry, which is replayed separately
+			result.Rcode = dns.RcodeToString[reply.Rcode]
+			result.Error = "captured response was truncated"
+		default:
+			result.Rcode = dns.RcodeToString[reply.Rcode]
+			result.Difference = compareReplay(ex.Response, reply)
+			result.Match = result.Difference == ""
+		}
+		results = append(results, result)
+	}
+
+	printer := output.Printer{
+		Out:  out,
+		Opts: &opts,
+	}
+	printer.PrintReplay(results)
+	return nil
+}
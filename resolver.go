@@ -1,27 +1,99 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net"
+	"net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/miekg/dns"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/proxy"
 
 	"github.com/natesales/q/cli"
+	"github.com/natesales/q/output"
 	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+	tlsutil "github.com/natesales/q/util/tls"
 )
 
+// resolver creates the transports of a run, holding the connection options that are parsed once per run
+type resolver struct {
+	clientCerts tlsutil.ClientCertificates // Client certificates to present to each server name, set by --tls-client-cert-for
+	proxyDialer proxy.Dialer               // SOCKS5 proxy to connect to servers through, set by --proxy
+	sourceIP    net.IP                     // Local address to send queries from, set by --source-ip
+}
+
+// newResolver parses the client certificate, proxy, and source address options
+func newResolver(opts cli.Flags) (*resolver, error) {
+	r := &resolver{}
+	var err error
+	if r.clientCerts, err = tlsutil.LoadClientCertificates(opts.TLSClientCertFor); err != nil {
+		return nil, err
+	}
+	if opts.Proxy != "" {
+		if r.proxyDialer, err = parseProxy(opts.Proxy); err != nil {
+			return nil, err
+		}
+	}
+	if opts.SourceIP != "" {
+		if r.sourceIP = net.ParseIP(opts.SourceIP); r.sourceIP == nil {
+			return nil, fmt.Errorf("invalid source IP %s", opts.SourceIP)
+		}
+	}
+	return r, nil
+}
+
+// common returns the transport options shared by every transport to a server
+func (r *resolver) common(server string) transport.Common {
+	return transport.Common{
+		Server:      server,
+		ReuseConn:   opts.ReuseConn,
+		ProxyDialer: r.proxyDialer,
+		SourceIP:    r.sourceIP,
+		Interface:   opts.Interface,
+	}
+}
+
+// queryID is the next query ID with --id-sequential, kept wider than an ID so it wraps when truncated to 16 bits
+var queryID atomic.Uint32
+
+// nextQueryID returns the next ID in the --id-sequential sequence
+func nextQueryID() uint16 {
+	return uint16(queryID.Add(1) - 1)
+}
+
+// ednsKeyTag is the EDNS0 option code of edns-key-tag (RFC 8145)
+const ednsKeyTag = 14
+
 // createQuery creates a slice of DNS queries
 func createQuery(opts cli.Flags, rrTypes []uint16) []dns.Msg {
 	var queries []dns.Msg
 
+	// Generate a client cookie shared by every query
+	cookie := opts.Cookie
+	if cookie == cookieAuto {
+		cookie = fmt.Sprintf("%016x", rand.Uint64())
+		log.Debugf("Using random client cookie %s", cookie)
+	}
+
 	// Query for each requested RR type
 	for _, qType := range rrTypes {
 		req := dns.Msg{}
 
-		if opts.ID != -1 {
+		if opts.IDSequential {
+			req.Id = nextQueryID()
+		} else if opts.ID != -1 {
 			req.Id = uint16(opts.ID)
 		} else {
 			req.Id = dns.Id()
@@ -33,8 +105,9 @@ func createQuery(opts cli.Flags, rrTypes []uint16) []dns.Msg {
 		req.RecursionAvailable = opts.RecursionAvailable
 		req.Zero = opts.Zero
 		req.Truncated = opts.Truncated
+		req.Compress = !opts.NoCompression
 
-		if opts.DNSSEC || opts.NSID || opts.Pad || opts.ClientSubnet != "" || opts.Cookie != "" {
+		if opts.DNSSEC || opts.CompactOK || opts.NSID || opts.Pad || opts.ClientSubnet != "" || opts.Cookie != "" || opts.EDNSVersion != 0 || opts.Expire || opts.KeyTags != "" {
 			opt := &dns.OPT{
 				Hdr: dns.RR_Header{
 					Name:   ".",
@@ -47,12 +120,40 @@ func createQuery(opts cli.Flags, rrTypes []uint16) []dns.Msg {
 				opt.SetDo()
 			}
 
+			if opts.CompactOK {
+				opt.SetCo()
+			}
+
+			if opts.EDNSVersion != 0 {
+				opt.SetVersion(opts.EDNSVersion)
+			}
+
 			if opts.NSID {
 				opt.Option = append(opt.Option, &dns.EDNS0_NSID{
 					Code: dns.EDNS0NSID,
 				})
 			}
 
+			if opts.Expire {
+				opt.Option = append(opt.Option, &dns.EDNS0_EXPIRE{
+					Code:  dns.EDNS0EXPIRE,
+					Empty: true,
+				})
+			}
+
+			// miekg/dns has no type for the key tag option, so encode its list of 16-bit tags directly (RFC 8145 section 4.1)
+			if opts.KeyTags != "" {
+				tags, _ := cli.ParseKeyTags(opts.KeyTags)
+				data := make([]byte, 0, 2*len(tags))
+				for _, tag := range tags {
+					data = binary.BigEndian.AppendUint16(data, tag)
+				}
+				opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{
+					Code: ednsKeyTag,
+					Data: data,
+				})
+			}
+
 			if opts.Pad {
 				paddingOpt := new(dns.EDNS0_PADDING)
 
@@ -93,12 +194,11 @@ func createQuery(opts cli.Flags, rrTypes []uint16) []dns.Msg {
 				opt.Option = append(opt.Option, ednsSubnet)
 			}
 
-			if opts.Cookie != "" {
-				cookie := &dns.EDNS0_COOKIE{
+			if cookie != "" {
+				opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{
 					Code:   dns.EDNS0COOKIE,
-					Cookie: opts.Cookie,
-				}
-				opt.Option = append(opt.Option, cookie)
+					Cookie: cookie,
+				})
 			}
 
 			req.Extra = append(req.Extra, opt)
@@ -107,21 +207,108 @@ func createQuery(opts cli.Flags, rrTypes []uint16) []dns.Msg {
 		req.Question = []dns.Question{{
 			Name:   dns.Fqdn(opts.Name),
 			Qtype:  qType,
-			Qclass: opts.Class,
+			Qclass: uint16(opts.Class),
 		}}
 
+		// IXFR queries carry the SOA of the version the client has in the authority section (RFC 1995 section 3)
+		if qType == dns.TypeIXFR {
+			req.Ns = []dns.RR{&dns.SOA{
+				Hdr:    dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeSOA, Class: dns.ClassINET},
+				Ns:     ".",
+				Mbox:   ".",
+				Serial: opts.IXFRSerial,
+			}}
+		}
+
+		// The TSIG record must be the last additional record
+		signTSIG(&req)
+
+		log.Debugf("Query for %s %s is %d bytes (compression %t)", req.Question[0].Name, dns.TypeToString[qType], req.Len(), req.Compress)
 		queries = append(queries, req)
 	}
 	return queries
 }
 
+// serverHostname returns the host of a server address or URL without its port
+func serverHostname(server string) string {
+	if strings.Contains(server, "://") {
+		if u, err := url.Parse(server); err == nil {
+			return u.Hostname()
+		}
+	}
+	if host, _, err := net.SplitHostPort(server); err == nil {
+		return host
+	}
+	return server
+}
+
+// clientCertConfig returns a copy of a TLS config that presents the client certificate configured for the server's
+// name, which is the TLS server name if set or otherwise the server's hostname
+func (r *resolver) clientCertConfig(tlsConfig *tls.Config, server string) *tls.Config {
+	name := tlsConfig.ServerName
+	if name == "" {
+		name = serverHostname(server)
+	}
+	c := tlsConfig.Clone()
+	c.GetClientCertificate = r.clientCerts.GetClientCertificate(name, tlsConfig.Certificates)
+	return c
+}
+
+// parseProxy creates a dialer for a socks5:// proxy URL, authenticating with the username and password in it if set
+func parseProxy(s string) (proxy.Dialer, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing proxy %s: %s", s, err)
+	}
+	if (u.Scheme != "socks5" && u.Scheme != "socks5h") || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %s, expected socks5://[user:pass@]host:port", s)
+	}
+	return proxy.FromURL(u, proxy.Direct)
+}
+
+// checkSourceFamily returns an error if the server is an IP address of a different family than the source IP. Servers
+// given by name are only dialed at addresses of the source IP's family.
+func (r *resolver) checkSourceFamily(server string) error {
+	ip := net.ParseIP(serverHostname(server))
+	if r.sourceIP == nil || ip == nil {
+		return nil
+	}
+	family := func(ip net.IP) string {
+		if ip.To4() != nil {
+			return "IPv4"
+		}
+		return "IPv6"
+	}
+	if family(r.sourceIP) != family(ip) {
+		return fmt.Errorf("source IP %s is %s but server %s is %s", r.sourceIP, family(r.sourceIP), server, family(ip))
+	}
+	return nil
+}
+
 // newTransport creates a new transport based on local options
-func newTransport(server string, transportType transport.Type, tlsConfig *tls.Config) (*transport.Transport, error) {
+func (r *resolver) newTransport(server string, transportType transport.Type, tlsConfig *tls.Config) (*transport.Transport, error) {
 	var ts transport.Transport
+	if len(r.clientCerts) > 0 && tlsConfig != nil {
+		tlsConfig = r.clientCertConfig(tlsConfig, server)
+	}
 
-	common := transport.Common{
-		Server:    server,
-		ReuseConn: opts.ReuseConn,
+	common := r.common(server)
+
+	// QUIC and DNSCrypt run over UDP, and the WebSocket dialer can't be replaced
+	if r.proxyDialer != nil && (transportType == transport.TypeQUIC || transportType == transport.TypeDNSCrypt ||
+		transportType == transport.TypeWS || (transportType == transport.TypeHTTP && opts.HTTP3)) {
+		return nil, fmt.Errorf("%s transport can't be used through a SOCKS5 proxy", transportType)
+	}
+
+	// The QUIC and DNSCrypt libraries open their own sockets
+	if common.SourceIP != nil || common.Interface != "" {
+		if transportType == transport.TypeQUIC || transportType == transport.TypeDNSCrypt ||
+			(transportType == transport.TypeHTTP && opts.HTTP3) {
+			return nil, fmt.Errorf("%s transport can't be used with --source-ip or --interface", transportType)
+		}
+		if err := r.checkSourceFamily(server); err != nil {
+			return nil, err
+		}
 	}
 
 	switch transportType {
@@ -132,6 +319,7 @@ func newTransport(server string, transportType transport.Type, tlsConfig *tls.Co
 				Common:    common,
 				Proxy:     opts.ODoHProxy,
 				TLSConfig: tlsConfig,
+				Timeout:   opts.Timeout,
 			}
 		} else {
 			log.Debugf("Using HTTP(s) transport: %s", server)
@@ -159,8 +347,16 @@ func newTransport(server string, transportType transport.Type, tlsConfig *tls.Co
 				HTTP3:     opts.HTTP3,
 				NoPMTUd:   !opts.PMTUD,
 				Headers:   headers,
+				Timeout:   opts.Timeout,
 			}
 		}
+	case transport.TypeWS:
+		log.Debugf("Using WebSocket transport: %s", server)
+		ts = &transport.WebSocket{
+			Common:    common,
+			TLSConfig: tlsConfig,
+			Timeout:   opts.Timeout,
+		}
 	case transport.TypeDNSCrypt:
 		log.Debugf("Using DNSCrypt transport: %s", server)
 		if strings.HasPrefix(server, "sdns://") {
@@ -170,6 +366,7 @@ func newTransport(server string, transportType transport.Type, tlsConfig *tls.Co
 				ServerStamp: server,
 				TCP:         opts.DNSCryptTCP,
 				UDPSize:     opts.DNSCryptUDPSize,
+				Timeout:     opts.Timeout,
 			}
 		} else {
 			log.Traceln("Using manual DNSCrypt configuration")
@@ -179,6 +376,7 @@ func newTransport(server string, transportType transport.Type, tlsConfig *tls.Co
 				UDPSize:      opts.DNSCryptUDPSize,
 				PublicKey:    opts.DNSCryptPublicKey,
 				ProviderName: opts.DNSCryptProvider,
+				Timeout:      opts.Timeout,
 			}
 		}
 	case transport.TypeQUIC:
@@ -192,28 +390,37 @@ func newTransport(server string, transportType transport.Type, tlsConfig *tls.Co
 			TLSConfig:       tc,
 			PMTUD:           opts.PMTUD,
 			AddLengthPrefix: opts.QUICLengthPrefix,
+			Timeout:         opts.Timeout,
 		}
 	case transport.TypeTLS:
 		log.Debugf("Using TLS transport: %s", server)
 		ts = &transport.TLS{
 			Common:    common,
 			TLSConfig: tlsConfig,
+			TFO:       opts.TFO,
+			Timeout:   opts.Timeout,
 		}
 	case transport.TypeTCP:
 		log.Debugf("Using TCP transport: %s", server)
 		ts = &transport.Plain{
-			Common:    common,
-			PreferTCP: true,
-			UDPBuffer: opts.UDPBuffer,
-			Timeout:   opts.Timeout,
+			Common:     common,
+			PreferTCP:  true,
+			UDPBuffer:  opts.UDPBuffer,
+			Timeout:    opts.Timeout,
+			TFO:        opts.TFO,
+			SourcePort: opts.SourcePort,
+			TsigSecret: tsigSecrets(),
 		}
 	case transport.TypePlain:
 		log.Debugf("Using UDP with TCP fallback: %s", server)
 		ts = &transport.Plain{
-			Common:    common,
-			PreferTCP: false,
-			UDPBuffer: opts.UDPBuffer,
-			Timeout:   opts.Timeout,
+			Common:     common,
+			PreferTCP:  false,
+			UDPBuffer:  opts.UDPBuffer,
+			Timeout:    opts.Timeout,
+			TFO:        opts.TFO,
+			SourcePort: opts.SourcePort,
+			TsigSecret: tsigSecrets(),
 		}
 	default:
 		return nil, fmt.Errorf("unknown transport protocol %s", transportType)
@@ -221,3 +428,194 @@ func newTransport(server string, transportType transport.Type, tlsConfig *tls.Co
 
 	return &ts, nil
 }
+
+// Retry failure categories
+const (
+	retryTimeout  = "timeout"
+	retryNetwork  = "network"
+	retryServfail = "servfail"
+	retryRefused  = "refused"
+	retryFormerr  = "formerr"
+	retryNXDomain = "nxdomain"
+)
+
+// retryCategories is a list of all failure categories that can be retried
+var retryCategories = []string{retryTimeout, retryNetwork, retryServfail, retryRefused, retryFormerr, retryNXDomain}
+
+// failureCategory classifies the result of an exchange into a retry category, returning an empty string on success
+func failureCategory(reply *dns.Msg, err error) string {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return retryTimeout
+		}
+		if netErr != nil ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) {
+			return retryNetwork
+		}
+		return "error"
+	}
+	if reply == nil {
+		return retryNetwork
+	}
+
+	switch reply.Rcode {
+	case dns.RcodeServerFailure:
+		return retryServfail
+	case dns.RcodeRefused:
+		return retryRefused
+	case dns.RcodeFormatError:
+		return retryFormerr
+	case dns.RcodeNameError:
+		return retryNXDomain
+	}
+	return ""
+}
+
+// retryDelay returns how long to wait before a retry, doubling opts.RetryBackoff after every attempt
+func retryDelay(attempt int) time.Duration {
+	return opts.RetryBackoff << (attempt - 1)
+}
+
+// exchange sends a message over a transport, retrying up to opts.Retry times on the failure categories in opts.RetryOn.
+// Each attempt times out after opts.Timeout on its own, so retries and their backoff aren't limited by a total budget.
+func exchange(ctx context.Context, txp *transport.Transport, msg *dns.Msg) (*dns.Msg, error) {
+	var reply *dns.Msg
+	var err error
+	attempt := 0
+	for ; attempt <= opts.Retry; attempt++ {
+		if attempt > 0 {
+			if delay := retryDelay(attempt); delay > 0 {
+				log.Debugf("Waiting %s before retrying %s", delay, questionName(msg))
+				if err := sleep(ctx, delay); err != nil {
+					return nil, err
+				}
+			}
+			if opts.RetryRandomID && opts.IDSequential {
+				msg.Id = nextQueryID()
+			} else if opts.RetryRandomID {
+				msg.Id = dns.Id()
+			}
+		}
+		log.Debugf("Attempt %d for %s with ID %d", attempt+1, questionName(msg), msg.Id)
+		reply, err = exchangeAttempt(ctx, txp, msg)
+		category := failureCategory(reply, err)
+		if category == "" || !slices.Contains(opts.RetryOn, category) || ctx.Err() != nil {
+			break
+		}
+		if attempt < opts.Retry {
+			log.Debugf("Attempt %d for %s failed (%s), retrying", attempt+1, questionName(msg), category)
+		}
+	}
+	log.Debugf("Made %d attempt(s) for %s", min(attempt+1, opts.Retry+1), questionName(msg))
+	return reply, err
+}
+
+// sleep waits for a duration, returning the context's error if it's done first
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// questionName returns the name of a message's first question, or "." if it has none
+func questionName(msg *dns.Msg) string {
+	if len(msg.Question) == 0 {
+		return "."
+	}
+	return msg.Question[0].Name
+}
+
+// exchangeAttempt sends a message over a transport, retrying once if the server asks for a different cookie (BADCOOKIE) or EDNS version (BADVERS)
+func exchangeAttempt(ctx context.Context, txp *transport.Transport, msg *dns.Msg) (*dns.Msg, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	reply, err := (*txp).Exchange(ctx, msg)
+	if err != nil || reply == nil {
+		return reply, err
+	}
+
+	switch reply.Rcode {
+	case dns.RcodeBadCookie:
+		if retryBadCookie(msg, reply) {
+			return (*txp).Exchange(ctx, msg)
+		}
+	case dns.RcodeBadVers:
+		if retryBadVers(msg, reply) {
+			return (*txp).Exchange(ctx, msg)
+		}
+	}
+	return reply, err
+}
+
+// retryBadCookie updates msg with the server cookie from a BADCOOKIE reply (RFC 7873 section 5.3) and returns whether to retry
+func retryBadCookie(msg, reply *dns.Msg) bool {
+	sent, sentOk := util.EDNSOption[*dns.EDNS0_COOKIE](msg)
+	received, receivedOk := util.EDNSOption[*dns.EDNS0_COOKIE](reply)
+	if !sentOk || !receivedOk || len(received.Cookie) <= 16 {
+		log.Debugf("BADCOOKIE from server without a server cookie, not retrying")
+		return false
+	}
+
+	log.Infof("Server responded with BADCOOKIE, retrying with server cookie %s", received.Cookie[16:])
+	sent.Cookie = received.Cookie
+	return true
+}
+
+// retryBadVers downgrades msg to the EDNS version advertised in a BADVERS reply (RFC 6891 section 6.1.3) and returns whether to retry
+func retryBadVers(msg, reply *dns.Msg) bool {
+	sent := msg.IsEdns0()
+	received := reply.IsEdns0()
+	if sent == nil || received == nil || received.Version() >= sent.Version() {
+		log.Debugf("BADVERS from server without a lower EDNS version, not retrying")
+		return false
+	}
+
+	log.Infof("Server responded with BADVERS, retrying with EDNS version %d", received.Version())
+	sent.SetVersion(received.Version())
+	return true
+}
+
+// queryType sends a single query for a name and type over a transport using the global query options
+func queryType(ctx context.Context, txp *transport.Transport, name string, qType uint16) (*dns.Msg, error) {
+	o := opts
+	o.Name = name
+	msg := createQuery(o, []uint16{qType})[0]
+	return exchange(ctx, txp, &msg)
+}
+
+// verifyReply sends a query again and compares its answer set to the first reply, returning the differing answers if any
+func verifyReply(ctx context.Context, txp *transport.Transport, msg *dns.Msg, reply *dns.Msg) (*output.Inconsistency, error) {
+	second, err := exchange(ctx, txp, msg.Copy())
+	if err != nil {
+		return nil, err
+	}
+	if second == nil {
+		return nil, fmt.Errorf("no reply from server")
+	}
+
+	first := output.AnswerSet([]*dns.Msg{reply})
+	repeated := output.AnswerSet([]*dns.Msg{second})
+	if slices.Equal(first, repeated) {
+		return nil, nil
+	}
+
+	q := msg.Question[0]
+	log.Debugf("Inconsistent answers for %s %s: %v != %v", q.Name, dns.TypeToString[q.Qtype], first, repeated)
+	return &output.Inconsistency{
+		Question: fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype]),
+		First:    first,
+		Second:   repeated,
+	}, nil
+}
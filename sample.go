@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+// takeSample sends a query and records its latency and rcode
+func takeSample(ctx context.Context, txp *transport.Transport, msg dns.Msg, server string) output.LatencySample {
+	sample := output.LatencySample{
+		Timestamp: time.Now(),
+		Server:    server,
+	}
+	if len(msg.Question) > 0 {
+		sample.Name = msg.Question[0].Name
+		sample.Type = dns.TypeToString[msg.Question[0].Qtype]
+	}
+
+	reply, err := exchange(ctx, txp, msg.Copy())
+	sample.LatencyMs = float64(time.Since(sample.Timestamp).Microseconds()) / 1000
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	sample.Rcode = dns.RcodeToString[reply.Rcode]
+	return sample
+}
+
+// sampleLatency sends every query at a fixed interval for a fixed duration, writing a timestamped latency series
+// as each sample is taken
+func sampleLatency(ctx context.Context, msgs []dns.Msg, server string, txp *transport.Transport, out io.Writer) error {
+	if opts.SampleInterval <= 0 {
+		return fmt.Errorf("sample interval must be positive")
+	}
+	w, err := output.NewSampleWriter(out, opts.SampleFormat)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(opts.SampleDuration)
+	ticker := time.NewTicker(opts.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, msg := range msgs {
+			sample := takeSample(ctx, txp, msg, server)
+			if sample.Error != "" {
+				log.Debugf("Sample for %s %s: %s", sample.Name, sample.Type, sample.Error)
+			}
+			if err := w.Write(sample); err != nil {
+				return fmt.Errorf("writing sample: %s", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t := <-ticker.C:
+			if !t.Before(deadline) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// querySecondary queries an authoritative server directly for a zone's SOA with an empty EDNS0 expire option (RFC 7314)
+func querySecondary(ctx context.Context, r *resolver, zone, nameserver, addr, port string) output.SecondaryStatus {
+	status := output.SecondaryStatus{Nameserver: nameserver, Address: addr}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(zone, dns.TypeSOA)
+	msg.RecursionDesired = false
+	msg.SetEdns0(opts.UDPBuffer, false)
+	msg.IsEdns0().Option = append(msg.IsEdns0().Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Empty: true})
+
+	var txp transport.Transport = &transport.Plain{
+		Common:    r.common(net.JoinHostPort(addr, port)),
+		UDPBuffer: opts.UDPBuffer,
+		Timeout:   opts.Timeout,
+	}
+	defer txp.Close()
+	reply, err := exchange(ctx, &txp, msg)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if reply.Rcode != dns.RcodeSuccess {
+		status.Error = dns.RcodeToString[reply.Rcode]
+		return status
+	}
+
+	// Serial 0 is valid, so track whether there was an SOA separately
+	var found bool
+	for _, rr := range reply.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			status.Serial = soa.Serial
+			found = true
+		}
+	}
+	if !found {
+		status.Error = "no SOA in answer"
+		return status
+	}
+
+	if expire, ok := util.EDNSOption[*dns.EDNS0_EXPIRE](reply); ok && !expire.Empty {
+		status.Expire = &expire.Expire
+	}
+	return status
+}
+
+// checkSecondaries reports the SOA serial and expire timer of every authoritative server for a zone
+func checkSecondaries(ctx context.Context, r *resolver, zone, port string, txp *transport.Transport, out io.Writer) error {
+	zone = dns.Fqdn(zone)
+	reply, err := queryType(ctx, txp, zone, dns.TypeNS)
+	if err != nil {
+		return fmt.Errorf("resolving NS records for %s: %s", zone, err)
+	}
+
+	var statuses []output.SecondaryStatus
+	for _, rr := range reply.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		var addrs []string
+		for _, qType := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			addrReply, err := queryType(ctx, txp, ns.Ns, qType)
+			if err != nil {
+				log.Warnf("resolving %s %s: %s", ns.Ns, dns.TypeToString[qType], err)
+				continue
+			}
+			for _, rr := range addrReply.Answer {
+				switch rr := rr.(type) {
+				case *dns.A:
+					addrs = append(addrs, rr.A.String())
+				case *dns.AAAA:
+					addrs = append(addrs, rr.AAAA.String())
+				}
+			}
+		}
+		if len(addrs) == 0 {
+			statuses = append(statuses, output.SecondaryStatus{Nameserver: ns.Ns, Error: "no addresses"})
+			continue
+		}
+
+		for _, addr := range addrs {
+			log.Debugf("Querying %s (%s) for %s SOA", ns.Ns, addr, zone)
+			statuses = append(statuses, querySecondary(ctx, r, zone, ns.Ns, addr, port))
+		}
+	}
+	if len(statuses) == 0 {
+		return fmt.Errorf("no NS records found for %s", zone)
+	}
+
+	// Flag servers that aren't serving the newest serial
+	var newest uint32
+	var seeded bool
+	for _, s := range statuses {
+		if s.Error == "" && (!seeded || dnsSerialNewer(s.Serial, newest)) {
+			newest = s.Serial
+			seeded = true
+		}
+	}
+	for i := range statuses {
+		statuses[i].SerialMismatch = statuses[i].Error == "" && statuses[i].Serial != newest
+	}
+
+	printer := output.Printer{
+		Out:  out,
+		Opts: &opts,
+	}
+	printer.PrintSecondaries(statuses)
+	return nil
+}
+
+// dnsSerialNewer reports whether SOA serial a is newer than b using RFC 1982 serial number arithmetic
+func dnsSerialNewer(a, b uint32) bool {
+	return a != b && int32(a-b) > 0
+}
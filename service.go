@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+// resolveAddrs returns the A and AAAA addresses of a name
+func resolveAddrs(ctx context.Context, txp *transport.Transport, name string) []string {
+	var addrs []string
+	for _, qType := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		reply, err := queryType(ctx, txp, name, qType)
+		if err != nil {
+			log.Warnf("resolving %s %s: %s", name, dns.TypeToString[qType], err)
+			continue
+		}
+		for _, rr := range reply.Answer {
+			switch rr := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, rr.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, rr.AAAA.String())
+			}
+		}
+	}
+	return addrs
+}
+
+// resolveInstance looks up the SRV and TXT records of a DNS-SD service instance and the addresses of its SRV targets
+func resolveInstance(ctx context.Context, txp *transport.Transport, name string) output.ServiceInstance {
+	instance := output.ServiceInstance{Name: name, Endpoints: []output.ServiceEndpoint{}, TXT: []string{}}
+
+	reply, err := queryType(ctx, txp, name, dns.TypeSRV)
+	if err != nil {
+		instance.Error = fmt.Sprintf("resolving SRV: %s", err)
+		return instance
+	}
+	for _, rr := range reply.Answer {
+		if srv, ok := rr.(*dns.SRV); ok {
+			instance.Endpoints = append(instance.Endpoints, output.ServiceEndpoint{
+				Target:    srv.Target,
+				Port:      srv.Port,
+				Priority:  srv.Priority,
+				Weight:    srv.Weight,
+				Addresses: resolveAddrs(ctx, txp, srv.Target),
+			})
+		}
+	}
+	if len(instance.Endpoints) == 0 {
+		instance.Error = "no SRV records"
+	}
+
+	reply, err = queryType(ctx, txp, name, dns.TypeTXT)
+	if err != nil {
+		log.Warnf("resolving %s TXT: %s", name, err)
+		return instance
+	}
+	for _, rr := range reply.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			for _, s := range txt.Txt {
+				// A single empty string means the instance has no metadata (RFC 6763 section 6.1)
+				if s != "" {
+					instance.TXT = append(instance.TXT, s)
+				}
+			}
+		}
+	}
+	return instance
+}
+
+// discoverService browses a DNS-SD service type in a domain (e.g. _http._tcp in example.com) by querying its
+// PTR records for instances, then resolving each instance (RFC 6763 section 4)
+func discoverService(ctx context.Context, service, domain string, txp *transport.Transport, out io.Writer) error {
+	name := dns.Fqdn(service)
+	if domain != "" && domain != "." {
+		name = dns.Fqdn(strings.TrimSuffix(service, ".") + "." + domain)
+	}
+
+	reply, err := queryType(ctx, txp, name, dns.TypePTR)
+	if err != nil {
+		return fmt.Errorf("browsing %s: %s", name, err)
+	}
+
+	instances := []output.ServiceInstance{}
+	for _, rr := range reply.Answer {
+		if ptr, ok := rr.(*dns.PTR); ok {
+			log.Debugf("Resolving service instance %s", ptr.Ptr)
+			instances = append(instances, resolveInstance(ctx, txp, ptr.Ptr))
+		}
+	}
+
+	printer := output.Printer{
+		Out:  out,
+		Opts: &opts,
+	}
+	printer.PrintServices(name, instances)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/netip"
+	"sync"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+const (
+	sweepWarnAddresses = 1 << 10 // Warn when sweeping more than a /22 worth of addresses
+	sweepMaxAddresses  = 1 << 16 // Refuse to sweep more than a /16 (or IPv6 /112) worth of addresses
+)
+
+// sweepAddrs returns every address in a CIDR range
+func sweepAddrs(cidr string) ([]netip.Addr, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sweep range: %s", err)
+	}
+	prefix = prefix.Masked()
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("sweep range %s is too large (more than %d addresses)", prefix, sweepMaxAddresses)
+	}
+	if 1<<hostBits > sweepWarnAddresses {
+		log.Warnf("Sweeping %d addresses in %s, this may take a while", 1<<hostBits, prefix)
+	}
+
+	var addrs []netip.Addr
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// sweep queries PTR records for every address in a CIDR range and prints the addresses that have one
+func sweep(ctx context.Context, r *resolver, cidr, server string, transportType transport.Type, tlsConfig *tls.Config, out io.Writer) error {
+	addrs, err := sweepAddrs(cidr)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Sweeping %d addresses in %s", len(addrs), cidr)
+
+	// Create every transport before starting the workers so that none are left running if one fails
+	txps := make([]*transport.Transport, max(opts.SweepConcurrency, 1))
+	for w := range txps {
+		txp, err := r.newTransport(server, transportType, tlsConfig)
+		if err != nil {
+			for _, t := range txps[:w] {
+				(*t).Close()
+			}
+			return fmt.Errorf("creating transport: %s", err)
+		}
+		txps[w] = txp
+	}
+
+	results := make([][]string, len(addrs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for _, txp := range txps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer (*txp).Close()
+
+			for i := range jobs {
+				qname, err := dns.ReverseAddr(addrs[i].String())
+				if err != nil {
+					log.Warnf("reversing %s: %s", addrs[i], err)
+					continue
+				}
+
+				reply, err := queryType(ctx, txp, qname, dns.TypePTR)
+				if err != nil {
+					log.Warnf("PTR lookup for %s: %s", addrs[i], err)
+					continue
+				}
+				for _, rr := range reply.Answer {
+					if ptr, ok := rr.(*dns.PTR); ok {
+						results[i] = append(results[i], ptr.Ptr)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range addrs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var sweepResults []output.SweepResult
+	for i, ptrs := range results {
+		if len(ptrs) > 0 {
+			sweepResults = append(sweepResults, output.SweepResult{IP: addrs[i].String(), PTRs: ptrs})
+		}
+	}
+
+	printer := output.Printer{
+		Out:  out,
+		Opts: &opts,
+	}
+	printer.PrintSweep(sweepResults)
+	return nil
+}
@@ -0,0 +1,72 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+	"net/url"
+	"strings"
+
+	"github.com/natesales/q/output"
+)
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+	"auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG, "lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS,
+	"uucp": syslog.LOG_UUCP, "cron": syslog.LOG_CRON, "authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+	"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5, "local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+var syslogSeverities = map[string]syslog.Priority{
+	"emerg": syslog.LOG_EMERG, "alert": syslog.LOG_ALERT, "crit": syslog.LOG_CRIT, "err": syslog.LOG_ERR,
+	"warning": syslog.LOG_WARNING, "notice": syslog.LOG_NOTICE, "info": syslog.LOG_INFO, "debug": syslog.LOG_DEBUG,
+}
+
+// syslogPriority parses a facility and severity by name
+func syslogPriority(facility, severity string) (syslog.Priority, error) {
+	f, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return 0, fmt.Errorf("invalid syslog facility %s", facility)
+	}
+	s, ok := syslogSeverities[strings.ToLower(severity)]
+	if !ok {
+		return 0, fmt.Errorf("invalid syslog severity %s", severity)
+	}
+	return f | s, nil
+}
+
+// sendSyslog sends each query result to the local syslog daemon, or to a remote server given as udp://host:port or tcp://host:port
+func sendSyslog(entries []*output.Entry) error {
+	priority, err := syslogPriority(opts.SyslogFacility, opts.SyslogSeverity)
+	if err != nil {
+		return err
+	}
+
+	var network, addr string
+	if opts.SyslogServer != "" {
+		u, err := url.Parse(opts.SyslogServer)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid syslog server %s (expected udp://host:port or tcp://host:port)", opts.SyslogServer)
+		}
+		network, addr = u.Scheme, u.Host
+	}
+
+	w, err := syslog.Dial(network, addr, priority, "q")
+	if err != nil {
+		return fmt.Errorf("connecting to syslog: %s", err)
+	}
+	defer w.Close()
+
+	messages, err := output.SyslogMessages(entries, opts.SyslogJSON)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			return fmt.Errorf("writing to syslog: %s", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/natesales/q/output"
+)
+
+// sendSyslog is unsupported on platforms without log/syslog
+func sendSyslog(_ []*output.Entry) error {
+	return fmt.Errorf("syslog is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// tailAnswers queries the server at the --tail interval, appending the output of each poll under a timestamp and
+// noting whether the answers changed since the previous poll, so the result reads as a chronological log
+func tailAnswers(ctx context.Context, msgs []dns.Msg, server string, txp *transport.Transport, out io.Writer) error {
+	if opts.Tail <= 0 {
+		return fmt.Errorf("tail interval must be positive")
+	}
+	ticker := time.NewTicker(opts.Tail)
+	defer ticker.Stop()
+
+	var previous []string
+	for poll := 1; ; poll++ {
+		start := time.Now()
+		entry := &output.Entry{Server: server}
+		var pollErr error
+		for i := range msgs {
+			reply, err := exchange(ctx, txp, msgs[i].Copy())
+			if err != nil {
+				pollErr = err
+				break
+			}
+			entry.Queries = append(entry.Queries, msgs[i])
+			entry.Replies = append(entry.Replies, reply)
+		}
+		entry.Time = time.Since(start)
+
+		header := util.Color(util.ColorWhite, "=== "+start.Format(time.RFC3339))
+		if pollErr != nil {
+			util.MustWritef(out, "%s %s\n", header, util.Color(util.ColorRed, "error: "+pollErr.Error()))
+		} else {
+			answers := output.AnswerSet(entry.Replies)
+			switch {
+			case poll == 1:
+				util.MustWriteln(out, header)
+			case slices.Equal(answers, previous):
+				util.MustWritef(out, "%s %s\n", header, util.Color(util.ColorGreen, "unchanged"))
+			default:
+				util.MustWritef(out, "%s %s\n", header, util.Color(util.ColorYellow, "changed"))
+			}
+			previous = answers
+
+			var buf bytes.Buffer
+			if err := printEntries(output.Printer{Out: &buf, Opts: &opts}, []*output.Entry{entry}); err != nil {
+				return err
+			}
+			if _, err := out.Write(buf.Bytes()); err != nil {
+				return err
+			}
+		}
+
+		if opts.TailCount > 0 && poll >= opts.TailCount {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+// privateAddress returns whether an address can't be reached from the public internet
+func privateAddress(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
+
+// resolveTarget resolves the addresses of an NS or MX target, recording those it finds and any problems with them. NS
+// and MX targets must have address records of their own, so a CNAME is a problem even if it resolves (RFC 2181 section
+// 10.3).
+func resolveTarget(ctx context.Context, txp *transport.Transport, s *output.TargetStatus) {
+	var cname, rcode string
+	for _, qType := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		reply, err := queryType(ctx, txp, s.Target, qType)
+		if err != nil {
+			s.Problems = append(s.Problems, fmt.Sprintf("resolving %s: %s", dns.TypeToString[qType], err))
+			continue
+		}
+		if reply.Rcode != dns.RcodeSuccess {
+			rcode = dns.RcodeToString[reply.Rcode]
+		}
+
+		for _, rr := range reply.Answer {
+			var ip net.IP
+			switch rr := rr.(type) {
+			case *dns.CNAME:
+				if cname == "" && dns.CanonicalName(rr.Hdr.Name) == dns.CanonicalName(s.Target) {
+					cname = rr.Target
+				}
+				continue
+			case *dns.A:
+				ip = rr.A
+			case *dns.AAAA:
+				ip = rr.AAAA
+			default:
+				continue
+			}
+			s.Addresses = append(s.Addresses, ip.String())
+			if privateAddress(ip) {
+				s.Problems = append(s.Problems, fmt.Sprintf("private address %s", ip))
+			}
+		}
+	}
+
+	if cname != "" {
+		s.Problems = append(s.Problems, fmt.Sprintf("CNAME to %s", cname))
+	}
+	if len(s.Addresses) == 0 {
+		problem := "doesn't resolve"
+		if rcode != "" {
+			problem += fmt.Sprintf(" (%s)", rcode)
+		}
+		s.Problems = append(s.Problems, problem)
+	}
+}
+
+// validateTargets sends the NS and MX queries, resolves the target of each record in their answers, and reports any
+// that don't resolve, are CNAMEs, or point to private addresses
+func validateTargets(ctx context.Context, msgs []dns.Msg, txp *transport.Transport, out io.Writer) error {
+	var statuses []output.TargetStatus
+	var queried []string
+	for i := range msgs {
+		q := msgs[i].Question[0]
+		if q.Qtype != dns.TypeNS && q.Qtype != dns.TypeMX {
+			continue
+		}
+		queried = append(queried, fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype]))
+
+		reply, err := exchange(ctx, txp, msgs[i].Copy())
+		if err != nil {
+			return fmt.Errorf("querying %s %s: %s", q.Name, dns.TypeToString[q.Qtype], err)
+		}
+		for _, rr := range reply.Answer {
+			var target string
+			switch rr := rr.(type) {
+			case *dns.NS:
+				target = rr.Ns
+			case *dns.MX:
+				// A null MX (RFC 7505) means the domain doesn't accept mail, so there's nothing to resolve
+				if rr.Mx == "." {
+					continue
+				}
+				target = rr.Mx
+			default:
+				continue
+			}
+
+			s := output.TargetStatus{
+				Name:      rr.Header().Name,
+				Type:      dns.TypeToString[rr.Header().Rrtype],
+				Target:    target,
+				Bailiwick: dns.IsSubDomain(rr.Header().Name, target),
+			}
+			resolveTarget(ctx, txp, &s)
+			statuses = append(statuses, s)
+		}
+	}
+	if len(queried) == 0 {
+		return fmt.Errorf("--validate-targets requires an NS or MX query")
+	}
+	if len(statuses) == 0 {
+		return fmt.Errorf("no NS or MX records found for %v", queried)
+	}
+
+	printer := output.Printer{
+		Out:  out,
+		Opts: &opts,
+	}
+	printer.PrintTargets(statuses)
+
+	var failed int
+	for _, s := range statuses {
+		if len(s.Problems) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets failed validation", failed, len(statuses))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+// traceMaxHops limits the number of referrals followed when tracing a delegation chain
+const traceMaxHops = 16
+
+// traceServer is a nameserver that can be queried at a level of the delegation chain
+type traceServer struct {
+	nameserver string // Nameserver name, empty for the starting server
+	address    string
+}
+
+// String formats a server as its name and address, or just its address if it has no name
+func (s traceServer) String() string {
+	if s.nameserver == "" {
+		return s.address
+	}
+	return fmt.Sprintf("%s (%s)", s.nameserver, s.address)
+}
+
+// referral extracts the delegated zone, its nameservers, and the glued address of each nameserver
+func referral(reply *dns.Msg) (string, []string, []traceServer) {
+	var zone string
+	var nameservers []string
+	for _, rr := range reply.Ns {
+		if ns, ok := rr.(*dns.NS); ok {
+			zone = ns.Hdr.Name
+			nameservers = append(nameservers, ns.Ns)
+		}
+	}
+
+	var glued []traceServer
+	for _, nameserver := range nameservers {
+		for _, rr := range reply.Extra {
+			if !strings.EqualFold(rr.Header().Name, nameserver) {
+				continue
+			}
+			switch rr := rr.(type) {
+			case *dns.A:
+				glued = append(glued, traceServer{nameserver, rr.A.String()})
+			case *dns.AAAA:
+				glued = append(glued, traceServer{nameserver, rr.AAAA.String()})
+			}
+		}
+	}
+	return zone, nameservers, glued
+}
+
+// traceQuery sends a single non-recursive query of a trace, giving up after the per-hop timeout
+func traceQuery(ctx context.Context, r *resolver, msg *dns.Msg, address string) (*dns.Msg, error) {
+	txp, err := r.newTransport(address, transport.TypePlain, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer (*txp).Close()
+	if p, ok := (*txp).(*transport.Plain); ok && opts.TraceTimeout > 0 {
+		p.Timeout = opts.TraceTimeout
+	}
+	return exchange(ctx, txp, msg)
+}
+
+// traceDelegation iteratively resolves a query starting at a server, following referrals using their glue
+// records. Referred nameservers are queried on the same port as the starting server. When a nameserver
+// fails, the next glued nameserver of the same zone is tried.
+func traceDelegation(ctx context.Context, r *resolver, msg dns.Msg, server string) []output.TraceHop {
+	_, port, err := net.SplitHostPort(server)
+	if err != nil {
+		port = "53"
+	}
+
+	zone := "."
+	servers := []traceServer{{address: server}}
+	var hops []output.TraceHop
+	for len(hops) < traceMaxHops {
+		query := msg.Copy()
+		query.RecursionDesired = false
+
+		hop := output.TraceHop{Zone: zone}
+		var reply *dns.Msg
+		for _, s := range servers {
+			hop.Nameserver, hop.Address = s.nameserver, s.address
+			log.Debugf("Tracing %s via %s (%s)", questionName(query), s.address, zone)
+			reply, err = traceQuery(ctx, r, query, s.address)
+			if err == nil {
+				break
+			}
+			log.Debugf("Trace query to %s failed: %s", s, err)
+			if failureCategory(nil, err) == retryTimeout {
+				hop.TimedOut = append(hop.TimedOut, s.String())
+			}
+		}
+		if err != nil {
+			hop.Error = err.Error()
+			if len(servers) > 1 {
+				hop.Error = fmt.Sprintf("all %d nameservers failed, last error: %s", len(servers), err)
+			}
+			return append(hops, hop)
+		}
+		hop.Rcode = dns.RcodeToString[reply.Rcode]
+
+		referred, nameservers, glued := referral(reply)
+		if len(reply.Answer) > 0 || reply.Rcode != dns.RcodeSuccess || referred == "" {
+			for _, rr := range reply.Answer {
+				hop.Answers = append(hop.Answers, rr.String())
+			}
+			return append(hops, hop)
+		}
+
+		hop.Referral = referred
+		hop.Nameservers = nameservers
+		switch {
+		case !dns.IsSubDomain(zone, referred) || dns.CountLabel(referred) <= dns.CountLabel(zone):
+			hop.Error = fmt.Sprintf("referral to %s does not descend from %s", referred, zone)
+			return append(hops, hop)
+		case len(glued) == 0:
+			hop.Error = fmt.Sprintf("no glue for referral to %s", referred)
+			return append(hops, hop)
+		}
+
+		hops = append(hops, hop)
+		zone = referred
+		servers = nil
+		for _, s := range glued {
+			servers = append(servers, traceServer{s.nameserver, net.JoinHostPort(s.address, port)})
+		}
+	}
+
+	hops[len(hops)-1].Error = fmt.Sprintf("exceeded %d referrals", traceMaxHops)
+	return hops
+}
+
+// traceGraph traces the delegation chain of the first query and writes it as a Graphviz DOT graph to a file, or out if file is "-"
+func traceGraph(ctx context.Context, r *resolver, file string, msgs []dns.Msg, server string, out io.Writer) error {
+	if len(msgs) == 0 || len(msgs[0].Question) == 0 {
+		return fmt.Errorf("no question to trace")
+	}
+	q := msgs[0].Question[0]
+	hops := traceDelegation(ctx, r, msgs[0], server)
+
+	w := out
+	if file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("creating trace graph file: %s", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	output.WriteTraceGraph(w, fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype]), hops)
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/transport"
+	"github.com/natesales/q/util"
+)
+
+// transferQuery returns the AXFR or IXFR query of a query set, or nil if there isn't one
+func transferQuery(msgs []dns.Msg) *dns.Msg {
+	for i := range msgs {
+		if len(msgs[i].Question) > 0 {
+			if qType := msgs[i].Question[0].Qtype; qType == dns.TypeAXFR || qType == dns.TypeIXFR {
+				return &msgs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// dialTransfer connects to a server over TCP for a zone transfer. The connection is closed when the context is done,
+// stopping a transfer that's still streaming.
+func dialTransfer(ctx context.Context, server string, timeout time.Duration) (*dns.Conn, error) {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	return &dns.Conn{Conn: conn}, nil
+}
+
+// streamTransfer performs a zone transfer over TCP, printing the records of each message as it arrives instead of
+// buffering the whole zone. The transfer ends at the closing SOA record, after which the record count and transfer
+// time are reported.
+func streamTransfer(ctx context.Context, msg *dns.Msg, server string, transportType transport.Type, out io.Writer) error {
+	if transportType != transport.TypePlain && transportType != transport.TypeTCP {
+		return fmt.Errorf("zone transfers are only supported over plain DNS and TCP, not %s", transportType)
+	}
+	q := msg.Question[0]
+	qType := dns.TypeToString[q.Qtype]
+	if q.Qtype == dns.TypeIXFR {
+		log.Debugf("Requesting IXFR of %s since serial %d", q.Name, opts.IXFRSerial)
+	}
+
+	t := &dns.Transfer{
+		DialTimeout:  opts.Timeout,
+		ReadTimeout:  opts.Timeout,
+		WriteTimeout: opts.Timeout,
+		TsigSecret:   tsigSecrets(),
+	}
+	start := time.Now()
+	var err error
+	if t.Conn, err = dialTransfer(ctx, server, opts.Timeout); err != nil {
+		return fmt.Errorf("%s of %s: %s", qType, q.Name, err)
+	}
+	ch, err := t.In(msg, server)
+	if err != nil {
+		return fmt.Errorf("%s of %s: %s", qType, q.Name, err)
+	}
+
+	var records, messages int
+	for env := range ch {
+		if env.Error != nil {
+			return fmt.Errorf("%s of %s failed after %d records: %s", qType, q.Name, records, tsigError(nil, env.Error))
+		}
+		messages++
+		for _, rr := range env.RR {
+			util.MustWriteln(out, rr.String())
+		}
+		records += len(env.RR)
+	}
+
+	util.MustWritef(out, ";; %s of %s from %s: %d records in %d messages, %s\n",
+		qType, q.Name, server, records, messages, time.Since(start).Round(100*time.Microsecond))
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package transport
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToDevice binds a socket to a network interface with SO_BINDTODEVICE, so its packets leave through that interface
+// regardless of the routing table
+func bindToDevice(iface string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.BindToDevice(int(fd), iface)
+	})
+	if err != nil {
+		return err
+	}
+	if sockErr != nil {
+		return fmt.Errorf("binding to interface %s: %w", iface, sockErr)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package transport
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// bindToDevice fails on platforms without SO_BINDTODEVICE, since silently sending queries through another interface
+// would be misleading
+func bindToDevice(iface string, _ syscall.RawConn) error {
+	return fmt.Errorf("binding to interface %s is not supported on %s", iface, runtime.GOOS)
+}
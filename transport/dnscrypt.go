@@ -1,6 +1,11 @@
 package transport
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
 	"github.com/ameshkov/dnscrypt/v2"
 	"github.com/jedisct1/go-dnsstamps"
 	"github.com/miekg/dns"
@@ -12,6 +17,7 @@ type DNSCrypt struct {
 	ServerStamp string
 	TCP         bool // default false (UDP)
 	UDPSize     int
+	Timeout     time.Duration
 
 	// ServerStamp takes precedence if set
 	PublicKey    string
@@ -25,6 +31,7 @@ func (d *DNSCrypt) setup() {
 	if d.client == nil || d.resolver == nil || !d.ReuseConn {
 		d.client = &dnscrypt.Client{
 			UDPSize: d.UDPSize,
+			Timeout: d.Timeout,
 		}
 
 		if d.ServerStamp == "" {
@@ -50,9 +57,24 @@ func (d *DNSCrypt) setup() {
 	}
 }
 
-func (d *DNSCrypt) Exchange(msg *dns.Msg) (*dns.Msg, error) {
+func (d *DNSCrypt) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
 	d.setup()
-	return d.client.Exchange(msg, d.resolver)
+
+	// Dial the connection here instead of in the client so the context can interrupt the exchange
+	dialer := &net.Dialer{Timeout: d.Timeout}
+	conn, err := dialer.DialContext(ctx, d.client.Net, d.resolver.ServerAddress)
+	if err != nil {
+		return nil, fmt.Errorf("dialing: %w", contextErr(ctx, err))
+	}
+	defer conn.Close()
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+
+	reply, err := d.client.ExchangeConn(conn, msg, d.resolver)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging: %w", contextErr(ctx, err))
+	}
+	return reply, nil
 }
 
 func (d *DNSCrypt) Close() error {
@@ -2,11 +2,17 @@ package transport
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/miekg/dns"
 	"github.com/quic-go/quic-go"
@@ -24,22 +30,31 @@ type HTTP struct {
 	HTTP2, HTTP3 bool
 	NoPMTUd      bool
 	Headers      map[string][]string
+	Timeout      time.Duration
 
-	conn *http.Client
+	conn      *http.Client
+	connState *tls.ConnectionState
+	timings   Timings
 }
 
-func (h *HTTP) Exchange(m *dns.Msg) (*dns.Msg, error) {
+func (h *HTTP) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
 	if h.conn == nil || !h.ReuseConn {
 		transport := http.DefaultTransport.(*http.Transport)
 		transport.TLSClientConfig = h.TLSConfig
+		if dial := h.customDialContext(); dial != nil {
+			transport = transport.Clone()
+			transport.DialContext = dial
+		}
 		h.conn = &http.Client{
 			Transport: transport,
+			Timeout:   h.Timeout,
 		}
 		if h.HTTP2 {
 			log.Debug("Using HTTP/2")
 			h.conn.Transport = &http2.Transport{
 				TLSClientConfig: h.TLSConfig,
 				AllowHTTP:       true,
+				DialTLSContext:  h.dialTLSContext(),
 			}
 		}else if h.HTTP3 {
 			log.Debug("Using HTTP/3")
@@ -61,14 +76,17 @@ func (h *HTTP) Exchange(m *dns.Msg) (*dns.Msg, error) {
 	var req *http.Request
 	switch h.Method {
 	case http.MethodGet:
-		queryURL = h.Server + "?dns=" + base64.RawURLEncoding.EncodeToString(buf)
-		req, err = http.NewRequest(http.MethodGet, queryURL, nil)
+		queryURL, err = getURL(h.Server, buf)
+		if err != nil {
+			return nil, fmt.Errorf("parsing server URL %s: %w", h.Server, err)
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
 		if err != nil {
 			return nil, fmt.Errorf("creating http request to %s: %w", queryURL, err)
 		}
 	case http.MethodPost:
 		queryURL = h.Server
-		req, err = http.NewRequest(http.MethodPost, queryURL, bytes.NewReader(buf))
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, queryURL, bytes.NewReader(buf))
 		if err != nil {
 			return nil, fmt.Errorf("creating http request to %s: %w", queryURL, err)
 		}
@@ -93,6 +111,33 @@ func (h *HTTP) Exchange(m *dns.Msg) (*dns.Msg, error) {
 		}
 	}
 
+	// Record connection setup and time to first response byte separately from the total response time
+	start := time.Now()
+	h.timings = Timings{}
+	var connectStart, handshakeStart time.Time
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			connectStart = time.Now()
+		},
+		ConnectStart: func(string, string) {
+			if connectStart.IsZero() {
+				connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(string, string, error) {
+			h.timings.Connect = time.Since(connectStart)
+		},
+		TLSHandshakeStart: func() {
+			handshakeStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			h.timings.Handshake = time.Since(handshakeStart)
+		},
+		GotFirstResponseByte: func() {
+			h.timings.FirstByte = time.Since(start)
+		},
+	}))
+
 	log.Debugf("[http] sending %s request to %s", h.Method, queryURL)
 	resp, err := h.conn.Do(req)
 	if resp != nil && resp.Body != nil {
@@ -101,11 +146,13 @@ func (h *HTTP) Exchange(m *dns.Msg) (*dns.Msg, error) {
 	if err != nil {
 		return nil, fmt.Errorf("requesting %s: %w", queryURL, err)
 	}
+	h.connState = resp.TLS
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("reading %s: %w", queryURL, err)
 	}
+	h.timings.Total = time.Since(start)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("got status code %d from %s", resp.StatusCode, queryURL)
@@ -119,6 +166,54 @@ func (h *HTTP) Exchange(m *dns.Msg) (*dns.Msg, error) {
 	return &response, nil
 }
 
+// dialTLSContext returns an HTTP/2 DialTLSContext function that dials through the proxy or from the source address
+// and interface, or nil to dial directly
+func (h *HTTP) dialTLSContext() func(context.Context, string, string, *tls.Config) (net.Conn, error) {
+	dial := h.customDialContext()
+	if dial == nil {
+		return nil
+	}
+	return func(ctx context.Context, network, address string, config *tls.Config) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		// Cleartext HTTP/2 (h2c) doesn't use TLS
+		if strings.HasPrefix(h.Server, "http://") {
+			return conn, nil
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// getURL returns the URL of an RFC 8484 GET request, with the wire format message encoded as unpadded base64url in
+// the dns parameter alongside any query parameters the server URL already has
+func getURL(server string, msg []byte) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("dns", base64.RawURLEncoding.EncodeToString(msg))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// ConnectionState returns the TLS state of the most recent HTTPS response
+func (h *HTTP) ConnectionState() *tls.ConnectionState {
+	return h.connState
+}
+
+// Timings returns the timing breakdown of the most recent request
+func (h *HTTP) Timings() Timings {
+	return h.timings
+}
+
 func (h *HTTP) Close() error {
 	h.conn.CloseIdleConnections()
 	return nil
@@ -1,7 +1,11 @@
 package transport
 
 import (
+	"context"
+	"encoding/base64"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -21,7 +25,7 @@ func httpTransport() *HTTP {
 func TestTransportHTTPPOST(t *testing.T) {
 	tp := httpTransport()
 	tp.Method = http.MethodPost
-	reply, err := tp.Exchange(validQuery())
+	reply, err := tp.Exchange(context.Background(), validQuery())
 	assert.Nil(t, err)
 	assert.Greater(t, len(reply.Answer), 0)
 }
@@ -29,7 +33,7 @@ func TestTransportHTTPPOST(t *testing.T) {
 func TestTransportHTTP3(t *testing.T) {
 	tp := httpTransport()
 	tp.HTTP3 = true
-	reply, err := tp.Exchange(validQuery())
+	reply, err := tp.Exchange(context.Background(), validQuery())
 	assert.Nil(t, err)
 	assert.Greater(t, len(reply.Answer), 0)
 }
@@ -37,7 +41,7 @@ func TestTransportHTTP3(t *testing.T) {
 func TestTransportHTTPInvalidResolver(t *testing.T) {
 	tp := httpTransport()
 	tp.Server = "https://example.com"
-	_, err := tp.Exchange(validQuery())
+	_, err := tp.Exchange(context.Background(), validQuery())
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "unpacking DNS response")
 }
@@ -55,7 +59,7 @@ func TestTransportHTTPServerError(t *testing.T) {
 
 	tp := httpTransport()
 	tp.Server = "http://localhost" + listen
-	_, err := tp.Exchange(validQuery())
+	_, err := tp.Exchange(context.Background(), validQuery())
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "got status code 500")
 }
@@ -83,8 +87,75 @@ func TestTransportHTTPIDMismatch(t *testing.T) {
 	tp := httpTransport()
 	tp.Server = "http://localhost" + listen
 	query := validQuery()
-	reply, err := tp.Exchange(query)
+	reply, err := tp.Exchange(context.Background(), query)
 	assert.Nil(t, err)
 	assert.Equal(t, uint16(1), reply.Id)
 	assert.NotEqual(t, 1, query.Id)
 }
+
+func TestTransportHTTPTimings(t *testing.T) {
+	listen := ":5382"
+	go func() {
+		if err := http.ListenAndServe(listen, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond) // Simulate server processing time
+			msg := dns.Msg{}
+			buf, err := msg.Pack()
+			if err != nil {
+				t.Errorf("error packing DNS message: %s", err)
+				return
+			}
+			if _, err := w.Write(buf); err != nil {
+				t.Errorf("error writing DNS message: %s", err)
+			}
+		})); err != nil {
+			t.Errorf("error starting HTTP server: %s", err)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond) // Wait for server to start
+
+	tp := httpTransport()
+	tp.Server = "http://localhost" + listen
+	_, err := tp.Exchange(context.Background(), validQuery())
+	assert.Nil(t, err)
+	assert.Greater(t, tp.Timings().Connect, time.Duration(0))
+	assert.Zero(t, tp.Timings().Handshake)
+	assert.GreaterOrEqual(t, tp.Timings().FirstByte, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, tp.Timings().Total, tp.Timings().FirstByte)
+}
+
+func TestTransportHTTPGETEncoding(t *testing.T) {
+	query := validQuery()
+	buf, err := query.Pack()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(base64.URLEncoding.EncodeToString(buf), "="), "query should need base64 padding")
+
+	var rawQuery, accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery, accept = r.URL.RawQuery, r.Header.Get("Accept")
+		wire, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		msg := new(dns.Msg)
+		if err := msg.Unpack(wire); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := new(dns.Msg)
+		reply.SetReply(msg)
+		out, _ := reply.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(out)
+	}))
+	defer server.Close()
+
+	tp := httpTransport()
+	tp.Server = server.URL + "/dns-query?ct=1"
+	reply, err := tp.Exchange(context.Background(), query)
+	assert.Nil(t, err)
+	assert.Equal(t, query.Question, reply.Question)
+	assert.Equal(t, "ct=1&dns="+base64.RawURLEncoding.EncodeToString(buf), rawQuery)
+	assert.NotContains(t, strings.TrimPrefix(rawQuery, "ct=1&dns="), "=")
+	assert.Equal(t, "application/dns-message", accept)
+}
@@ -28,6 +28,7 @@ package transport
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -35,6 +36,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/miekg/dns"
 	log "github.com/sirupsen/logrus"
@@ -64,13 +66,16 @@ type ODoH struct {
 	Common    // Server is the target
 	Proxy     string
 	TLSConfig *tls.Config
+	Timeout   time.Duration
 
-	conn *http.Client
+	conn      *http.Client
+	connState *tls.ConnectionState
 }
 
-func (o *ODoH) Exchange(m *dns.Msg) (*dns.Msg, error) {
+func (o *ODoH) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
 	// Query ODoH configs on target
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodGet,
 		buildURL(strings.TrimSuffix(o.Server, "/dns-query"), "/.well-known/odohconfigs").String(),
 		nil,
@@ -83,7 +88,9 @@ func (o *ODoH) Exchange(m *dns.Msg) (*dns.Msg, error) {
 		o.conn = &http.Client{
 			Transport: &http.Transport{
 				TLSClientConfig: o.TLSConfig,
+				DialContext:     o.customDialContext(),
 			},
+			Timeout: o.Timeout,
 		}
 	}
 	resp, err := o.conn.Do(req)
@@ -130,7 +137,7 @@ func (o *ODoH) Exchange(m *dns.Msg) (*dns.Msg, error) {
 	p.RawQuery = qry.Encode()
 
 	log.Debugf("POST %s %+v", p, odnsMessage)
-	req, err = http.NewRequest(http.MethodPost, p.String(), bytes.NewBuffer(odnsMessage.Marshal()))
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, p.String(), bytes.NewBuffer(odnsMessage.Marshal()))
 	if err != nil {
 		return nil, fmt.Errorf("create new request: %s", err)
 	}
@@ -141,6 +148,7 @@ func (o *ODoH) Exchange(m *dns.Msg) (*dns.Msg, error) {
 	if err != nil {
 		return nil, fmt.Errorf("do request: %s", err)
 	}
+	o.connState = resp.TLS
 	contentType := resp.Header.Get("Content-Type")
 	if contentType != ODoHContentType {
 		return nil, fmt.Errorf("%s responded with an invalid Content-Type header %s, expected %s", req.URL, contentType, ODoHContentType)
@@ -168,6 +176,11 @@ func (o *ODoH) Exchange(m *dns.Msg) (*dns.Msg, error) {
 	return msg, err
 }
 
+// ConnectionState returns the TLS state of the most recent proxy response
+func (o *ODoH) ConnectionState() *tls.ConnectionState {
+	return o.connState
+}
+
 func (o *ODoH) Close() error {
 	o.conn.CloseIdleConnections()
 	return nil
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -32,7 +33,7 @@ func TestODoHBuildURL(t *testing.T) {
 func TestTransportODoHInvalidTarget(t *testing.T) {
 	tp := odohTransport()
 	tp.Server = "example.com"
-	_, err := tp.Exchange(validQuery())
+	_, err := tp.Exchange(context.Background(), validQuery())
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "Invalid serialized ObliviousDoHConfig")
 }
@@ -40,7 +41,7 @@ func TestTransportODoHInvalidTarget(t *testing.T) {
 func TestTransportODoHInvalidProxy(t *testing.T) {
 	tp := odohTransport()
 	tp.Proxy = "example.com"
-	_, err := tp.Exchange(validQuery())
+	_, err := tp.Exchange(context.Background(), validQuery())
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "responded with an invalid Content-Type header")
 }
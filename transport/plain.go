@@ -1,6 +1,11 @@
 package transport
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
 	"time"
 
 	"github.com/miekg/dns"
@@ -10,29 +15,102 @@ import (
 // Plain makes a DNS query over TCP or UDP (with TCP fallback)
 type Plain struct {
 	Common
-	PreferTCP bool
-	UDPBuffer uint16
-	Timeout   time.Duration
+	PreferTCP  bool
+	UDPBuffer  uint16
+	Timeout    time.Duration
+	TsigSecret map[string]string
+	TFO        bool   // Enable TCP Fast Open for TCP queries
+	SourcePort uint16 // Bind to a fixed local port, 0 for a random port
+	Family     string // Force an address family ("4" or "6"), empty for either
+	timings    Timings
 }
 
-func (p *Plain) Exchange(m *dns.Msg) (*dns.Msg, error) {
-	tcpClient := dns.Client{Net: "tcp", Timeout: p.Timeout}
-	if p.PreferTCP {
-		reply, _, tcpErr := tcpClient.Exchange(m, p.Server)
-		return reply, tcpErr
+func (p *Plain) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	tcpClient := dns.Client{Net: "tcp" + p.Family, Timeout: p.Timeout, Dialer: p.dialer("tcp"), TsigSecret: p.TsigSecret}
+	// UDP can't be sent through a proxy, so use TCP instead
+	if p.PreferTCP || p.ProxyDialer != nil {
+		reply, tcpErr := p.exchangeTCP(ctx, &tcpClient, m)
+		return reply, p.portErr(contextErr(ctx, tcpErr))
 	}
 
-	client := dns.Client{UDPSize: p.UDPBuffer, Timeout: p.Timeout}
-	reply, _, err := client.Exchange(m, p.Server)
+	client := dns.Client{Net: "udp" + p.Family, UDPSize: p.UDPBuffer, Timeout: p.Timeout, Dialer: p.dialer("udp"), TsigSecret: p.TsigSecret}
+	conn, err := client.DialContext(ctx, p.Server)
+	if err != nil {
+		return nil, p.portErr(contextErr(ctx, err))
+	}
+	defer conn.Close()
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+	reply, rtt, err := client.ExchangeWithConnContext(ctx, m, conn)
+
+	// A UDP response arrives in a single datagram, so the first byte arrives with the rest of it
+	p.timings = Timings{FirstByte: rtt, Total: rtt}
 
 	if reply != nil && reply.Truncated {
 		log.Debugf("Truncated reply from %s for %s over UDP, retrying over TCP", p.Server, m.Question[0].String())
-		reply, _, err = tcpClient.Exchange(m, p.Server)
+		reply, err = p.exchangeTCP(ctx, &tcpClient, m)
+	}
+
+	return reply, p.portErr(contextErr(ctx, err))
+}
+
+// exchangeTCP sends a message over a new TCP connection, recording the time to the first response byte
+func (p *Plain) exchangeTCP(ctx context.Context, client *dns.Client, m *dns.Msg) (*dns.Msg, error) {
+	p.timings = Timings{}
+	start := time.Now()
+	var conn *dns.Conn
+	if p.ProxyDialer != nil {
+		c, err := p.dialProxy(ctx, p.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		conn = &dns.Conn{Conn: c}
+	} else {
+		var err error
+		if conn, err = client.DialContext(ctx, p.Server); err != nil {
+			return nil, err
+		}
 	}
+	defer conn.Close()
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+	connect := time.Since(start)
 
+	timed := newTimedConn(conn.Conn)
+	conn.Conn = timed
+	reply, _, err := client.ExchangeWithConnContext(ctx, m, conn)
+	p.timings = timed.timings()
+	p.timings.Connect = connect
 	return reply, err
 }
 
+// dialer returns a dialer with the configured socket options for a network, or nil to use the client's default
+func (p *Plain) dialer(network string) *net.Dialer {
+	if !p.TFO && p.SourcePort == 0 && !p.bound() {
+		return nil
+	}
+
+	d := &net.Dialer{Timeout: p.Timeout}
+	if network == "tcp" && p.TFO {
+		d.Control = setTFO
+	}
+	p.bind(d, network, p.SourcePort)
+	return d
+}
+
+// portErr adds context to errors caused by the fixed source port being unavailable
+func (p *Plain) portErr(err error) error {
+	if p.SourcePort != 0 && (errors.Is(err, syscall.EADDRINUSE) || errors.Is(err, syscall.EACCES)) {
+		return fmt.Errorf("source port %d is unavailable: %w", p.SourcePort, err)
+	}
+	return err
+}
+
+// Timings returns the timing breakdown of the most recent exchange
+func (p *Plain) Timings() Timings {
+	return p.timings
+}
+
 // Close is a no-op for the plain transport
 func (p *Plain) Close() error {
 	return nil
@@ -1,7 +1,12 @@
 package transport
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
@@ -21,7 +26,7 @@ func plainTransport() *Plain {
 func TestTransportPlainPreferTCP(t *testing.T) {
 	tp := plainTransport()
 	tp.PreferTCP = true
-	reply, err := tp.Exchange(validQuery())
+	reply, err := tp.Exchange(context.Background(), validQuery())
 	assert.Nil(t, err)
 	assert.Greater(t, len(reply.Answer), 0)
 }
@@ -29,7 +34,7 @@ func TestTransportPlainPreferTCP(t *testing.T) {
 func TestTransportPlainInvalidResolver(t *testing.T) {
 	tp := plainTransport()
 	tp.Server = "127.127.127.127:53"
-	_, err := tp.Exchange(validQuery())
+	_, err := tp.Exchange(context.Background(), validQuery())
 	assert.NotNil(t, err)
 }
 
@@ -53,7 +58,83 @@ func TestTransportPlainLargeResponse(t *testing.T) {
 
 	tp := plainTransport()
 	tp.Server = "f.root-servers.net:53"
-	reply, err := tp.Exchange(&msg)
+	reply, err := tp.Exchange(context.Background(), &msg)
 	assert.Nil(t, err)
 	assert.Greater(t, len(reply.Answer), 0)
 }
+
+func TestTransportPlainTFO(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := &dns.Server{Listener: listener, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer server.Shutdown()
+
+	tp := plainTransport()
+	tp.Server = listener.Addr().String()
+	tp.PreferTCP = true
+	tp.TFO = true
+	reply, err := tp.Exchange(context.Background(), validQuery())
+	assert.Nil(t, err)
+	assert.Equal(t, dns.RcodeSuccess, reply.Rcode)
+}
+
+func TestTransportPlainSourcePort(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	var remotePort atomic.Int64
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		remotePort.Store(int64(w.RemoteAddr().(*net.UDPAddr).Port))
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer server.Shutdown()
+
+	// Find a free local port
+	free, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := free.LocalAddr().(*net.UDPAddr).Port
+	assert.Nil(t, free.Close())
+
+	tp := plainTransport()
+	tp.Server = conn.LocalAddr().String()
+	tp.SourcePort = uint16(port)
+	_, err = tp.Exchange(context.Background(), validQuery())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(port), remotePort.Load())
+
+	// Port in use
+	busy, err := net.ListenPacket("udp", fmt.Sprintf(":%d", port))
+	assert.Nil(t, err)
+	defer busy.Close()
+	_, err = tp.Exchange(context.Background(), validQuery())
+	assert.ErrorContains(t, err, fmt.Sprintf("source port %d is unavailable", port))
+}
+
+func TestTransportPlainTimings(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := &dns.Server{Listener: listener, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(20 * time.Millisecond) // Simulate server processing time
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer server.Shutdown()
+
+	tp := plainTransport()
+	tp.Server = listener.Addr().String()
+	tp.PreferTCP = true
+	_, err = tp.Exchange(context.Background(), validQuery())
+	assert.Nil(t, err)
+	assert.Greater(t, tp.Timings().Connect, time.Duration(0))
+	assert.GreaterOrEqual(t, tp.Timings().FirstByte, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, tp.Timings().Total, tp.Timings().FirstByte)
+}
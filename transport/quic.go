@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"time"
 
 	"github.com/miekg/dns"
 	"github.com/quic-go/quic-go"
@@ -31,16 +32,21 @@ type QUIC struct {
 	TLSConfig       *tls.Config
 	PMTUD           bool
 	AddLengthPrefix bool
+	Timeout         time.Duration // Timeout for the QUIC handshake and each exchange
 
-	conn *quic.Conn
+	conn    *quic.Conn
+	timings Timings
 }
 
 func (q *QUIC) connection() *quic.Conn {
 	return q.conn
 }
 
-// setServerName sets the TLS config server name to the QUIC server
+// setServerName sets the TLS config server name to the QUIC server unless one is already set
 func (q *QUIC) setServerName() {
+	if q.TLSConfig.ServerName != "" {
+		return
+	}
 	host, _, err := net.SplitHostPort(q.Server)
 	if err != nil {
 		log.Fatalf("invalid QUIC server address: %s", err)
@@ -48,7 +54,8 @@ func (q *QUIC) setServerName() {
 	q.TLSConfig.ServerName = host
 }
 
-func (q *QUIC) Exchange(msg *dns.Msg) (*dns.Msg, error) {
+func (q *QUIC) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	q.timings = Timings{}
 	if q.conn == nil || !q.ReuseConn {
 		log.Debugf("Connecting to %s", q.Server)
 		q.setServerName()
@@ -57,8 +64,15 @@ func (q *QUIC) Exchange(msg *dns.Msg) (*dns.Msg, error) {
 			q.TLSConfig.NextProtos = []string{"doq"}
 		}
 		log.Debugf("Dialing with QUIC ALPN tokens: %v", q.TLSConfig.NextProtos)
+		dialCtx := ctx
+		if q.Timeout > 0 {
+			var cancel context.CancelFunc
+			dialCtx, cancel = context.WithTimeout(ctx, q.Timeout)
+			defer cancel()
+		}
+		start := time.Now()
 		conn, err := quic.DialAddr(
-			context.Background(),
+			dialCtx,
 			q.Server,
 			q.TLSConfig,
 			&quic.Config{
@@ -66,9 +80,12 @@ func (q *QUIC) Exchange(msg *dns.Msg) (*dns.Msg, error) {
 			},
 		)
 		if err != nil {
-			return nil, fmt.Errorf("opening quic session to %s: %v", q.Server, err)
+			return nil, fmt.Errorf("opening quic session to %s: %v", q.Server, contextErr(ctx, err))
 		}
 		q.conn = conn
+
+		// QUIC establishes the connection as part of the handshake
+		q.timings.Handshake = time.Since(start)
 	}
 
 	// Clients and servers MUST NOT send the edns-tcp-keepalive EDNS(0) Option [RFC7828] in any messages sent
@@ -84,10 +101,22 @@ func (q *QUIC) Exchange(msg *dns.Msg) (*dns.Msg, error) {
 		}
 	}
 
+	start := time.Now()
 	stream, err := q.connection().OpenStream()
 	if err != nil {
-		return nil, fmt.Errorf("open new stream to %s: %v", q.Server, err)
+		// Discard the connection so the next exchange dials a new one
+		_ = q.connection().CloseWithError(DoQNoError, "")
+		q.conn = nil
+		return nil, fmt.Errorf("open new stream to %s: %w", q.Server, err)
 	}
+	if q.Timeout > 0 {
+		_ = stream.SetDeadline(time.Now().Add(q.Timeout))
+	}
+	stop := context.AfterFunc(ctx, func() {
+		stream.CancelRead(DoQRequestCancelled)
+		stream.CancelWrite(DoQRequestCancelled)
+	})
+	defer stop()
 
 	// When sending queries over a QUIC connection, the DNS Message ID MUST
 	// be set to zero. The stream mapping for DoQ allows for unambiguous
@@ -120,8 +149,9 @@ func (q *QUIC) Exchange(msg *dns.Msg) (*dns.Msg, error) {
 	_ = stream.Close()
 
 	respBuf, err := io.ReadAll(stream)
+	q.timings.Total = time.Since(start)
 	if err != nil {
-		return nil, fmt.Errorf("reading response from %s: %s", q.Server, err)
+		return nil, fmt.Errorf("reading response from %s: %s", q.Server, contextErr(ctx, err))
 	}
 	if len(respBuf) == 0 {
 		return nil, fmt.Errorf("empty response from %s", q.Server)
@@ -140,6 +170,20 @@ func (q *QUIC) Exchange(msg *dns.Msg) (*dns.Msg, error) {
 	return &reply, nil
 }
 
+// ConnectionState returns the TLS state of the QUIC connection
+func (q *QUIC) ConnectionState() *tls.ConnectionState {
+	if q.conn == nil {
+		return nil
+	}
+	state := q.conn.ConnectionState().TLS
+	return &state
+}
+
+// Timings returns the timing breakdown of the most recent exchange
+func (q *QUIC) Timings() Timings {
+	return q.timings
+}
+
 // addPrefix adds a 2-byte prefix with the DNS message length.
 func addPrefix(b []byte) (m []byte) {
 	m = make([]byte, 2+len(b))
@@ -150,5 +194,8 @@ func addPrefix(b []byte) (m []byte) {
 }
 
 func (q *QUIC) Close() error {
+	if q.conn == nil {
+		return nil
+	}
 	return q.connection().CloseWithError(DoQNoError, "")
 }
@@ -0,0 +1,26 @@
+//go:build linux
+
+package transport
+
+import (
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// setTFO enables TCP Fast Open on a socket before it connects, so the first write is sent in the SYN.
+// Errors from the socket option are logged and ignored to fall back to a regular handshake.
+func setTFO(_, _ string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	if sockErr != nil {
+		log.Debugf("TCP Fast Open not supported, continuing without it: %s", sockErr)
+	}
+	return nil
+}
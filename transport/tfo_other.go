@@ -0,0 +1,16 @@
+//go:build !linux
+
+package transport
+
+import (
+	"runtime"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setTFO is a no-op on platforms without TCP_FASTOPEN_CONNECT
+func setTFO(_, _ string, _ syscall.RawConn) error {
+	log.Debugf("TCP Fast Open is not supported on %s, continuing without it", runtime.GOOS)
+	return nil
+}
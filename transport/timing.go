@@ -0,0 +1,31 @@
+package transport
+
+import (
+	"net"
+	"time"
+)
+
+// timedConn records when the first response byte is read from a stream connection
+type timedConn struct {
+	net.Conn
+	start     time.Time
+	firstByte time.Duration
+}
+
+// newTimedConn wraps a connection, measuring from now
+func newTimedConn(conn net.Conn) *timedConn {
+	return &timedConn{Conn: conn, start: time.Now()}
+}
+
+func (c *timedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && c.firstByte == 0 {
+		c.firstByte = time.Since(c.start)
+	}
+	return n, err
+}
+
+// timings returns the time to the first response byte and the time since the connection was wrapped
+func (c *timedConn) timings() Timings {
+	return Timings{FirstByte: c.firstByte, Total: time.Since(c.start)}
+}
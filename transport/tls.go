@@ -1,9 +1,11 @@
 package transport
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/miekg/dns"
 )
@@ -12,32 +14,109 @@ import (
 type TLS struct {
 	Common
 	TLSConfig *tls.Config
+	TFO       bool          // Enable TCP Fast Open
+	Timeout   time.Duration // Timeout for connecting, the TLS handshake, and each exchange
 	conn      *tls.Conn
+	timings   Timings
 }
 
-func (t *TLS) Exchange(msg *dns.Msg) (*dns.Msg, error) {
+// dial connects to the server and completes the TLS handshake, returning the time each took
+func (t *TLS) dial(ctx context.Context) (time.Duration, time.Duration, error) {
+	dialer := &net.Dialer{Timeout: t.Timeout}
+	if t.TFO {
+		dialer.Control = setTFO
+	}
+	t.bind(dialer, "tcp", 0)
+
+	start := time.Now()
+	var rawConn net.Conn
+	var err error
+	if t.ProxyDialer != nil {
+		rawConn, err = t.dialProxy(ctx, t.Timeout)
+	} else {
+		rawConn, err = dialer.DialContext(ctx, "tcp", t.Server)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	connect := time.Since(start)
+
+	// Verify the server's host name unless another name is set, as tls.Dial does
+	config := t.TLSConfig
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		if host, _, err := net.SplitHostPort(t.Server); err == nil {
+			config.ServerName = host
+		}
+	}
+
+	start = time.Now()
+	t.conn = tls.Client(rawConn, config)
+	if t.Timeout > 0 {
+		_ = t.conn.SetDeadline(time.Now().Add(t.Timeout))
+	}
+	if err := t.conn.HandshakeContext(ctx); err != nil {
+		_ = rawConn.Close()
+		t.conn = nil
+		return 0, 0, err
+	}
+	return connect, time.Since(start), nil
+}
+
+func (t *TLS) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	var connect, handshake time.Duration
 	if t.conn == nil || !t.ReuseConn {
 		var err error
-		t.conn, err = tls.DialWithDialer(
-			&net.Dialer{},
-			"tcp",
-			t.Server,
-			t.TLSConfig,
-		)
-		if err != nil {
-			return nil, err
-		}
-		if err = t.conn.Handshake(); err != nil {
-			return nil, err
+		if connect, handshake, err = t.dial(ctx); err != nil {
+			return nil, contextErr(ctx, err)
 		}
 	}
+	if t.Timeout > 0 {
+		_ = t.conn.SetDeadline(time.Now().Add(t.Timeout))
+	}
+	stop := closeOnDone(ctx, t.conn)
+	defer stop()
 
-	c := dns.Conn{Conn: t.conn}
+	// Time the exchange from sending the query, excluding connection setup
+	timed := newTimedConn(t.conn)
+	t.timings = Timings{}
+	c := dns.Conn{Conn: timed}
 	if err := c.WriteMsg(msg); err != nil {
-		return nil, fmt.Errorf("write msg to %s: %v", t.Server, err)
+		t.reset()
+		return nil, fmt.Errorf("write msg to %s: %w", t.Server, contextErr(ctx, err))
 	}
 
-	return c.ReadMsg()
+	reply, err := c.ReadMsg()
+	t.timings = timed.timings()
+	t.timings.Connect = connect
+	t.timings.Handshake = handshake
+	if err != nil {
+		t.reset()
+	}
+	return reply, contextErr(ctx, err)
+}
+
+// reset closes and discards a broken connection so the next exchange dials a new one
+func (t *TLS) reset() {
+	_ = t.conn.Close()
+	t.conn = nil
+}
+
+// ConnectionState returns the state of the TLS connection
+func (t *TLS) ConnectionState() *tls.ConnectionState {
+	if t.conn == nil {
+		return nil
+	}
+	state := t.conn.ConnectionState()
+	return &state
+}
+
+// Timings returns the timing breakdown of the most recent exchange
+func (t *TLS) Timings() Timings {
+	return t.timings
 }
 
 // Close closes the TLS connection
@@ -1,5 +1,18 @@
 package transport
 
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
 func tlsTransport() *TLS {
 	return &TLS{
 		Common: Common{
@@ -8,3 +21,68 @@ func tlsTransport() *TLS {
 		},
 	}
 }
+
+func TestTransportTLSTimings(t *testing.T) {
+	// Borrow the test certificate for 127.0.0.1 from an HTTPS test server
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	roots := ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: ts.TLS.Certificates})
+	assert.Nil(t, err)
+	server := &dns.Server{Listener: listener, Net: "tcp-tls", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	defer server.Shutdown()
+
+	tp := tlsTransport()
+	tp.Server = listener.Addr().String()
+	tp.ReuseConn = true
+	tp.TLSConfig = &tls.Config{RootCAs: roots}
+	defer tp.Close()
+
+	_, err = tp.Exchange(context.Background(), validQuery())
+	assert.Nil(t, err)
+	assert.Greater(t, tp.Timings().Connect, time.Duration(0))
+	assert.Greater(t, tp.Timings().Handshake, time.Duration(0))
+	assert.GreaterOrEqual(t, tp.Timings().Total, tp.Timings().FirstByte)
+
+	// No connection setup when the connection is reused
+	_, err = tp.Exchange(context.Background(), validQuery())
+	assert.Nil(t, err)
+	assert.Zero(t, tp.Timings().Connect)
+	assert.Zero(t, tp.Timings().Handshake)
+}
+
+func TestTransportTLSTimeout(t *testing.T) {
+	// Accept connections without ever completing the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, c := range conns {
+					_ = c.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	tp := tlsTransport()
+	tp.Server = listener.Addr().String()
+	tp.Timeout = 100 * time.Millisecond
+	defer tp.Close()
+
+	start := time.Now()
+	_, err = tp.Exchange(context.Background(), validQuery())
+	assert.ErrorContains(t, err, "timeout")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -1,17 +1,132 @@
 package transport
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
 	"github.com/miekg/dns"
+	"golang.org/x/net/proxy"
 )
 
+// Transport sends DNS messages to a server. Each exchange is bounded by the transport's own Timeout and by the
+// context, which interrupts connection setup and a pending response when it's canceled.
 type Transport interface {
-	Exchange(*dns.Msg) (*dns.Msg, error)
+	Exchange(context.Context, *dns.Msg) (*dns.Msg, error)
 	Close() error
 }
 
+// TLSStater is implemented by transports that run over TLS
+type TLSStater interface {
+	// ConnectionState returns the state of the most recently used TLS connection, or nil if there isn't one
+	ConnectionState() *tls.ConnectionState
+}
+
+// Timer is implemented by transports that record a timing breakdown of their exchanges
+type Timer interface {
+	// Timings returns the timing breakdown of the most recent exchange
+	Timings() Timings
+}
+
+// Timings stores the timing breakdown of a single exchange. Connection setup phases are zero when an existing
+// connection was reused or the transport can't distinguish them.
+type Timings struct {
+	Connect   time.Duration // Time to resolve the server's name and establish the connection
+	Handshake time.Duration // Time to complete the TLS (or QUIC) handshake
+	FirstByte time.Duration // Time from starting the exchange until the first response byte was received
+	Total     time.Duration // Time from starting the exchange until the response was fully read
+}
+
 type Common struct {
-	Server    string
-	ReuseConn bool
+	Server      string
+	ReuseConn   bool
+	ProxyDialer proxy.Dialer // Proxy (e.g. SOCKS5) to dial TCP connections through, nil to dial directly
+	SourceIP    net.IP       // Local address to send queries from, nil to let the OS choose
+	Interface   string       // Network interface to send queries through, empty to let the OS choose
+}
+
+// bound returns whether queries are sent from a fixed source address or interface
+func (c *Common) bound() bool {
+	return c.SourceIP != nil || c.Interface != ""
+}
+
+// bind sets a dialer's local address to the source IP and a port (0 for a random port) for a network ("tcp" or
+// "udp"), and binds its sockets to the interface if set
+func (c *Common) bind(d *net.Dialer, network string, port uint16) {
+	if c.SourceIP != nil || port != 0 {
+		if network == "udp" {
+			d.LocalAddr = &net.UDPAddr{IP: c.SourceIP, Port: int(port)}
+		} else {
+			d.LocalAddr = &net.TCPAddr{IP: c.SourceIP, Port: int(port)}
+		}
+	}
+	if c.Interface != "" {
+		control := d.Control
+		d.Control = func(network, address string, raw syscall.RawConn) error {
+			if control != nil {
+				if err := control(network, address, raw); err != nil {
+					return err
+				}
+			}
+			return bindToDevice(c.Interface, raw)
+		}
+	}
+}
+
+// dialContext dials a connection to an address through the proxy
+func (c *Common) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d, ok := c.ProxyDialer.(proxy.ContextDialer); ok {
+		return d.DialContext(ctx, network, address)
+	}
+	return c.ProxyDialer.Dial(network, address)
+}
+
+// customDialContext returns a DialContext function that dials through the proxy or from the source address and
+// interface, or nil to dial with the default dialer
+func (c *Common) customDialContext() func(context.Context, string, string) (net.Conn, error) {
+	if c.ProxyDialer != nil {
+		return c.dialContext
+	}
+	if c.bound() {
+		d := &net.Dialer{}
+		c.bind(d, "tcp", 0)
+		return d.DialContext
+	}
+	return nil
+}
+
+// dialProxy dials a TCP connection to the server through the proxy, giving up after a timeout if it's positive
+func (c *Common) dialProxy(ctx context.Context, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	conn, err := c.dialContext(ctx, "tcp", c.Server)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %s through proxy: %w", c.Server, err)
+	}
+	return conn, nil
+}
+
+// closeOnDone closes a connection when the context is done, interrupting a blocked read or write. The returned
+// function stops watching the context.
+func closeOnDone(ctx context.Context, conn io.Closer) func() bool {
+	return context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+}
+
+// contextErr returns the context's error if it was canceled, since that's the cause of an interrupted exchange
+func contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 type Type string
@@ -23,10 +138,11 @@ const (
 	TypeHTTP     Type = "http"
 	TypeQUIC     Type = "quic"
 	TypeDNSCrypt Type = "dnscrypt"
+	TypeWS       Type = "ws"
 )
 
 // Types is a list of all supported transports
-var Types = []Type{TypePlain, TypeTCP, TypeTLS, TypeHTTP, TypeQUIC, TypeDNSCrypt}
+var Types = []Type{TypePlain, TypeTCP, TypeTLS, TypeHTTP, TypeQUIC, TypeDNSCrypt, TypeWS}
 
 // Interface guards
 var (
@@ -36,4 +152,15 @@ var (
 	_ Transport = (*ODoH)(nil)
 	_ Transport = (*QUIC)(nil)
 	_ Transport = (*DNSCrypt)(nil)
+	_ Transport = (*WebSocket)(nil)
+
+	_ TLSStater = (*TLS)(nil)
+	_ TLSStater = (*HTTP)(nil)
+	_ TLSStater = (*ODoH)(nil)
+	_ TLSStater = (*QUIC)(nil)
+
+	_ Timer = (*Plain)(nil)
+	_ Timer = (*TLS)(nil)
+	_ Timer = (*HTTP)(nil)
+	_ Timer = (*QUIC)(nil)
 )
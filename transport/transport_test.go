@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"testing"
 
 	"github.com/miekg/dns"
@@ -47,7 +48,7 @@ func transportHarness(t *testing.T, transport Transport) {
 		{Name: "InvalidQuery", ShouldError: true, Query: invalidQuery()},
 	} {
 		t.Run("TransportHarness"+tc.Name, func(t *testing.T) {
-			reply, err := transport.Exchange(tc.Query)
+			reply, err := transport.Exchange(context.Background(), tc.Query)
 			if tc.ShouldError {
 				assert.NotNil(t, err)
 			} else {
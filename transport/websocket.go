@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket makes a DNS query over a WebSocket (ws:// or wss://), sending each message as a binary frame
+type WebSocket struct {
+	Common
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+	conn      *websocket.Conn
+}
+
+// dial opens a WebSocket connection to the server
+func (w *WebSocket) dial(ctx context.Context) (*websocket.Conn, error) {
+	u, err := url.Parse(w.Server)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s as URL: %w", w.Server, err)
+	}
+
+	// The handshake requires an origin, so use the server's own
+	origin := "http://" + u.Host
+	if u.Scheme == "wss" {
+		origin = "https://" + u.Host
+	}
+	config, err := websocket.NewConfig(w.Server, origin)
+	if err != nil {
+		return nil, err
+	}
+	config.TlsConfig = w.TLSConfig
+	config.Dialer = &net.Dialer{Timeout: w.Timeout}
+	w.bind(config.Dialer, "tcp", 0)
+
+	return config.DialContext(ctx)
+}
+
+func (w *WebSocket) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	if w.conn == nil || !w.ReuseConn {
+		if w.conn != nil {
+			_ = w.conn.Close()
+		}
+		conn, err := w.dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dialing %s: %w", w.Server, contextErr(ctx, err))
+		}
+		w.conn = conn
+	}
+	if w.Timeout > 0 {
+		_ = w.conn.SetDeadline(time.Now().Add(w.Timeout))
+	}
+	stop := closeOnDone(ctx, w.conn)
+	defer stop()
+
+	buf, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("packing message: %w", err)
+	}
+	if err := websocket.Message.Send(w.conn, buf); err != nil {
+		w.reset()
+		return nil, fmt.Errorf("write msg to %s: %w", w.Server, contextErr(ctx, err))
+	}
+
+	var resp []byte
+	if err := websocket.Message.Receive(w.conn, &resp); err != nil {
+		w.reset()
+		return nil, fmt.Errorf("read msg from %s: %w", w.Server, contextErr(ctx, err))
+	}
+
+	reply := new(dns.Msg)
+	if err := reply.Unpack(resp); err != nil {
+		return nil, fmt.Errorf("unpacking DNS response: %w", err)
+	}
+	return reply, nil
+}
+
+// reset closes and discards a broken connection so the next exchange dials a new one
+func (w *WebSocket) reset() {
+	_ = w.conn.Close()
+	w.conn = nil
+}
+
+// Close closes the WebSocket connection
+func (w *WebSocket) Close() error {
+	if w.conn != nil {
+		return w.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package transport
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+// websocketServer starts a local WebSocket server that answers each binary frame with an empty reply
+func websocketServer(t *testing.T) string {
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		for {
+			var buf []byte
+			if err := websocket.Message.Receive(conn, &buf); err != nil {
+				return
+			}
+			query := new(dns.Msg)
+			if err := query.Unpack(buf); err != nil {
+				return
+			}
+			reply := new(dns.Msg)
+			reply.SetReply(query)
+			b, _ := reply.Pack()
+			if err := websocket.Message.Send(conn, b); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws://" + strings.TrimPrefix(server.URL, "http://") + "/dns"
+}
+
+func TestTransportWebSocket(t *testing.T) {
+	tp := &WebSocket{Common: Common{Server: websocketServer(t), ReuseConn: true}}
+	defer tp.Close()
+
+	for i := 0; i < 2; i++ {
+		query := validQuery()
+		reply, err := tp.Exchange(context.Background(), query)
+		assert.Nil(t, err)
+		assert.Equal(t, query.Id, reply.Id)
+		assert.Equal(t, query.Question, reply.Question)
+	}
+}
+
+func TestTransportWebSocketInvalidServer(t *testing.T) {
+	tp := &WebSocket{Common: Common{Server: "ws://127.0.0.1:1/dns"}}
+	_, err := tp.Exchange(context.Background(), validQuery())
+	assert.NotNil(t, err)
+}
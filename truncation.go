@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/util"
+)
+
+// truncationBuffer is the EDNS0 UDP buffer size advertised when testing truncation, the smallest allowed by RFC 6891
+const truncationBuffer = 512
+
+// classifyTruncation describes how a server handled a constrained UDP response compared to the full response
+// received over TCP, and whether that behavior is compliant with RFC 6891 and RFC 8906
+func classifyTruncation(udp, full *dns.Msg, udpLen int) (string, bool) {
+	switch {
+	case udpLen > truncationBuffer:
+		return fmt.Sprintf("response exceeded the advertised buffer (%d > %d B)", udpLen, truncationBuffer), false
+	case udp.Truncated && len(udp.Answer) == 0 && len(udp.Ns) == 0:
+		return "TC set with empty sections (minimal response)", true
+	case udp.Truncated && len(udp.Answer) < len(full.Answer):
+		return "TC set with a partial answer section", true
+	case udp.Truncated:
+		return "TC set with a complete answer section", true
+	case len(udp.Answer) < len(full.Answer):
+		return "partial answer section without TC", false
+	case len(udp.Ns) < len(full.Ns):
+		return "authority records dropped without TC", false
+	case len(udp.Extra) < len(full.Extra):
+		return "additional records dropped without TC (permitted by RFC 2181 section 9)", true
+	default:
+		return "complete response fit in the buffer", true
+	}
+}
+
+// exchangeUDP sends a message over UDP without TCP fallback and returns the reply and its size on the wire
+func exchangeUDP(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, int, error) {
+	client := dns.Client{Net: "udp", Timeout: opts.Timeout}
+	conn, err := client.DialContext(ctx, server)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	// Read with the largest possible buffer to detect responses that exceed the advertised size
+	conn.UDPSize = dns.MaxMsgSize
+	if err := conn.SetDeadline(time.Now().Add(opts.Timeout)); err != nil {
+		return nil, 0, err
+	}
+	if err := conn.WriteMsg(msg); err != nil {
+		return nil, 0, err
+	}
+	raw, err := conn.ReadMsgHeader(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reply := new(dns.Msg)
+	if err := reply.Unpack(raw); err != nil {
+		return nil, 0, err
+	}
+	return reply, len(raw), nil
+}
+
+// truncationTest sends each query over UDP with a minimal buffer and reports how the server truncated its response
+func truncationTest(ctx context.Context, msgs []dns.Msg, server string, out io.Writer) error {
+	tcpClient := dns.Client{Net: "tcp", Timeout: opts.Timeout}
+
+	for _, msg := range msgs {
+		if opt := msg.IsEdns0(); opt != nil {
+			opt.SetUDPSize(truncationBuffer)
+		} else {
+			msg.SetEdns0(truncationBuffer, opts.DNSSEC)
+		}
+		q := msg.Question[0]
+		label := fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype])
+
+		full, _, err := tcpClient.ExchangeContext(ctx, &msg, server)
+		if err != nil {
+			return fmt.Errorf("TCP exchange for %s: %s", label, err)
+		}
+
+		udp, udpLen, err := exchangeUDP(ctx, &msg, server)
+		if err != nil {
+			util.MustWritef(out, "%s: %s (%s)\n", label, util.Color(util.ColorRed, "no UDP response"), err)
+			continue
+		}
+
+		description, compliant := classifyTruncation(udp, full, udpLen)
+		color := util.ColorGreen
+		if !compliant {
+			color = util.ColorRed
+		}
+		util.MustWritef(out, "%s: %s\n", label, util.Color(color, description))
+		util.MustWritef(out, "  UDP: %d B response to %d B buffer, TC=%t, %d/%d/%d records\n",
+			udpLen, truncationBuffer, udp.Truncated, len(udp.Answer), len(udp.Ns), len(udp.Extra))
+		util.MustWritef(out, "  TCP: %d B response, %d/%d/%d records\n",
+			full.Len(), len(full.Answer), len(full.Ns), len(full.Extra))
+	}
+
+	return nil
+}
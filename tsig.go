@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/cli"
+	"github.com/natesales/q/transport"
+)
+
+// tsigFudge is the allowed clock skew in seconds between the client and server for TSIG signed messages
+const tsigFudge = 300
+
+// tsigSecrets returns the secret of the --tsig key by key name, or nil if queries aren't signed
+func tsigSecrets() map[string]string {
+	if opts.TSIG == "" {
+		return nil
+	}
+	name, _, secret, err := cli.ParseTSIG(opts.TSIG)
+	if err != nil {
+		return nil
+	}
+	return map[string]string{name: secret}
+}
+
+// signTSIG adds a TSIG record for the --tsig key to a message, which the transport signs when it's sent
+func signTSIG(msg *dns.Msg) {
+	if opts.TSIG == "" {
+		return
+	}
+	name, algorithm, _, err := cli.ParseTSIG(opts.TSIG)
+	if err != nil {
+		return
+	}
+	msg.SetTsig(name, algorithm, tsigFudge, time.Now().Unix())
+}
+
+// tsigTransport returns true if a transport signs and verifies TSIG messages
+func tsigTransport(t transport.Type) bool {
+	return t == transport.TypePlain || t == transport.TypeTCP
+}
+
+// tsigError describes why a TSIG signed exchange failed, or returns err unchanged if TSIG wasn't the cause
+func tsigError(reply *dns.Msg, err error) error {
+	if reply != nil {
+		if t := reply.IsTsig(); t != nil && t.Error != dns.RcodeSuccess {
+			return fmt.Errorf("TSIG rejected by server: %s", dns.RcodeToString[int(t.Error)])
+		}
+	}
+	if errors.Is(err, dns.ErrSig) || errors.Is(err, dns.ErrTime) || errors.Is(err, dns.ErrSecret) || errors.Is(err, dns.ErrKeyAlg) {
+		return fmt.Errorf("TSIG verification failed: %w", err)
+	}
+	return err
+}
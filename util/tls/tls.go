@@ -1,7 +1,12 @@
 package tls
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -86,3 +91,85 @@ func Version(version string, fallback uint16) uint16 {
 		return fallback
 	}
 }
+
+// ClientCertificates maps server names to the client certificate to present to them. A name starting with *. matches
+// any subdomain.
+type ClientCertificates map[string]*tls.Certificate
+
+// LoadClientCertificates loads the certificate and key files of name=certfile,keyfile mappings
+func LoadClientCertificates(mappings []string) (ClientCertificates, error) {
+	certs := make(ClientCertificates)
+	for _, mapping := range mappings {
+		name, files, ok := strings.Cut(mapping, "=")
+		certFile, keyFile, ok2 := strings.Cut(files, ",")
+		if !ok || !ok2 || name == "" {
+			return nil, fmt.Errorf("invalid client certificate mapping %s, expected name=certfile,keyfile", mapping)
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate for %s: %w", name, err)
+		}
+		certs[strings.ToLower(strings.TrimSuffix(name, "."))] = &cert
+	}
+	return certs, nil
+}
+
+// Lookup returns the client certificate for a server name, preferring an exact match over a wildcard
+func (c ClientCertificates) Lookup(serverName string) (*tls.Certificate, bool) {
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if cert, ok := c[name]; ok {
+		return cert, true
+	}
+	for i := strings.Index(name, "."); i != -1; i = strings.Index(name, ".") {
+		name = name[i+1:]
+		if cert, ok := c["*."+name]; ok {
+			return cert, true
+		}
+	}
+	return nil, false
+}
+
+// GetClientCertificate returns a tls.Config callback that presents the certificate for a server name, or the first
+// fallback certificate if none is configured for it
+func (c ClientCertificates) GetClientCertificate(serverName string, fallback []tls.Certificate) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		if cert, ok := c.Lookup(serverName); ok {
+			return cert, nil
+		}
+		if len(fallback) > 0 {
+			return &fallback[0], nil
+		}
+		// An empty certificate sends none
+		return &tls.Certificate{}, nil
+	}
+}
+
+// SPKIHash returns the base64 encoded SHA-256 hash of a certificate's SubjectPublicKeyInfo
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPins returns a tls.Config VerifyConnection callback that fails the handshake unless the public key of the
+// server certificate matches one of the base64 encoded SHA-256 SPKI hashes
+func VerifyPins(pins []string) (func(tls.ConnectionState) error, error) {
+	pinned := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		b, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid SPKI pin %s, expected a base64 encoded SHA-256 hash", pin)
+		}
+		pinned[base64.StdEncoding.EncodeToString(b)] = true
+	}
+
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("server presented no certificate to check against the pinned keys")
+		}
+		observed := SPKIHash(state.PeerCertificates[0])
+		if !pinned[observed] {
+			return fmt.Errorf("server certificate public key doesn't match any pinned key, got sha256 %s", observed)
+		}
+		return nil
+	}, nil
+}
@@ -3,8 +3,10 @@ package util
 import (
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
+	"github.com/miekg/dns"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,16 +23,47 @@ const (
 	ColorWhite   = "white"
 )
 
-// ANSI colors
-var colors = map[string]string{
-	ColorBlack:   "\033[1;30m%s\033[0m",
-	ColorRed:     "\033[1;31m%s\033[0m",
-	ColorGreen:   "\033[1;32m%s\033[0m",
-	ColorYellow:  "\033[1;33m%s\033[0m",
-	ColorPurple:  "\033[1;34m%s\033[0m",
-	ColorMagenta: "\033[1;35m%s\033[0m",
-	ColorTeal:    "\033[1;36m%s\033[0m",
-	ColorWhite:   "\033[1;37m%s\033[0m",
+// Themes maps theme names to the ANSI escape format of each color
+var Themes = map[string]map[string]string{
+	"default": {
+		ColorBlack:   "\033[1;30m%s\033[0m",
+		ColorRed:     "\033[1;31m%s\033[0m",
+		ColorGreen:   "\033[1;32m%s\033[0m",
+		ColorYellow:  "\033[1;33m%s\033[0m",
+		ColorPurple:  "\033[1;34m%s\033[0m",
+		ColorMagenta: "\033[1;35m%s\033[0m",
+		ColorTeal:    "\033[1;36m%s\033[0m",
+		ColorWhite:   "\033[1;37m%s\033[0m",
+	},
+	// Okabe-Ito palette, with good and bad results shown in blue and vermillion instead of green and red
+	"colorblind": {
+		ColorBlack:   "\033[1;30m%s\033[0m",
+		ColorRed:     "\033[1;38;5;202m%s\033[0m",
+		ColorGreen:   "\033[1;38;5;32m%s\033[0m",
+		ColorYellow:  "\033[1;38;5;220m%s\033[0m",
+		ColorPurple:  "\033[1;38;5;141m%s\033[0m",
+		ColorMagenta: "\033[1;38;5;175m%s\033[0m",
+		ColorTeal:    "\033[1;38;5;117m%s\033[0m",
+		ColorWhite:   "\033[1;37m%s\033[0m",
+	},
+}
+
+// colors is the ANSI escape format of each color in the current theme
+var colors = Themes["default"]
+
+// SetTheme sets the colors used by Color to those of a theme in Themes
+func SetTheme(name string) error {
+	theme, ok := Themes[name]
+	if !ok {
+		names := make([]string, 0, len(Themes))
+		for n := range Themes {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("invalid theme %s. expected: %+v", name, names)
+	}
+	colors = theme
+	return nil
 }
 
 // Color returns a color formatted string
@@ -66,3 +99,21 @@ func MustWritef(out io.Writer, format string, a ...interface{}) {
 		log.Fatal(err)
 	}
 }
+
+// EDNSOption returns the first EDNS0 option of type T in a message's OPT record
+func EDNSOption[T dns.EDNS0](m *dns.Msg) (T, bool) {
+	var zero T
+	if m == nil {
+		return zero, false
+	}
+	opt := m.IsEdns0()
+	if opt == nil {
+		return zero, false
+	}
+	for _, o := range opt.Option {
+		if t, ok := o.(T); ok {
+			return t, true
+		}
+	}
+	return zero, false
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,3 +30,28 @@ func TestUtilColor(t *testing.T) {
 	assert.Equal(t, "\033[1;31mfoo\033[0m", Color("red", "foo"))
 	assert.Equal(t, "\033[1;37mfoo\033[0m", Color("white", "foo"))
 }
+
+func TestUtilSetTheme(t *testing.T) {
+	t.Cleanup(func() {
+		_ = SetTheme("default")
+	})
+	assert.Nil(t, SetTheme("colorblind"))
+	assert.Equal(t, "\033[1;38;5;202mfoo\033[0m", Color(ColorRed, "foo"))
+	assert.Equal(t, "\033[1;38;5;32mfoo\033[0m", Color(ColorGreen, "foo"))
+
+	assert.ErrorContains(t, SetTheme("neon"), "invalid theme neon. expected: [colorblind default]")
+	assert.Nil(t, SetTheme("default"))
+	assert.Equal(t, "\033[1;31mfoo\033[0m", Color(ColorRed, "foo"))
+}
+
+func TestUtilEDNSOption(t *testing.T) {
+	m := new(dns.Msg)
+	_, ok := EDNSOption[*dns.EDNS0_COOKIE](m)
+	assert.False(t, ok)
+
+	m.SetEdns0(1232, false)
+	m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Expire: 60})
+	expire, ok := EDNSOption[*dns.EDNS0_EXPIRE](m)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(60), expire.Expire)
+}
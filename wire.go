@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/natesales/q/output"
+)
+
+// wireFiles returns the file name for each of n responses, numbering them before the extension if there's more than one
+func wireFiles(path string, n int) []string {
+	if n == 1 {
+		return []string{path}
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	files := make([]string, n)
+	for i := range files {
+		files[i] = fmt.Sprintf("%s-%d%s", base, i+1, ext)
+	}
+	return files
+}
+
+// writeWire packs every reply and writes it to a file in DNS wire format
+func writeWire(path string, entries []*output.Entry) error {
+	var replies []*dns.Msg
+	for _, e := range entries {
+		replies = append(replies, e.Replies...)
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+
+	for i, file := range wireFiles(path, len(replies)) {
+		b, err := replies[i].Pack()
+		if err != nil {
+			return fmt.Errorf("packing response: %s", err)
+		}
+		if err := os.WriteFile(file, b, 0644); err != nil {
+			return fmt.Errorf("writing response: %s", err)
+		}
+		log.Debugf("Wrote %d byte response to %s", len(b), file)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -19,13 +20,18 @@ var (
 	all     []dns.RR
 )
 
-func axfr(label, server string) []dns.RR {
-	t := new(dns.Transfer)
+func axfr(ctx context.Context, label, server string) ([]dns.RR, error) {
+	t := &dns.Transfer{TsigSecret: tsigSecrets()}
 	m := new(dns.Msg)
 	m.SetAxfr(dns.Fqdn(label))
+	signTSIG(m)
+	var err error
+	if t.Conn, err = dialTransfer(ctx, server, opts.Timeout); err != nil {
+		return nil, fmt.Errorf("transferring zone %s: %s", label, err)
+	}
 	ch, err := t.In(m, server)
 	if err != nil {
-		log.Fatalf("Failed to transfer zone: %s", err)
+		return nil, fmt.Errorf("transferring zone %s: %s", label, err)
 	}
 
 	var rrs []dns.RR
@@ -37,11 +43,11 @@ func axfr(label, server string) []dns.RR {
 		rrs = append(rrs, env.RR...)
 	}
 
-	return rrs
+	return rrs, nil
 }
 
 // RecAXFR performs an AXFR on the given label and all of its children and writes the zone file to disk
-func RecAXFR(label, server string, out io.Writer) []dns.RR {
+func RecAXFR(ctx context.Context, label, server string, out io.Writer) ([]dns.RR, error) {
 	util.MustWritef(out, "Attempting recursive AXFR for %s\n", label)
 
 	// Reset state
@@ -57,24 +63,35 @@ func RecAXFR(label, server string, out io.Writer) []dns.RR {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
-			log.Fatalf("creating recaxfr directory: %s", err)
+			return nil, fmt.Errorf("creating recaxfr directory: %s", err)
 		}
 	}
 
-	addToTree(label, dir, server, out)
+	if err := addToTree(ctx, label, dir, server, out); err != nil {
+		return nil, err
+	}
 	util.MustWritef(out, "AXFR complete, %d records saved to %s\n", len(all), dir)
 
-	return all
+	return all, nil
 }
 
-func addToTree(label, dir, server string, out io.Writer) {
+// addToTree transfers a zone and each delegated child zone. With --continue-on-error, a zone that can't be
+// transferred or written is recorded as failed and skipped instead of aborting the whole transfer.
+func addToTree(ctx context.Context, label, dir, server string, out io.Writer) error {
 	label = dns.Fqdn(label)
 	if queried[label] {
-		return
+		return nil
 	}
 	util.MustWritef(out, "AXFR %s\n", label)
 	queried[label] = true
-	rrs := axfr(label, server)
+	rrs, err := axfr(ctx, label, server)
+	if err != nil {
+		if !opts.ContinueOnError {
+			return err
+		}
+		util.MustWritef(out, "AXFR %s failed: %s\n", label, err)
+		return nil
+	}
 
 	// Write RRs to zone file
 	if len(rrs) > 0 {
@@ -87,14 +104,20 @@ func addToTree(label, dir, server string, out io.Writer) {
 			[]byte(zoneFile),
 			0644,
 		); err != nil {
-			log.Fatalf("Failed to write zone file: %s", err)
+			if !opts.ContinueOnError {
+				return fmt.Errorf("writing zone file: %s", err)
+			}
+			util.MustWritef(out, "AXFR %s failed: writing zone file: %s\n", label, err)
 		}
 	}
 
 	for _, rr := range rrs {
 		all = append(all, rr)
 		if _, ok := rr.(*dns.NS); ok {
-			addToTree(rr.Header().Name, dir, server, out)
+			if err := addToTree(ctx, rr.Header().Name, dir, server, out); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
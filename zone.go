@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/natesales/q/output"
+	"github.com/natesales/q/transport"
+)
+
+// findZone walks up the labels of a name with SOA queries until one returns an SOA record owned by the name it asked
+// for, which is the apex of the zone the name belongs to
+func findZone(ctx context.Context, txp *transport.Transport, name string) (*output.ZoneCut, error) {
+	name = dns.Fqdn(name)
+	labels := dns.SplitDomainName(name)
+	for depth := 0; depth <= len(labels); depth++ {
+		candidate := dns.Fqdn(strings.Join(labels[depth:], "."))
+		reply, err := queryType(ctx, txp, candidate, dns.TypeSOA)
+		if err != nil {
+			return nil, fmt.Errorf("querying SOA of %s: %s", candidate, err)
+		}
+		for _, rr := range reply.Answer {
+			if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(soa.Hdr.Name, candidate) {
+				return &output.ZoneCut{Name: name, Zone: soa.Hdr.Name, Depth: depth}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no SOA record for %s or any of its parents", name)
+}